@@ -0,0 +1,42 @@
+package main
+
+import (
+    "crypto/sha256"
+    "crypto/subtle"
+    "net/http"
+    "os"
+    "strings"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// readPasswordFile returns the first line of the file, so a trailing newline is not part of the password
+func readPasswordFile(path string) (string, error) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return "", err
+    }
+    line, _, _ := strings.Cut(string(raw), "\n")
+    return strings.TrimRight(line, "\r"), nil
+}
+
+// withBasicAuth protects next with HTTP basic auth. Credentials are compared as SHA-256 digests
+// with subtle.ConstantTimeCompare so neither their content nor their length leaks through timing.
+func withBasicAuth(next http.Handler, user, password string, failures prometheus.Counter) http.Handler {
+    wantUser := sha256.Sum256([]byte(user))
+    wantPass := sha256.Sum256([]byte(password))
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        u, p, ok := r.BasicAuth()
+        gotUser := sha256.Sum256([]byte(u))
+        gotPass := sha256.Sum256([]byte(p))
+        userOK := subtle.ConstantTimeCompare(gotUser[:], wantUser[:])
+        passOK := subtle.ConstantTimeCompare(gotPass[:], wantPass[:])
+        if !ok || userOK&passOK != 1 {
+            failures.Inc()
+            w.Header().Set("WWW-Authenticate", `Basic realm="temperature-exporter", charset="UTF-8"`)
+            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
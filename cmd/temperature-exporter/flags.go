@@ -0,0 +1,152 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "slices"
+    "strconv"
+    "strings"
+)
+
+// stringList is a repeatable flag that also accepts comma-separated values
+type stringList []string
+
+func (s *stringList) String() string {
+    return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+    for _, p := range strings.Split(v, ",") {
+        if p = strings.TrimSpace(p); p != "" {
+            *s = append(*s, p)
+        }
+    }
+    return nil
+}
+
+// labelFlags is a repeatable key=value flag
+type labelFlags map[string]string
+
+func (l labelFlags) String() string {
+    var parts []string
+    for k, v := range l {
+        parts = append(parts, k+"="+v)
+    }
+    return strings.Join(parts, ",")
+}
+
+func (l labelFlags) Set(v string) error {
+    k, val, ok := strings.Cut(v, "=")
+    if !ok {
+        return fmt.Errorf("attendu clé=valeur, reçu %q", v)
+    }
+    k = strings.TrimSpace(k)
+    if !labelNameRe.MatchString(k) || strings.HasPrefix(k, "__") {
+        return fmt.Errorf("nom de label Prometheus invalide: %q", k)
+    }
+    if _, ok := l[k]; ok {
+        return fmt.Errorf("label %q défini plusieurs fois", k)
+    }
+    l[k] = val
+    return nil
+}
+
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are the label names already used by the exporter's own metrics
+var reservedLabels = []string{"chip", "sensor", "label", "core", "ccd", "source", "adapter", "driver", "device", "path", "card", "pci_address", "package", "domain", "cpu", "scope", "reason", "target", "le", "version", "commit", "date", "goversion"}
+
+// checkConstLabels rejects extra labels that would clash with the exporter's own
+func checkConstLabels(l labelFlags) error {
+    for _, r := range reservedLabels {
+        if _, ok := l[r]; ok {
+            return fmt.Errorf("le label %q est réservé par l'exporteur", r)
+        }
+    }
+    return nil
+}
+
+// applyEnv sets every flag not given on the command line from PREFIX_FLAG_NAME
+// (e.g. TEMP_EXPORTER_ENABLE_SENSORS_CLI, TEMP_EXPORTER_WEB_CONFIG_FILE), so the precedence is flag > env > default.
+// Values go through the flag's own Set, exactly as if passed on the command line.
+func applyEnv(fs *flag.FlagSet, prefix string) error {
+    set := map[string]bool{}
+    fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+    var err error
+    fs.VisitAll(func(f *flag.Flag) {
+        if err != nil || set[f.Name] {
+            return
+        }
+        name := prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(f.Name))
+        v, ok := os.LookupEnv(name)
+        if !ok {
+            return
+        }
+        if e := fs.Set(f.Name, v); e != nil {
+            err = fmt.Errorf("%s=%q: %v", name, v, e)
+        }
+    })
+    return err
+}
+
+// sysfsPathFlags are the flags whose default is a sysfs path; a new sysfs source lists its own
+// path flag here so -sysfs-prefix applies to it too
+var sysfsPathFlags = []string{"hwmon", "thermal", "rapl-path", "cpu-path"}
+
+// applySysfsPrefix prepends prefix to the default of every sysfs path flag left unset on the
+// command line and in the environment, so an explicit path always wins
+func applySysfsPrefix(fs *flag.FlagSet, prefix string) error {
+    set := map[string]bool{}
+    fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+    for _, name := range sysfsPathFlags {
+        if set[name] {
+            continue
+        }
+        if err := fs.Set(name, filepath.Join(prefix, fs.Lookup(name).DefValue)); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// parseUnits checks the -units list. Celsius is always exported, so only the two extra units
+// are reported.
+func parseUnits(units []string) (fahrenheit, kelvin bool, err error) {
+    for _, u := range units {
+        switch strings.ToLower(u) {
+        case "celsius":
+        case "fahrenheit":
+            fahrenheit = true
+        case "kelvin":
+            kelvin = true
+        default:
+            return false, false, fmt.Errorf("unité %q inconnue (valeurs possibles: celsius, fahrenheit, kelvin)", u)
+        }
+    }
+    return fahrenheit, kelvin, nil
+}
+
+// defaultHistogramBuckets covers idle to throttling temperatures in 5°C steps
+var defaultHistogramBuckets = []float64{20, 25, 30, 35, 40, 45, 50, 55, 60, 65, 70, 75, 80, 85, 90, 95, 100}
+
+// parseBuckets reads the -histogram-buckets upper bounds, which must be strictly increasing
+func parseBuckets(list []string) ([]float64, error) {
+    if len(list) == 0 {
+        return defaultHistogramBuckets, nil
+    }
+    buckets := make([]float64, len(list))
+    for i, s := range list {
+        v, err := strconv.ParseFloat(s, 64)
+        if err != nil {
+            return nil, fmt.Errorf("%q n'est pas un nombre", s)
+        }
+        buckets[i] = v
+    }
+    if !slices.IsSorted(buckets) || len(slices.Compact(slices.Clone(buckets))) != len(buckets) {
+        return nil, fmt.Errorf("les bornes doivent être strictement croissantes")
+    }
+    return buckets, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "log/syslog"
+    "os"
+    "slices"
+    "strconv"
+    "strings"
+
+    "github.com/coreos/go-systemd/v22/journal"
+)
+
+// logConfig gathers the -log-* and -syslog-* flags
+type logConfig struct {
+    level    string
+    format   string // text or json, for stderr only
+    output   string // stderr, syslog or journal
+    facility string
+    tag      string
+}
+
+// setupLogging applies the logging flags. On stderr the text format keeps the log package's
+// timestamped lines, now prefixed with the level, while json hands every message to slog's
+// JSON handler. syslog and journal leave timestamps to the daemon. Except for stderr text, the
+// startup errors still going through log.Fatalf are logged at error.
+func setupLogging(cfg logConfig) error {
+    var lvl slog.Level
+    if err := lvl.UnmarshalText([]byte(cfg.level)); err != nil {
+        return fmt.Errorf("-log-level: niveau %q inconnu (attendu: debug, info, warn ou error)", cfg.level)
+    }
+    if cfg.format != "text" && cfg.format != "json" {
+        return fmt.Errorf("-log-format: format %q inconnu (attendu: text ou json)", cfg.format)
+    }
+    var h slog.Handler
+    switch cfg.output {
+    case "stderr":
+    case "syslog":
+        facility, ok := syslogFacilities[cfg.facility]
+        if !ok {
+            return fmt.Errorf("-syslog-facility: facility %q inconnue (attendu: daemon, user, local0 à local7…)", cfg.facility)
+        }
+        w, err := syslog.New(facility|syslog.LOG_INFO, cfg.tag)
+        if err != nil {
+            return fmt.Errorf("-log-output syslog: %v", err)
+        }
+        h = &sinkHandler{level: lvl, send: syslogSender(w)}
+    case "journal":
+        if journal.Enabled() {
+            h = &sinkHandler{level: lvl, send: journalSender(cfg.tag)}
+        }
+    default:
+        return fmt.Errorf("-log-output: sortie %q inconnue (attendu: stderr, syslog ou journal)", cfg.output)
+    }
+    if h == nil && cfg.format == "json" {
+        h = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})
+    }
+    if h == nil {
+        slog.SetLogLoggerLevel(lvl)
+    } else {
+        slog.SetDefault(slog.New(h))
+        slog.SetLogLoggerLevel(slog.LevelError)
+    }
+    if cfg.output == "journal" && !journal.Enabled() {
+        slog.Warn("-log-output journal: socket journald absent, journaux écrits sur stderr")
+    }
+    return nil
+}
+
+var syslogFacilities = map[string]syslog.Priority{
+    "kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "mail": syslog.LOG_MAIL, "daemon": syslog.LOG_DAEMON,
+    "auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG, "lpr": syslog.LOG_LPR, "news": syslog.LOG_NEWS,
+    "uucp": syslog.LOG_UUCP, "cron": syslog.LOG_CRON, "authpriv": syslog.LOG_AUTHPRIV, "ftp": syslog.LOG_FTP,
+    "local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1, "local2": syslog.LOG_LOCAL2, "local3": syslog.LOG_LOCAL3,
+    "local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5, "local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+}
+
+// sinkHandler is a slog.Handler for outputs taking one message at a time with its level
+// (syslog, journald). Attributes are flattened, groups joining their keys with dots.
+type sinkHandler struct {
+    level  slog.Level
+    attrs  []slog.Attr
+    prefix string
+    send   func(level slog.Level, msg string, attrs []slog.Attr) error
+}
+
+func (h *sinkHandler) Enabled(_ context.Context, level slog.Level) bool {
+    return level >= h.level
+}
+
+func (h *sinkHandler) Handle(_ context.Context, r slog.Record) error {
+    attrs := slices.Clip(h.attrs)
+    r.Attrs(func(a slog.Attr) bool {
+        attrs = flattenAttr(attrs, h.prefix, a)
+        return true
+    })
+    return h.send(r.Level, r.Message, attrs)
+}
+
+func (h *sinkHandler) WithAttrs(as []slog.Attr) slog.Handler {
+    next := *h
+    next.attrs = slices.Clip(h.attrs)
+    for _, a := range as {
+        next.attrs = flattenAttr(next.attrs, h.prefix, a)
+    }
+    return &next
+}
+
+func (h *sinkHandler) WithGroup(name string) slog.Handler {
+    next := *h
+    next.prefix += name + "."
+    return &next
+}
+
+func flattenAttr(attrs []slog.Attr, prefix string, a slog.Attr) []slog.Attr {
+    v := a.Value.Resolve()
+    if v.Kind() == slog.KindGroup {
+        for _, g := range v.Group() {
+            attrs = flattenAttr(attrs, prefix+a.Key+".", g)
+        }
+        return attrs
+    }
+    if a.Key == "" {
+        return attrs
+    }
+    return append(attrs, slog.Attr{Key: prefix + a.Key, Value: v})
+}
+
+// syslogSender writes "msg key=value…" lines at the syslog severity of the level
+func syslogSender(w *syslog.Writer) func(slog.Level, string, []slog.Attr) error {
+    return func(level slog.Level, msg string, attrs []slog.Attr) error {
+        var b strings.Builder
+        b.WriteString(msg)
+        for _, a := range attrs {
+            v := a.Value.String()
+            if v == "" || strings.ContainsAny(v, " \"=") {
+                v = strconv.Quote(v)
+            }
+            fmt.Fprintf(&b, " %s=%s", a.Key, v)
+        }
+        switch {
+        case level >= slog.LevelError:
+            return w.Err(b.String())
+        case level >= slog.LevelWarn:
+            return w.Warning(b.String())
+        case level >= slog.LevelInfo:
+            return w.Info(b.String())
+        default:
+            return w.Debug(b.String())
+        }
+    }
+}
+
+// journalSender sends native journald entries: attributes become fields (source → SOURCE,
+// chip → CHIP…), filterable with journalctl SOURCE=hwmon
+func journalSender(tag string) func(slog.Level, string, []slog.Attr) error {
+    return func(level slog.Level, msg string, attrs []slog.Attr) error {
+        vars := map[string]string{"SYSLOG_IDENTIFIER": tag}
+        for _, a := range attrs {
+            if key := journalField(a.Key); key != "" {
+                vars[key] = a.Value.String()
+            }
+        }
+        pri := journal.PriDebug
+        switch {
+        case level >= slog.LevelError:
+            pri = journal.PriErr
+        case level >= slog.LevelWarn:
+            pri = journal.PriWarning
+        case level >= slog.LevelInfo:
+            pri = journal.PriInfo
+        }
+        return journal.Send(msg, pri, vars)
+    }
+}
+
+// journalField turns an attribute key into a journald field name: uppercase letters, digits
+// and underscores, not starting with an underscore (reserved for trusted fields)
+func journalField(key string) string {
+    b := []byte(strings.ToUpper(key))
+    for i, c := range b {
+        if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+            b[i] = '_'
+        }
+    }
+    return strings.TrimLeft(string(b), "_0123456789")
+}
@@ -1,26 +1,28 @@
 package main
 
 import (
-    "bufio"
     "context"
-    "encoding/json"
+    "crypto/tls"
     "errors"
     "flag"
     "fmt"
     "log"
+    "log/slog"
+    "net"
     "net/http"
     "os"
     "os/signal"
-    "os/exec"
-    "path/filepath"
-    "regexp"
-    "strconv"
+    "runtime"
     "strings"
     "syscall"
     "time"
 
+    "github.com/coreos/go-systemd/v22/daemon"
     "github.com/prometheus/client_golang/prometheus"
-    "github.com/prometheus/client_golang/prometheus/promhttp"
+    "github.com/prometheus/client_golang/prometheus/collectors"
+    "github.com/prometheus/exporter-toolkit/web"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/collector"
 )
 
 // These variables are intended to be set at build time via -ldflags
@@ -30,338 +32,639 @@ var (
     date    = "unknown"
 )
 
-// sensorReading represents a single sensor with an optional label name (e.g., CPU, GPU, etc.)
-type sensorReading struct {
-    chip   string // hwmon chip directory name
-    name   string // sensor name from name file when available
-    label  string // content of temp*_label when present
-    path   string // path to temp*_input
-    factor float64 // multiplier (usually 0.001) to convert millidegree C to degree C
-}
-
-// collector implements prometheus.Collector
-type collector struct {
-    basePath   string
-    thermalPath string
-    enableHwmon bool
-    enableThermal bool
-    enableSensorsCli bool
-    sensorsCliPath string
-    sensorsTimeout time.Duration
-    sensors    *prometheus.GaugeVec
-    scrapeTime prometheus.Gauge
-}
-
-var sensorsCliWarned bool
-
-func newCollector(basePath string, thermalPath string, enableHwmon, enableThermal bool, enableSensorsCli bool, sensorsCliPath string, sensorsTimeout time.Duration, namespace string) *collector {
-    labels := []string{"chip", "sensor", "label"}
-    return &collector{
-        basePath: basePath,
-        thermalPath: thermalPath,
-        enableHwmon: enableHwmon,
-        enableThermal: enableThermal,
-        enableSensorsCli: enableSensorsCli,
-        sensorsCliPath: sensorsCliPath,
-        sensorsTimeout: sensorsTimeout,
-        sensors: prometheus.NewGaugeVec(prometheus.GaugeOpts{
-            Namespace: namespace,
-            Name:      "temperature_celsius",
-            Help:      "Température en degrés Celsius lue depuis les capteurs système (hwmon, thermal, lm-sensors).",
-        }, labels),
-        scrapeTime: prometheus.NewGauge(prometheus.GaugeOpts{
-            Namespace: namespace,
-            Name:      "scrape_duration_seconds",
-            Help:      "Durée de la dernière collecte des températures.",
-        }),
+func main() {
+    collector.Version = version
+    // FreeBSD has neither /sys nor lm-sensors: the sysctl source replaces them by default
+    freebsd := runtime.GOOS == "freebsd"
+    var (
+        metricsPath = flag.String("path", "/metrics", "Chemin HTTP pour exposer les métriques")
+        basePath    = flag.String("hwmon", "/sys/class/hwmon", "Chemin(s) de base vers les capteurs hwmon, séparés par des virgules")
+        sysfsPrefix = flag.String("sysfs-prefix", "", "Préfixe ajouté aux chemins sysfs par défaut (-hwmon, -thermal, -rapl-path, -cpu-path), ex: /host quand le /sys de l'hôte est monté sous /host/sys; un chemin donné explicitement n'est pas modifié")
+        thermalPath = flag.String("thermal", "/sys/class/thermal", "Chemin(s) de base vers les zones thermiques (thermal zones), séparés par des virgules")
+        enableHwmon = flag.Bool("enable-hwmon", !freebsd, "Activer la lecture via hwmon (/sys/class/hwmon, Linux)")
+        hwmonDiscoveryTTL = flag.Duration("hwmon-discovery-ttl", time.Minute, "Durée de mise en cache de la découverte hwmon, invalidée aussi par les événements inotify (0 pour redécouvrir à chaque collecte)")
+        readConcurrency = flag.Int("read-concurrency", collector.DefaultReadConcurrency, "Nombre maximal de fichiers capteurs (hwmon, thermal) lus en parallèle")
+        includeDisabled = flag.Bool("include-disabled-sensors", false, "Lire aussi les canaux hwmon désactivés (tempN_enable à 0), qui renvoient d'habitude une valeur figée ou nulle")
+        clearIntrusion  = flag.Bool("enable-intrusion-clear", false, "Exposer POST /-/clear-intrusion, qui remet à 0 les alarmes d'intrusion châssis (écriture dans sysfs, protégé comme /metrics)")
+        readTimeout     = flag.Duration("sensor-read-timeout", 500*time.Millisecond, "Délai maximal de lecture d'un fichier capteur (hwmon, thermal), au-delà le capteur est ignoré pour cette collecte (0 pour aucun)")
+        hwmonTimeout = flag.Duration("hwmon-timeout", 2*time.Second, "Délai maximal de découverte et lecture des capteurs hwmon par collecte (0 pour aucun)")
+        enableThermal = flag.Bool("enable-thermal", !freebsd, "Activer la lecture via thermal zones (/sys/class/thermal, Linux)")
+        thermalTimeout = flag.Duration("thermal-timeout", 2*time.Second, "Délai maximal de découverte et lecture des thermal zones par collecte (0 pour aucun)")
+    enableSensorsCli = flag.Bool("enable-sensors-cli", !freebsd, "Activer la lecture via 'sensors -j' (nécessite lm-sensors)")
+        sensorsCliPath = flag.String("sensors-cli-path", "sensors", "Chemin de la commande 'sensors'")
+        sensorsCliFormat = flag.String("sensors-cli-format", "auto", "Format de sortie de 'sensors': auto (-j puis repli sur -u), json (-j) ou raw (-u)")
+        sensorsCliConfig = flag.String("sensors-cli-config", "", "Fichier de configuration passé à 'sensors -c' (vide pour la configuration système)")
+        sensorsCliArgs   = flag.String("sensors-cli-args", "", "Arguments supplémentaires séparés par des espaces, ajoutés après -j (ex: 'nct6798-*')")
+        sensorsTimeout = flag.Duration("sensors-timeout", 2*time.Second, "Timeout pour l'exécution de 'sensors -j'")
+        enableSysctl   = flag.Bool("enable-sysctl", freebsd, "Activer la lecture des sysctls de température FreeBSD (dev.cpu.N.temperature, hw.acpi.thermal.tzN.temperature), par défaut sous FreeBSD uniquement")
+        sshTargetsFile = flag.String("ssh-targets-file", "", "Fichier YAML des hôtes distants lus par SSH (hwmon ou sensors -j), exportés avec un label target (vide pour désactiver)")
+        enableIPMI  = flag.Bool("enable-ipmi", false, "Activer la lecture des capteurs du BMC via IPMI")
+        ipmiBackend = flag.String("ipmi-backend", "ipmitool", "Backend IPMI: ipmitool ou freeipmi (ipmi-sensors)")
+        ipmiPath    = flag.String("ipmi-path", "ipmitool", "Chemin de la commande 'ipmitool'")
+        ipmiSensorsPath = flag.String("ipmi-sensors-path", "ipmi-sensors", "Chemin de la commande 'ipmi-sensors' (backend freeipmi)")
+        ipmiSDRCacheDir = flag.String("ipmi-sdr-cache-dir", "/var/cache/temperature-exporter/sdr", "Répertoire du cache SDR de FreeIPMI (backend freeipmi)")
+        ipmiTimeout = flag.Duration("ipmi-timeout", 10*time.Second, "Timeout pour l'exécution de la commande IPMI")
+        ipmiCache   = flag.Duration("ipmi-cache", 30*time.Second, "Durée de mise en cache des lectures IPMI (0 pour désactiver)")
+        enableNvidia  = flag.Bool("enable-nvidia", false, "Activer la lecture des GPU NVIDIA via 'nvidia-smi'")
+        nvidiaSmiPath = flag.String("nvidia-smi-path", "nvidia-smi", "Chemin de la commande 'nvidia-smi'")
+        nvidiaTimeout = flag.Duration("nvidia-timeout", 2*time.Second, "Timeout pour l'exécution de 'nvidia-smi'")
+        enableVcgencmd  = flag.Bool("enable-vcgencmd", false, "Activer la lecture de la température SoC Raspberry Pi via 'vcgencmd measure_temp'")
+        vcgencmdPath    = flag.String("vcgencmd-path", "vcgencmd", "Chemin de la commande 'vcgencmd'")
+        vcgencmdTimeout = flag.Duration("vcgencmd-timeout", 2*time.Second, "Timeout pour l'exécution de 'vcgencmd measure_temp'")
+        enableRapl  = flag.Bool("enable-rapl", false, "Activer les compteurs d'énergie Intel RAPL (/sys/class/powercap)")
+        raplPath    = flag.String("rapl-path", "/sys/class/powercap", "Chemin de base vers les zones powercap (intel-rapl)")
+        enableThrottle = flag.Bool("enable-throttle", false, "Exporter les compteurs de ralentissements thermiques des CPU (thermal_throttle, Intel)")
+        cpuPath        = flag.String("cpu-path", "/sys/devices/system/cpu", "Chemin de base vers les CPU (cpuN/thermal_throttle)")
+        k10tempCorrect = flag.Bool("k10temp-correct-tctl", false, "Corriger le décalage du Tctl AMD k10temp (Threadripper 1000/2000, Ryzen 1600X/1700X/1800X/2700X) pour exporter la température réelle du die")
+        enableStorcli  = flag.Bool("enable-storcli", false, "Activer la lecture des contrôleurs MegaRAID via 'storcli64 /call show all J' (ou perccli)")
+        storcliPath    = flag.String("storcli-path", "storcli64", "Chemin de la commande storcli64 ou perccli64")
+        storcliTimeout = flag.Duration("storcli-timeout", 10*time.Second, "Timeout pour l'exécution de storcli")
+        storcliCache   = flag.Duration("storcli-cache", 60*time.Second, "Durée de mise en cache des lectures storcli (0 pour désactiver)")
+        apcupsdAddress = flag.String("apcupsd-address", "", "Adresse host:port du serveur NIS apcupsd (ex: 127.0.0.1:3551), vide pour désactiver")
+        apcupsdTimeout = flag.Duration("apcupsd-timeout", 2*time.Second, "Timeout de connexion et de lecture vers apcupsd")
+        nutTimeout     = flag.Duration("nut-timeout", 2*time.Second, "Timeout de connexion et de lecture par onduleur NUT")
+        enableLiquidctl  = flag.Bool("enable-liquidctl", false, "Activer la lecture des watercoolings AIO via 'liquidctl status --json'")
+        liquidctlPath    = flag.String("liquidctl-path", "liquidctl", "Chemin de la commande 'liquidctl'")
+        liquidctlTimeout = flag.Duration("liquidctl-timeout", 5*time.Second, "Timeout pour l'exécution de 'liquidctl status'")
+        sourceLabel    = flag.Bool("source-label", true, "Ajouter un label source (hwmon, thermal, sensors-cli...) aux métriques de capteurs")
+        dedupeCli      = flag.Bool("dedupe-sensors-cli", true, "Ignorer les lectures de 'sensors -j' déjà fournies par hwmon (même chip et même libellé)")
+        dedupe         = flag.Bool("dedupe", false, "Ne garder que la source la plus prioritaire pour un même triplet chip/sensor/label")
+        namespace   = flag.String("namespace", "temp_exporter", "Préfixe des métriques Prometheus")
+        timeout     = flag.Duration("read-timeout", 5*time.Second, "Timeout lecture HTTP")
+        writeTO     = flag.Duration("write-timeout", 10*time.Second, "Timeout écriture HTTP")
+        maxRequests = flag.Int("max-requests", 3, "Nombre maximal de scrapes /metrics simultanés, les suivants reçoivent un 503 (0: illimité)")
+        readHdrTO   = flag.Duration("read-header-timeout", 5*time.Second, "Timeout lecture des en-têtes HTTP")
+        idleTO      = flag.Duration("idle-timeout", 30*time.Second, "Timeout idle HTTP")
+        tlsCert     = flag.String("tls-cert", "", "Certificat TLS (PEM); avec -tls-key, active HTTPS")
+        tlsKey      = flag.String("tls-key", "", "Clé privée TLS (PEM)")
+        tlsMinVer   = flag.String("tls-min-version", "1.2", "Version TLS minimale: 1.2 ou 1.3")
+        webConfig   = flag.String("web.config.file", "", "Fichier de configuration web de l'exporter-toolkit Prometheus (TLS, authentification), vide pour HTTP simple")
+        authUser    = flag.String("auth-user", "", "Utilisateur HTTP basic auth exigé sur le chemin des métriques (vide pour désactiver)")
+        authPassFile = flag.String("auth-password-file", "", "Fichier contenant le mot de passe basic auth (première ligne)")
+        logRequests = flag.Bool("log-requests", false, "Journaliser les requêtes HTTP (méthode, chemin, statut, taille, durée, client, User-Agent)")
+        logReqExcl  = flag.String("log-requests-exclude", "/healthz,/readyz,/livez", "Chemins exclus de -log-requests, séparés par des virgules (vide pour tout journaliser)")
+        logLevel    = flag.String("log-level", "info", "Niveau de journalisation minimal: debug, info, warn ou error")
+        logFormat   = flag.String("log-format", "text", "Format des journaux: text (lignes lisibles) ou json (un objet par ligne, pour Loki)")
+        logOutput   = flag.String("log-output", "stderr", "Destination des journaux: stderr, syslog ou journal (journald natif, stderr si le socket est absent)")
+        syslogFac   = flag.String("syslog-facility", "daemon", "Facility syslog avec -log-output syslog (daemon, user, local0 à local7…)")
+        syslogTag   = flag.String("syslog-tag", "temperature-exporter", "Identifiant des messages avec -log-output syslog ou journal")
+        enablePprof = flag.Bool("enable-pprof", false, "Exposer les profils net/http/pprof sous /debug/pprof/, protégés comme /metrics")
+        pprofListen = flag.String("pprof-listen", "", "Servir /debug/pprof/ sur cette adresse locale distincte (ex: 127.0.0.1:6060) plutôt que sur -listen")
+        calibrationFile = flag.String("calibration-file", "", "Fichier CSV \"chip,label,offset,scale\" de corrections appliquées comme valeur*scale+offset")
+        renameFile = flag.String("rename-file", "", "Fichier CSV de règles de renommage \"chip_regex,sensor_regex,label_regex,chip,sensor,label\" (première règle correspondante)")
+        minValidTemp = flag.Float64("min-valid-temp", collector.DefaultMinValidTemp, "Température minimale plausible en °C, les lectures inférieures sont écartées")
+        maxValidTemp = flag.Float64("max-valid-temp", collector.DefaultMaxValidTemp, "Température maximale plausible en °C, les lectures supérieures sont écartées")
+        dropZero     = flag.Bool("drop-zero", false, "Écarter les lectures hwmon valant exactement 0°C (sondes débranchées)")
+        noBlocklist = flag.Bool("disable-default-blocklist", false, "Désactiver la liste intégrée des capteurs connus pour être fantaisistes (acpitz, AUXTIN nct67xx)")
+        blocklistFile = flag.String("blocklist-file", "", "Fichier de regex supplémentaires (une par ligne) appliquées à \"chip/label\" pour masquer des capteurs")
+        hostnameLabel = flag.Bool("hostname-label", false, "Ajouter un label node (nom d'hôte) à toutes les métriques")
+        hostname      = flag.String("hostname", "", "Valeur du label node à la place de os.Hostname() (conteneurs)")
+        noStatusPage    = flag.Bool("disable-status-page", false, "Ne pas servir la page HTML /status (tableau des températures)")
+        peersTimeout    = flag.Duration("peers-timeout", 3*time.Second, "Timeout de lecture de chaque pair de -peers par /all")
+        enablePVELabels = flag.Bool("enable-pve-labels", false, "Ajouter les labels pve_node et pve_cluster lus dans /etc/pve/.members (sans effet hors Proxmox)")
+        logFiltered = flag.Bool("log-filtered", false, "Journaliser les capteurs écartés par les filtres (première collecte) et par la liste de blocage")
+        failOnNoSensors = flag.Bool("fail-on-no-sensors", false, "Quitter en erreur au démarrage si aucune source ne trouve de capteur")
+        readyThreshold  = flag.Int("ready-failure-threshold", 0, "Nombre de collectes en échec d'affilée après lequel /readyz repasse à 503 (0: jamais)")
+        healthReadings  = flag.Bool("health-require-readings", true, "/healthz répond 503 si la dernière collecte n'a produit aucune lecture (en plus du cas où toutes les sources échouent)")
+        listSensors = flag.Bool("list-sensors", false, "Lister les capteurs découverts (valeur, chemin, filtrage) sur la sortie standard puis quitter")
+        listFormat  = flag.String("list-format", "table", "Format de -list-sensors: table, json ou csv")
+        once = flag.Bool("once", false, "Effectuer une seule collecte, écrire les métriques au format texte Prometheus sur la sortie standard puis quitter")
+        rwURL       = flag.String("remote-write-url", "", "URL remote_write Prometheus (Mimir, Thanos, VictoriaMetrics...) vers laquelle pousser les métriques, vide pour désactiver")
+        rwInterval  = flag.Duration("remote-write-interval", 30*time.Second, "Intervalle entre deux envois remote_write")
+        rwTimeout   = flag.Duration("remote-write-timeout", 10*time.Second, "Timeout d'une requête remote_write")
+        rwUser      = flag.String("remote-write-user", "", "Utilisateur basic auth pour remote_write")
+        rwPassFile  = flag.String("remote-write-password-file", "", "Fichier contenant le mot de passe basic auth remote_write (première ligne)")
+        rwTokenFile = flag.String("remote-write-bearer-token-file", "", "Fichier contenant le jeton bearer remote_write (première ligne)")
+        rwJob       = flag.String("remote-write-job", "temperature-exporter", "Valeur du label job des séries poussées")
+        rwInstance  = flag.String("remote-write-instance", "", "Valeur du label instance des séries poussées (par défaut le nom d'hôte)")
+        rwQueue     = flag.Int("remote-write-queue-size", 10, "Nombre de lots en attente au-delà duquel les plus anciens sont abandonnés")
+        rwRetries   = flag.Int("remote-write-max-retries", 3, "Nombre de nouvelles tentatives sur réponse 429 ou 5xx avant d'abandonner un lot")
+        influxURL       = flag.String("influxdb-url", "", "URL d'InfluxDB 2.x (API /api/v2/write) vers laquelle envoyer les températures, vide pour désactiver")
+        influxTokenFile = flag.String("influxdb-token-file", "", "Fichier contenant le jeton d'API InfluxDB (première ligne)")
+        influxOrg       = flag.String("influxdb-org", "", "Organisation InfluxDB")
+        influxBucket    = flag.String("influxdb-bucket", "", "Bucket InfluxDB")
+        influxUDP       = flag.String("influxdb-udp", "", "Adresse hôte:port UDP d'InfluxDB (protocole ligne, comme le serveur de métriques Proxmox), à la place de -influxdb-url")
+        influxInterval  = flag.Duration("influxdb-interval", 30*time.Second, "Intervalle entre deux envois vers InfluxDB")
+        influxTimeout   = flag.Duration("influxdb-timeout", 5*time.Second, "Timeout d'écriture vers InfluxDB")
+        graphiteAddr     = flag.String("graphite-address", "", "Adresse hôte:port du serveur Graphite (protocole texte) vers lequel envoyer les températures, vide pour désactiver")
+        graphiteProto    = flag.String("graphite-protocol", "tcp", "Transport Graphite: tcp ou udp")
+        graphitePrefix   = flag.String("graphite-prefix", "temperature", "Préfixe des chemins Graphite (prefix.hôte.chip.sensor.label)")
+        graphiteInterval = flag.Duration("graphite-interval", 30*time.Second, "Intervalle entre deux envois vers Graphite")
+        graphiteTimeout  = flag.Duration("graphite-timeout", 5*time.Second, "Timeout de connexion et d'écriture vers Graphite")
+        graphiteDryRun   = flag.Bool("graphite-dry-run", false, "Écrire les lignes Graphite sur la sortie standard au lieu de les envoyer, pour valider le nommage")
+        mqttBroker       = flag.String("mqtt-broker", "", "Broker MQTT (tcp://hôte:1883, ssl://hôte:8883 ou hôte:port) sur lequel publier les températures, vide pour désactiver")
+        mqttPrefix       = flag.String("mqtt-topic-prefix", "temperature", "Préfixe des topics MQTT (prefix/hôte/chip/label)")
+        mqttClientID     = flag.String("mqtt-client-id", "", "Identifiant client MQTT (par défaut temperature-exporter-<hôte>)")
+        mqttUser         = flag.String("mqtt-user", "", "Utilisateur MQTT")
+        mqttPassFile     = flag.String("mqtt-password-file", "", "Fichier contenant le mot de passe MQTT (première ligne)")
+        mqttQoS          = flag.Int("mqtt-qos", 0, "Qualité de service des publications MQTT: 0, 1 ou 2")
+        mqttCAFile       = flag.String("mqtt-tls-ca-file", "", "Autorité de certification (PEM) du broker MQTT, à la place des autorités du système")
+        mqttInsecure     = flag.Bool("mqtt-tls-insecure-skip-verify", false, "Ne pas vérifier le certificat du broker MQTT")
+        mqttInterval     = flag.Duration("mqtt-interval", 30*time.Second, "Intervalle entre deux publications MQTT")
+        mqttTimeout      = flag.Duration("mqtt-timeout", 5*time.Second, "Timeout de connexion, d'écriture et d'accusé de réception MQTT")
+        mqttHA           = flag.Bool("mqtt-homeassistant", false, "Annoncer les capteurs à Home Assistant (MQTT discovery) en plus de publier leurs valeurs")
+        mqttHAPrefix     = flag.String("mqtt-homeassistant-prefix", "homeassistant", "Préfixe de découverte MQTT de Home Assistant")
+        alertRules      = flag.String("alert-rules-file", "", "Fichier CSV de seuils d'alerte \"chip_regex,label_regex,warn,crit,hold,hysteresis\" évalués en continu (rechargé sur SIGHUP)")
+        alertWebhook    = flag.String("alert-webhook-url", "", "URL à laquelle POSTer en JSON les alertes déclenchées et résolues (vide: journal uniquement)")
+        alertInterval   = flag.Duration("alert-interval", 30*time.Second, "Intervalle d'évaluation des seuils d'alerte")
+        alertTimeout    = flag.Duration("alert-webhook-timeout", 5*time.Second, "Timeout d'une notification vers le webhook d'alerte")
+        histogram       = flag.Bool("histogram", false, "Enregistrer toutes les températures de chaque collecte dans l'histogramme temperature_celsius_histogram{source}")
+        histogramOnly   = flag.Bool("histogram-only", false, "Avec -histogram, ne plus exporter les séries par capteur des températures et de leurs seuils")
+        collectInterval = flag.Duration("collect-interval", 0, "Collecter en arrière-plan à cet intervalle et servir /metrics depuis la dernière collecte (0: collecte à chaque scrape)")
+        staleAfter      = flag.Duration("stale-after", 0, "Âge au-delà duquel une lecture n'est plus exportée (0: 3 × -collect-interval, au moins le cache IPMI/storcli + un intervalle, rien sans -collect-interval; négatif: jamais)")
+        precision       = flag.Int("precision", -1, "Nombre de décimales des valeurs exportées (températures, ventilateurs, tensions…), arrondi au pair le plus proche (négatif: valeurs complètes)")
+        timestamps      = flag.Bool("timestamps", false, "Horodater les lectures mesurées avant le scrape (-collect-interval, cache IPMI/storcli) avec leur heure de mesure")
+        peakInterval    = flag.Duration("peak-sample-interval", 0, "Intervalle d'échantillonnage en arrière-plan des pics de température (temperature_peak_celsius), 0 pour désactiver")
+        runtimeMetrics  = flag.Bool("enable-runtime-metrics", false, "Exporter les métriques go_* et process_* du processus (mémoire, descripteurs de fichiers, goroutines)")
+        showVersion     = flag.Bool("version", false, "Afficher la version, le commit, la date de compilation et la version de Go puis quitter")
+        versionJSON     = flag.Bool("version-json", false, "Comme -version, au format JSON")
+    )
+    var listenAddrs stringList
+    flag.Var(&listenAddrs, "listen", "Adresse d'écoute HTTP, ex : :9102 (répétable ou séparé par des virgules, par défaut :9102)")
+    var nutUPS stringList
+    var peerList stringList
+    flag.Var(&peerList, "peers", "Exporteurs des autres nœuds agrégés par /all, sous la forme [nœud=]URL (ex: pve2=http://10.0.0.2:9102/metrics, répétable ou séparé par des virgules)")
+    extraLabels := labelFlags{}
+    flag.Var(extraLabels, "label", "Label constant ajouté à toutes les métriques, sous la forme clé=valeur (répétable, ex: -label rack=r2)")
+    var hwmonChips, thermalZones, sensorsCliChips stringList
+    flag.Var(&hwmonChips, "hwmon-chips", "Chips hwmon à découvrir, motifs glob ou /regex/ séparés par des virgules, appliqués avant -chip-include/-chip-exclude (vide pour tous)")
+    flag.Var(&thermalZones, "thermal-zones", "Zones thermiques à découvrir par type (x86_pkg_temp) ou nom (thermal_zone0), motifs glob ou /regex/ (vide pour toutes)")
+    flag.Var(&sensorsCliChips, "sensors-cli-chips", "Chips lm-sensors à conserver, motifs glob ou /regex/ séparés par des virgules (vide pour tous)")
+    var chipInclude, chipExclude, sensorInclude, sensorExclude stringList
+    flag.Var(&chipInclude, "chip-include", "Regex RE2 des chips à conserver (répétable ou séparé par des virgules, vide pour tout garder)")
+    flag.Var(&chipExclude, "chip-exclude", "Regex RE2 des chips à ignorer, prioritaire sur -chip-include")
+    flag.Var(&sensorInclude, "sensor-include", "Regex RE2 des capteurs (sensor ou label) à conserver, vide pour tout garder")
+    flag.Var(&sensorExclude, "sensor-exclude", "Regex RE2 des capteurs (sensor ou label) à ignorer, prioritaire sur -sensor-include")
+    var sourcePriority stringList
+    var units stringList
+    var histogramBuckets stringList
+    var trustedProxies stringList
+    flag.Var(&histogramBuckets, "histogram-buckets", "Bornes hautes en °C des buckets de -histogram, séparées par des virgules (par défaut 20,25,...,100 par pas de 5)")
+    flag.Var(&trustedProxies, "trusted-proxies", "Adresses IP ou plages CIDR des reverse proxies dont -log-requests croit l'en-tête X-Forwarded-For (répétable ou séparé par des virgules)")
+    flag.Var(&units, "units", "Unités de température exportées, séparées par des virgules: celsius, fahrenheit, kelvin (temperature_celsius est toujours exporté)")
+    flag.Var(&sourcePriority, "source-priority", "Ordre de priorité des sources pour -dedupe, séparé par des virgules (par défaut hwmon,sensors-cli,thermal)")
+    flag.Var(&nutUPS, "nut-ups", "Onduleur NUT à interroger sous la forme ups@hôte[:port] (répétable ou séparé par des virgules)")
+    flag.Parse()
+    // checked before applyEnv: only an explicit flag switches to version output
+    if *showVersion || *versionJSON {
+        if err := printVersion(os.Stdout, *versionJSON); err != nil {
+            log.Fatalf("%v", err)
+        }
+        return
     }
-}
-
-func (c *collector) Describe(ch chan<- *prometheus.Desc) {
-    c.sensors.Describe(ch)
-    c.scrapeTime.Describe(ch)
-}
-
-func readFirstLine(path string) (string, error) {
-    f, err := os.Open(path)
+    if err := applyEnv(flag.CommandLine, "TEMP_EXPORTER_"); err != nil {
+        log.Fatalf("%v", err)
+    }
+    if err := setupLogging(logConfig{
+        level:    *logLevel,
+        format:   *logFormat,
+        output:   *logOutput,
+        facility: *syslogFac,
+        tag:      *syslogTag,
+    }); err != nil {
+        log.Fatalf("%v", err)
+    }
+    if *sysfsPrefix != "" {
+        if err := applySysfsPrefix(flag.CommandLine, *sysfsPrefix); err != nil {
+            log.Fatalf("-sysfs-prefix: %v", err)
+        }
+        if fi, err := os.Stat(*sysfsPrefix); err != nil || !fi.IsDir() {
+            slog.Warn("-sysfs-prefix: le répertoire n'existe pas", "prefix", *sysfsPrefix)
+        }
+    }
+    slog.Info("chemins sysfs", "hwmon", *basePath, "thermal", *thermalPath, "powercap", *raplPath, "cpu", *cpuPath)
+    if len(listenAddrs) == 0 {
+        listenAddrs = stringList{":9102"}
+    }
+    if len(sourcePriority) == 0 {
+        sourcePriority = stringList{"hwmon", "sensors-cli", "thermal"}
+    }
+    fahrenheit, kelvin, err := parseUnits(units)
     if err != nil {
-        return "", err
+        log.Fatalf("-units: %v", err)
+    }
+    var buckets []float64
+    if *histogram {
+        if buckets, err = parseBuckets(histogramBuckets); err != nil {
+            log.Fatalf("-histogram-buckets: %v", err)
+        }
+    } else if *histogramOnly {
+        log.Fatalf("-histogram-only nécessite -histogram")
     }
-    defer f.Close()
-    s := bufio.NewScanner(f)
-    if s.Scan() {
-        return strings.TrimSpace(s.Text()), nil
+    if err := checkConstLabels(extraLabels); err != nil {
+        log.Fatalf("-label: %v", err)
     }
-    if err := s.Err(); err != nil {
-        return "", err
+    // resolved once: the hostname is not expected to change while the exporter runs
+    if *hostnameLabel {
+        if _, ok := extraLabels["node"]; ok {
+            log.Fatalf("-hostname-label: le label node est déjà défini par -label")
+        }
+        node := *hostname
+        if node == "" {
+            if node, err = os.Hostname(); err != nil {
+                log.Fatalf("-hostname-label: %v (utilisez -hostname)", err)
+            }
+        }
+        extraLabels["node"] = node
+    }
+    if *enablePVELabels {
+        if node, cluster, ok := readPVEInfo(pveMembersPath); ok {
+            for k, v := range map[string]string{"pve_node": node, "pve_cluster": cluster} {
+                if _, dup := extraLabels[k]; dup {
+                    log.Fatalf("-enable-pve-labels: le label %s est déjà défini par -label", k)
+                }
+                if v != "" {
+                    extraLabels[k] = v
+                }
+            }
+        }
     }
-    return "", errors.New("empty file")
-}
 
-// discoverSensors scans basePath (default /sys/class/hwmon) to find temp*_input files and their labels.
-func discoverSensors(basePath string) ([]sensorReading, error) {
-    var sensors []sensorReading
-    // iterate hwmon devices
-    entries, err := os.ReadDir(basePath)
+    var basePaths, thermalPaths stringList
+    _ = basePaths.Set(*basePath)
+    _ = thermalPaths.Set(*thermalPath)
+
+    c, err := collector.New(collector.Options{
+        Namespace:        *namespace,
+        HwmonPaths:       basePaths,
+        ThermalPaths:     thermalPaths,
+        EnableHwmon:      *enableHwmon,
+        HwmonCache:       *hwmonDiscoveryTTL,
+        ReadConcurrency:  *readConcurrency,
+        ReadTimeout:      *readTimeout,
+        IncludeDisabled:  *includeDisabled,
+        HwmonTimeout:     *hwmonTimeout,
+        EnableThermal:    *enableThermal,
+        ThermalTimeout:   *thermalTimeout,
+        EnableSensorsCli: *enableSensorsCli,
+        SensorsCliPath:   *sensorsCliPath,
+        SensorsCliFormat: *sensorsCliFormat,
+        SensorsCliConfig: *sensorsCliConfig,
+        SensorsCliArgs:   strings.Fields(*sensorsCliArgs),
+        SensorsTimeout:   *sensorsTimeout,
+        EnableIPMI:       *enableIPMI,
+        IPMIBackend:      *ipmiBackend,
+        IPMIPath:         *ipmiPath,
+        IPMISensorsPath:  *ipmiSensorsPath,
+        IPMISDRCacheDir:  *ipmiSDRCacheDir,
+        IPMITimeout:      *ipmiTimeout,
+        IPMICache:        *ipmiCache,
+        EnableNvidia:     *enableNvidia,
+        NvidiaSmiPath:    *nvidiaSmiPath,
+        NvidiaTimeout:    *nvidiaTimeout,
+        EnableSysctl:     *enableSysctl,
+        SSHTargetsFile:   *sshTargetsFile,
+        EnableVcgencmd:   *enableVcgencmd,
+        VcgencmdPath:     *vcgencmdPath,
+        VcgencmdTimeout:  *vcgencmdTimeout,
+        EnableRapl:       *enableRapl,
+        RaplPath:         *raplPath,
+        EnableThrottle:   *enableThrottle,
+        CpuPath:          *cpuPath,
+        EnableStorcli:    *enableStorcli,
+        StorcliPath:      *storcliPath,
+        StorcliTimeout:   *storcliTimeout,
+        StorcliCache:     *storcliCache,
+        ApcupsdAddress:   *apcupsdAddress,
+        ApcupsdTimeout:   *apcupsdTimeout,
+        NutUPS:           nutUPS,
+        NutTimeout:       *nutTimeout,
+        EnableLiquidctl:  *enableLiquidctl,
+        LiquidctlPath:    *liquidctlPath,
+        LiquidctlTimeout: *liquidctlTimeout,
+        SourceLabel:      *sourceLabel,
+        Fahrenheit:       fahrenheit,
+        Kelvin:           kelvin,
+        PeakInterval:     *peakInterval,
+        CollectInterval:  *collectInterval,
+        StaleAfter:       *staleAfter,
+        ReadyThreshold:   *readyThreshold,
+        CorrectTctl:      *k10tempCorrect,
+        HistogramBuckets: buckets,
+        HistogramOnly:    *histogramOnly,
+        Timestamps:       *timestamps,
+        Round:            *precision >= 0,
+        Precision:        *precision,
+        DedupeCli:        *dedupeCli,
+        Dedupe:           *dedupe,
+        SourcePriority:   sourcePriority,
+        CalibrationFile:  *calibrationFile,
+        RenameFile:       *renameFile,
+        AlertRulesFile:   *alertRules,
+        MinValidTemp:     *minValidTemp,
+        MaxValidTemp:     *maxValidTemp,
+        DropZero:         *dropZero,
+        HwmonChips:       hwmonChips,
+        ThermalZones:     thermalZones,
+        SensorsCliChips:  sensorsCliChips,
+        ChipInclude:      chipInclude,
+        ChipExclude:      chipExclude,
+        SensorInclude:    sensorInclude,
+        SensorExclude:    sensorExclude,
+        DefaultBlocklist: !*noBlocklist,
+        BlocklistFile:    *blocklistFile,
+        LogFiltered:      *logFiltered,
+    })
     if err != nil {
-        return sensors, err
+        log.Fatalf("%v", err)
     }
-    for _, e := range entries {
-        if !e.IsDir() {
-            continue
+    // source errors are logged to stderr and only remove their own rows
+    if *listSensors {
+        switch *listFormat {
+        case "table", "json", "csv":
+        default:
+            log.Fatalf("-list-format: format %q inconnu (attendu: table, json ou csv)", *listFormat)
+        }
+        if err := c.WriteInventory(os.Stdout, *listFormat); err != nil {
+            log.Fatalf("-list-sensors: %v", err)
         }
-        chipDir := filepath.Join(basePath, e.Name())
-        // try to obtain a human friendly chip name
-        chipName := e.Name()
-        if n, err := readFirstLine(filepath.Join(chipDir, "name")); err == nil && n != "" {
-            chipName = n
+        return
+    }
+    // a first gather reports what each source found and catches calibration entries with a
+    // typo in chip or label; -once skips it, its single collection is the output itself
+    if !*once {
+        summary, total := c.Discover()
+        if *failOnNoSensors && total == 0 {
+            log.Fatalf("-fail-on-no-sensors: aucun capteur trouvé (%s); vérifiez que /sys est accessible (conteneur non privilégié, bind mount manquant) et les sources activées", summary)
         }
+    }
+    // the sensors live in their own registry so a collect[] scrape can replace them with a
+    // view restricted to some sources while keeping the exporter's own metrics from reg
+    reg := prometheus.NewRegistry()
+    sensorReg := prometheus.NewRegistry()
+    gatherer := prometheus.Gatherers{sensorReg, reg}
+    // every metric registered through the wrapper, present or future, carries the -label pairs
+    registerer := prometheus.WrapRegistererWith(prometheus.Labels(extraLabels), reg)
+    prometheus.WrapRegistererWith(prometheus.Labels(extraLabels), sensorReg).MustRegister(c)
+    buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+        Namespace: *namespace,
+        Name:      "build_info",
+        Help:      "Version de l'exporter (version, commit, date de compilation et version de Go), toujours 1.",
+    }, []string{"version", "commit", "date", "goversion"})
+    buildInfo.WithLabelValues(version, commit, date, runtime.Version()).Set(1)
+    registerer.MustRegister(buildInfo)
+    registerer.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+        Namespace: *namespace,
+        Name:      "ready",
+        Help:      "1 quand l'exporter est prêt (/readyz): un capteur a été trouvé et les dernières collectes n'ont pas toutes échoué.",
+    }, func() float64 {
+        if c.Ready() {
+            return 1
+        }
+        return 0
+    }))
+    // off by default so the output stays the same for existing dashboards
+    if *runtimeMetrics {
+        registerer.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+    }
 
-        // list files to find temp*_input
-        files, err := os.ReadDir(chipDir)
-        if err != nil {
-            // ignore unreadable chips, continue
-            continue
+    var rw *collector.RemoteWriter
+    if *rwURL != "" && !*once {
+        cfg := collector.RemoteWriteConfig{
+            URL:        *rwURL,
+            Interval:   *rwInterval,
+            Timeout:    *rwTimeout,
+            User:       *rwUser,
+            Job:        *rwJob,
+            Instance:   *rwInstance,
+            QueueSize:  *rwQueue,
+            MaxRetries: *rwRetries,
         }
-        for _, f := range files {
-            fname := f.Name()
-            if !strings.HasPrefix(fname, "temp") || !strings.HasSuffix(fname, "_input") {
-                continue
+        if *rwInterval <= 0 || *rwQueue < 1 || *rwRetries < 0 {
+            log.Fatalf("-remote-write-interval et -remote-write-queue-size doivent être positifs, -remote-write-max-retries au moins 0")
+        }
+        if cfg.User != "" {
+            if *rwPassFile == "" {
+                log.Fatalf("-remote-write-user nécessite -remote-write-password-file")
             }
-            // extract index between temp and _input
-            idx := strings.TrimSuffix(strings.TrimPrefix(fname, "temp"), "_input")
-            label := ""
-            // prefer temp{idx}_label when available
-            if l, err := readFirstLine(filepath.Join(chipDir, fmt.Sprintf("temp%v_label", idx))); err == nil {
-                label = l
-            } else if tname, err := readFirstLine(filepath.Join(chipDir, fmt.Sprintf("temp%v_type", idx))); err == nil {
-                // fallback to type (like Tctl, Tdie)
-                label = tname
+            if cfg.Password, err = readPasswordFile(*rwPassFile); err != nil {
+                log.Fatalf("-remote-write-password-file: %v", err)
             }
-            // sensor name from chip name
-            sensorName := chipName
-            sensors = append(sensors, sensorReading{
-                chip:   chipName,
-                name:   sensorName,
-                label:  label,
-                path:   filepath.Join(chipDir, fname),
-                factor: 0.001, // default millidegree to degree
-            })
-        }
-    }
-    return sensors, nil
-}
-
-// discoverThermalSensors scans /sys/class/thermal for thermal_zone*/temp
-func discoverThermalSensors(thermalBase string) ([]sensorReading, error) {
-    var sensors []sensorReading
-    entries, err := os.ReadDir(thermalBase)
-    if err != nil {
-        return sensors, err
-    }
-    for _, e := range entries {
-        if !e.IsDir() || !strings.HasPrefix(e.Name(), "thermal_zone") {
-            continue
-        }
-        zoneDir := filepath.Join(thermalBase, e.Name())
-        ttype, _ := readFirstLine(filepath.Join(zoneDir, "type"))
-        // Some systems have trip points; we only read current temp
-        tempPath := filepath.Join(zoneDir, "temp")
-        if _, err := os.Stat(tempPath); err == nil {
-            sensors = append(sensors, sensorReading{
-                chip:   "thermal",
-                name:   ttype,
-                label:  e.Name(),
-                path:   tempPath,
-                factor: 0.001,
-            })
-        }
-    }
-    return sensors, nil
-}
-
-type cliReading struct {
-    chip  string
-    name  string
-    label string
-    value float64
-}
+        } else if *rwTokenFile != "" {
+            if cfg.Token, err = readPasswordFile(*rwTokenFile); err != nil {
+                log.Fatalf("-remote-write-bearer-token-file: %v", err)
+            }
+        }
+        if cfg.Instance == "" {
+            cfg.Instance, _ = os.Hostname()
+        }
+        rw = collector.NewRemoteWriter(cfg, *namespace, gatherer)
+        registerer.MustRegister(rw.Collectors()...)
+    }
 
-// discoverSensorsCLI runs `sensors -j` and parses temperatures generically.
-func discoverSensorsCLI(bin string, timeout time.Duration) ([]cliReading, error) {
-    ctx, cancel := context.WithTimeout(context.Background(), timeout)
-    defer cancel()
-    cmd := exec.CommandContext(ctx, bin, "-j")
-    out, err := cmd.Output()
-    if err != nil {
-        return nil, err
-    }
-    var root map[string]interface{}
-    if err := json.Unmarshal(out, &root); err != nil {
-        return nil, err
-    }
-    var res []cliReading
-    // regex to match tempN_input
-    re := regexp.MustCompile(`^temp(\d+)_input$`)
-    // walk the structure
-    for chip, v := range root {
-        m, ok := v.(map[string]interface{})
-        if !ok {
-            continue
+    var iw *collector.InfluxWriter
+    if (*influxURL != "" || *influxUDP != "") && !*once {
+        if *influxURL != "" && *influxUDP != "" {
+            log.Fatalf("-influxdb-url et -influxdb-udp sont exclusifs")
         }
-        // second-level: sections like Tctl, Composite, etc.
-        for section, sv := range m {
-            sm, ok := sv.(map[string]interface{})
-            if !ok {
-                continue
-            }
-            // find keys tempN_input
-            for k, val := range sm {
-                match := re.FindStringSubmatch(k)
-                if match == nil {
-                    continue
-                }
-                // parse value as float64
-                var f float64
-                switch tv := val.(type) {
-                case float64:
-                    f = tv
-                case int:
-                    f = float64(tv)
-                case json.Number:
-                    ff, _ := tv.Float64()
-                    f = ff
-                default:
-                    continue
-                }
-                // find optional label in same section
-                idx := match[1]
-                labelKey := fmt.Sprintf("temp%v_label", idx)
-                label := ""
-                if lv, ok := sm[labelKey]; ok {
-                    if s, ok := lv.(string); ok {
-                        label = s
-                    }
-                }
-                res = append(res, cliReading{
-                    chip:  chip,
-                    name:  section,
-                    label: label,
-                    value: f, // already in degree C
-                })
+        if *influxInterval <= 0 {
+            log.Fatalf("-influxdb-interval doit être positif")
+        }
+        cfg := collector.InfluxConfig{
+            URL:      *influxURL,
+            Org:      *influxOrg,
+            Bucket:   *influxBucket,
+            UDPAddr:  *influxUDP,
+            Host:     *hostname,
+            Interval: *influxInterval,
+            Timeout:  *influxTimeout,
+        }
+        if cfg.URL != "" && cfg.Bucket == "" {
+            log.Fatalf("-influxdb-url nécessite -influxdb-bucket")
+        }
+        if *influxTokenFile != "" {
+            if cfg.Token, err = readPasswordFile(*influxTokenFile); err != nil {
+                log.Fatalf("-influxdb-token-file: %v", err)
             }
         }
+        if cfg.Host == "" {
+            cfg.Host, _ = os.Hostname()
+        }
+        iw = collector.NewInfluxWriter(cfg, c)
+        registerer.MustRegister(iw.Collectors()...)
     }
-    return res, nil
-}
 
-func (c *collector) Collect(ch chan<- prometheus.Metric) {
-    start := time.Now()
-    // for robustness, re-discover each scrape to account for hotplug; for large systems we could cache with ttl
-    var sensors []sensorReading
-    if c.enableHwmon {
-        if s, err := discoverSensors(c.basePath); err == nil {
-            sensors = append(sensors, s...)
-        } else {
-            log.Printf("discoverSensors error: %v", err)
-        }
-    }
-    if c.enableThermal {
-        if s, err := discoverThermalSensors(c.thermalPath); err == nil {
-            sensors = append(sensors, s...)
-        } else {
-            log.Printf("discoverThermalSensors error: %v", err)
-        }
-    }
-    // reset gaugevec by recreating a new one each collection is heavy; instead, we use Reset before setting new
-    c.sensors.Reset()
+    var gw *collector.GraphiteWriter
+    if (*graphiteAddr != "" || *graphiteDryRun) && !*once {
+        if *graphiteProto != "tcp" && *graphiteProto != "udp" {
+            log.Fatalf("-graphite-protocol: %q inconnu (attendu: tcp ou udp)", *graphiteProto)
+        }
+        if *graphiteInterval <= 0 {
+            log.Fatalf("-graphite-interval doit être positif")
+        }
+        cfg := collector.GraphiteConfig{
+            Address:  *graphiteAddr,
+            Protocol: *graphiteProto,
+            Prefix:   strings.Trim(*graphitePrefix, "."),
+            Host:     *hostname,
+            Interval: *graphiteInterval,
+            Timeout:  *graphiteTimeout,
+            DryRun:   *graphiteDryRun,
+        }
+        if cfg.Host == "" {
+            cfg.Host, _ = os.Hostname()
+        }
+        gw = collector.NewGraphiteWriter(cfg, c)
+        registerer.MustRegister(gw.Collectors()...)
+    }
 
-    for _, s := range sensors {
-        raw, err := readFirstLine(s.path)
-        if err != nil {
-            // ignore missing/permission issues gracefully
-            continue
+    var al *collector.Alerter
+    if c.AlertRulesFile != "" && !*once {
+        if *alertInterval <= 0 {
+            log.Fatalf("-alert-interval doit être positif")
         }
-        // Some drivers expose value in millidegree; tolerate empty/non-number
-        v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
-        if err != nil {
-            continue
+        host := *hostname
+        if host == "" {
+            host, _ = os.Hostname()
         }
-        tempC := v * s.factor
-        c.sensors.WithLabelValues(s.chip, s.name, s.label).Set(tempC)
+        al = collector.NewAlerter(c, *alertWebhook, host, *alertInterval, *alertTimeout)
+        registerer.MustRegister(al.Collectors()...)
+    } else if *alertWebhook != "" {
+        log.Fatalf("-alert-webhook-url nécessite -alert-rules-file")
     }
 
-    // Also collect via sensors -j if enabled
-    if c.enableSensorsCli {
-        if readings, err := discoverSensorsCLI(c.sensorsCliPath, c.sensorsTimeout); err == nil {
-            for _, r := range readings {
-                c.sensors.WithLabelValues(r.chip, r.name, r.label).Set(r.value)
+    var mw *collector.MQTTWriter
+    if *mqttBroker != "" && !*once {
+        if *mqttQoS < 0 || *mqttQoS > 2 {
+            log.Fatalf("-mqtt-qos: %d invalide (attendu: 0, 1 ou 2)", *mqttQoS)
+        }
+        if *mqttInterval <= 0 {
+            log.Fatalf("-mqtt-interval doit être positif")
+        }
+        cfg := collector.MQTTConfig{
+            Prefix:   strings.Trim(*mqttPrefix, "/"),
+            Host:     *hostname,
+            ClientID: *mqttClientID,
+            User:     *mqttUser,
+            QoS:      byte(*mqttQoS),
+            Interval: *mqttInterval,
+            Timeout:  *mqttTimeout,
+        }
+        if cfg.Broker, cfg.UseTLS, err = collector.ParseMQTTBroker(*mqttBroker); err != nil {
+            log.Fatalf("-mqtt-broker: %v", err)
+        }
+        if cfg.UseTLS {
+            if cfg.TLS, err = collector.MQTTTLSConfig(cfg.Broker, *mqttCAFile, *mqttInsecure); err != nil {
+                log.Fatalf("-mqtt-tls-ca-file: %v", err)
             }
-        } else {
-            if !sensorsCliWarned {
-                log.Printf("discoverSensorsCLI error: %v (désactivez -enable-sensors-cli ou installez lm-sensors)", err)
-                sensorsCliWarned = true
+        }
+        if *mqttPassFile != "" {
+            if cfg.Password, err = readPasswordFile(*mqttPassFile); err != nil {
+                log.Fatalf("-mqtt-password-file: %v", err)
             }
         }
+        if cfg.Host == "" {
+            cfg.Host, _ = os.Hostname()
+        }
+        if cfg.ClientID == "" {
+            cfg.ClientID = "temperature-exporter-" + cfg.Host
+        }
+        if *mqttHA {
+            cfg.HAPrefix = strings.Trim(*mqttHAPrefix, "/")
+        }
+        mw = collector.NewMQTTWriter(cfg, c)
+        registerer.MustRegister(mw.Collectors()...)
     }
 
-    // export metrics
-    c.sensors.Collect(ch)
-    c.scrapeTime.Set(time.Since(start).Seconds())
-    c.scrapeTime.Collect(ch)
-}
-
-// loggingResponseWriter wraps http.ResponseWriter to capture status code
-type loggingResponseWriter struct {
-    http.ResponseWriter
-    status int
-}
-
-func (lrw *loggingResponseWriter) WriteHeader(code int) {
-    lrw.status = code
-    lrw.ResponseWriter.WriteHeader(code)
-}
+    if *once {
+        ok, err := c.CollectOnce(os.Stdout, gatherer)
+        if err != nil {
+            log.Fatalf("-once: %v", err)
+        }
+        if !ok {
+            log.Fatalf("-once: aucune source n'a pu être collectée")
+        }
+        return
+    }
 
-func withRequestLogging(next http.Handler) http.Handler {
-    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        start := time.Now()
-        lrw := &loggingResponseWriter{ResponseWriter: w, status: 200}
-        next.ServeHTTP(lrw, r)
-        dur := time.Since(start)
-        log.Printf("%s %s -> %d (%s)", r.Method, r.URL.Path, lrw.status, dur)
-    })
-}
+    // with -collect-interval the first snapshot is taken before serving, scrapes never wait on a gather
+    c.Start()
 
-func main() {
-    var (
-        listenAddr = flag.String("listen", ":9102", "Adresse d'écoute HTTP, ex : :9102")
-        metricsPath = flag.String("path", "/metrics", "Chemin HTTP pour exposer les métriques")
-        basePath    = flag.String("hwmon", "/sys/class/hwmon", "Chemin de base vers les capteurs hwmon")
-        thermalPath = flag.String("thermal", "/sys/class/thermal", "Chemin de base vers les zones thermiques (thermal zones)")
-        enableHwmon = flag.Bool("enable-hwmon", true, "Activer la lecture via hwmon (/sys/class/hwmon)")
-        enableThermal = flag.Bool("enable-thermal", true, "Activer la lecture via thermal zones (/sys/class/thermal)")
-    enableSensorsCli = flag.Bool("enable-sensors-cli", true, "Activer la lecture via 'sensors -j' (nécessite lm-sensors)")
-        sensorsCliPath = flag.String("sensors-cli-path", "sensors", "Chemin de la commande 'sensors'")
-        sensorsTimeout = flag.Duration("sensors-timeout", 2*time.Second, "Timeout pour l'exécution de 'sensors -j'")
-        namespace   = flag.String("namespace", "temp_exporter", "Préfixe des métriques Prometheus")
-        timeout     = flag.Duration("read-timeout", 5*time.Second, "Timeout lecture HTTP")
-        writeTO     = flag.Duration("write-timeout", 10*time.Second, "Timeout écriture HTTP")
-        readHdrTO   = flag.Duration("read-header-timeout", 5*time.Second, "Timeout lecture des en-têtes HTTP")
-        idleTO      = flag.Duration("idle-timeout", 30*time.Second, "Timeout idle HTTP")
-        logRequests = flag.Bool("log-requests", false, "Journaliser les requêtes HTTP (méthode, chemin, statut, durée)")
-    )
-    flag.Parse()
-
-    c := newCollector(*basePath, *thermalPath, *enableHwmon, *enableThermal, *enableSensorsCli, *sensorsCliPath, *sensorsTimeout, *namespace)
-    reg := prometheus.NewRegistry()
-    reg.MustRegister(c)
+    if *maxRequests < 0 {
+        log.Fatalf("-max-requests ne peut pas être négatif")
+    }
+    rejected := prometheus.NewCounterVec(prometheus.CounterOpts{
+        Namespace: *namespace,
+        Name:      "scrapes_rejected_total",
+        Help:      "Scrapes refusés au-delà de -max-requests (reason=limit) ou interrompus à l'expiration de leur délai (reason=timeout).",
+    }, []string{"reason"})
+    rejected.WithLabelValues("limit")
+    rejected.WithLabelValues("timeout")
+    registerer.MustRegister(rejected)
+    metricsHandler := c.ScrapeHandler(reg, prometheus.Labels(extraLabels), *maxRequests, *writeTO, rejected)
+    // only the metrics, sensors, API, status, peak reset and pprof paths are protected, /healthz, /readyz and /livez stay open for load balancers
+    protect := func(h http.Handler) http.Handler { return h }
+    if *authUser != "" {
+        if *authPassFile == "" {
+            log.Fatalf("-auth-user nécessite -auth-password-file")
+        }
+        password, err := readPasswordFile(*authPassFile)
+        if err != nil {
+            log.Fatalf("-auth-password-file: %v", err)
+        }
+        authFailures := prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: *namespace,
+            Name:      "http_auth_failures_total",
+            Help:      "Nombre de requêtes refusées faute d'identifiants basic auth valides.",
+        })
+        registerer.MustRegister(authFailures)
+        protect = func(h http.Handler) http.Handler {
+            return withBasicAuth(h, *authUser, password, authFailures)
+        }
+    }
 
     mux := http.NewServeMux()
-    mux.Handle(*metricsPath, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
-    mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+    mux.Handle(*metricsPath, protect(metricsHandler))
+    mux.Handle("/sensors", protect(c.SensorsHandler()))
+    mux.Handle("/api/v1/temperatures", protect(c.TemperaturesHandler()))
+    if !*noStatusPage {
+        mux.Handle("/status", protect(c.StatusHandler()))
+    }
+    if c.PeakInterval > 0 {
+        mux.Handle("/-/reset-peaks", protect(c.ResetPeaksHandler()))
+    }
+    if *clearIntrusion {
+        mux.Handle("/-/clear-intrusion", protect(c.ClearIntrusionHandler()))
+    }
+    // profiles go on their own listener when asked, free of -write-timeout which cuts a 30s CPU profile
+    var pprofLn net.Listener
+    if *pprofListen != "" {
+        if !*enablePprof {
+            log.Fatalf("-pprof-listen nécessite -enable-pprof")
+        }
+        if pprofLn, err = listenPprof(*pprofListen); err != nil {
+            log.Fatalf("-pprof-listen: %v", err)
+        }
+    } else if *enablePprof {
+        mux.Handle("/debug/pprof/", protect(pprofHandler()))
+    }
+    mux.Handle("/healthz", c.HealthHandler(*healthReadings))
+    mux.Handle("/readyz", c.ReadyHandler())
+    mux.HandleFunc("/livez", func(w http.ResponseWriter, _ *http.Request) {
         w.WriteHeader(http.StatusOK)
         _, _ = w.Write([]byte("ok"))
     })
+    if len(peerList) > 0 {
+        peers, err := collector.ParsePeers(peerList)
+        if err != nil {
+            log.Fatalf("-peers: %v", err)
+        }
+        node := extraLabels["node"]
+        if node == "" {
+            node = *hostname
+        }
+        if node == "" {
+            if node, err = os.Hostname(); err != nil {
+                log.Fatalf("-peers: %v (utilisez -hostname)", err)
+            }
+        }
+        mux.Handle("/all", protect(c.AllHandler(reg, prometheus.Labels(extraLabels), collector.PeersConfig{
+            Peers:        peers,
+            Node:         node,
+            Timeout:      *peersTimeout,
+            WriteTimeout: *writeTO,
+        })))
+    }
     // Root helper to avoid 404 confusion in browsers
     mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
         if r.URL.Path != "/" { // keep other paths as 404 to not confuse scraping
@@ -369,16 +672,24 @@ func main() {
             return
         }
         w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-        _, _ = fmt.Fprintf(w, "Temperature Exporter\nMetrics: %s\nHealth: /healthz\n", *metricsPath)
+        _, _ = fmt.Fprintf(w, "Temperature Exporter\nMetrics: %s\nSensors: /sensors\nAPI: /api/v1/temperatures\nHealth: /healthz\nReadiness: /readyz\nLiveness: /livez\n", *metricsPath)
+        if !*noStatusPage {
+            _, _ = fmt.Fprintln(w, "Status: /status")
+        }
     })
 
     var handler http.Handler = mux
     if *logRequests {
-        handler = withRequestLogging(mux)
+        trusted, err := parseTrustedProxies(trustedProxies)
+        if err != nil {
+            log.Fatalf("-trusted-proxies: %v", err)
+        }
+        var exclude stringList
+        _ = exclude.Set(*logReqExcl)
+        handler = withRequestLogging(mux, exclude, trusted)
     }
 
     srv := &http.Server{
-        Addr:              *listenAddr,
         Handler:           handler,
         ReadTimeout:       *timeout,
         WriteTimeout:      *writeTO,
@@ -386,27 +697,138 @@ func main() {
         IdleTimeout:       *idleTO,
     }
 
-    log.Printf("Starting temperature exporter %s (commit %s, built %s) on %s%s (hwmon path: %s)", version, commit, date, *listenAddr, *metricsPath, *basePath)
+    useTLS := *tlsCert != "" || *tlsKey != ""
+    if *webConfig != "" {
+        if useTLS || *authUser != "" {
+            log.Fatalf("--web.config.file remplace -tls-cert/-tls-key et -auth-user, ne pas les combiner")
+        }
+        if err := web.Validate(*webConfig); err != nil {
+            log.Fatalf("--web.config.file: %v", err)
+        }
+    }
+    if useTLS {
+        if *tlsCert == "" || *tlsKey == "" {
+            log.Fatalf("TLS: -tls-cert et -tls-key doivent être fournis ensemble")
+        }
+        minVer, err := tlsVersion(*tlsMinVer)
+        if err != nil {
+            log.Fatalf("-tls-min-version: %v", err)
+        }
+        cr, err := newCertReloader(*tlsCert, *tlsKey)
+        if err != nil {
+            log.Fatalf("TLS: chargement de %s / %s impossible: %v", *tlsCert, *tlsKey, err)
+        }
+        srv.TLSConfig = &tls.Config{MinVersion: minVer, GetCertificate: cr.getCertificate}
+    }
 
-    // Start server in background
-    errCh := make(chan error, 1)
+    // bind every address up front so a busy port or a typo fails startup, naming the address
+    var listeners []net.Listener
+    if *webConfig == "" {
+        for _, addr := range listenAddrs {
+            ln, err := net.Listen("tcp", addr)
+            if err != nil {
+                log.Fatalf("-listen %s: %v", addr, err)
+            }
+            listeners = append(listeners, ln)
+        }
+    }
+
+    slog.Info("Starting temperature exporter", "version", version, "commit", commit, "built", date,
+        "listen", strings.Join(listenAddrs, ", "), "metrics_path", *metricsPath, "hwmon_path", strings.Join(c.HwmonPaths, ", "))
+
+    // Start server in background; every listener shares srv, so Shutdown closes them all
+    errCh := make(chan error, len(listeners)+2)
+    if *webConfig != "" {
+        go func() {
+            // the toolkit handles TLS and basic auth from the same YAML as node_exporter
+            systemdSocket := false
+            addrs := []string(listenAddrs)
+            err := web.ListenAndServe(srv, &web.FlagConfig{
+                WebListenAddresses: &addrs,
+                WebSystemdSocket:   &systemdSocket,
+                WebConfigFile:      webConfig,
+            }, slog.Default())
+            if err != nil && !errors.Is(err, http.ErrServerClosed) {
+                errCh <- err
+            }
+        }()
+    }
+    for _, ln := range listeners {
+        go func(ln net.Listener) {
+            var err error
+            if useTLS {
+                // the certificate comes from TLSConfig.GetCertificate
+                err = srv.ServeTLS(ln, "", "")
+            } else {
+                err = srv.Serve(ln)
+            }
+            if err != nil && !errors.Is(err, http.ErrServerClosed) {
+                errCh <- fmt.Errorf("%s: %w", ln.Addr(), err)
+            }
+        }(ln)
+    }
+    if pprofLn != nil {
+        slog.Info("pprof: profils servis", "url", fmt.Sprintf("http://%s/debug/pprof/", pprofLn.Addr()))
+        go func() {
+            psrv := &http.Server{Handler: protect(pprofHandler()), ReadHeaderTimeout: *readHdrTO}
+            errCh <- fmt.Errorf("pprof %s: %w", pprofLn.Addr(), psrv.Serve(pprofLn))
+        }()
+    }
+
+    // listeners are bound and the registry is ready: tell systemd (Type=notify)
+    if rw != nil {
+        go rw.Run(c.Context())
+    }
+    if iw != nil {
+        go iw.Run(c.Context())
+    }
+    if gw != nil {
+        go gw.Run(c.Context())
+    }
+    if mw != nil {
+        go mw.Run(c.Context())
+    }
+    if al != nil {
+        go al.Run(c.Context())
+    }
+    sdNotify(daemon.SdNotifyReady)
+    startWatchdog(c)
+
+    // SIGHUP reloads the rule files while the listener and registry keep serving
+    hupCh := make(chan os.Signal, 1)
+    signal.Notify(hupCh, syscall.SIGHUP)
     go func() {
-        if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-            errCh <- err
+        for range hupCh {
+            c.Reload()
         }
-        close(errCh)
     }()
+    // SIGUSR2 clears the peaks, like POST /-/reset-peaks
+    if c.PeakInterval > 0 {
+        usr2Ch := make(chan os.Signal, 1)
+        signal.Notify(usr2Ch, syscall.SIGUSR2)
+        go func() {
+            for range usr2Ch {
+                c.ResetPeaks()
+            }
+        }()
+    }
 
     // Handle termination signals for graceful shutdown
     sigCh := make(chan os.Signal, 1)
     signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
     select {
     case sig := <-sigCh:
-        log.Printf("Received signal %s, shutting down...", sig)
+        slog.Info("Received signal, shutting down...", "signal", sig.String())
+        sdNotify(daemon.SdNotifyStopping)
         ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
         defer cancel()
+        // kill running commands first so in-flight scrapes return instead of holding Shutdown
+        c.Stop()
         if err := srv.Shutdown(ctx); err != nil {
-            log.Printf("HTTP server Shutdown: %v", err)
+            slog.Error("HTTP server Shutdown", "err", err)
+        }
+        if err := c.Wait(ctx); err != nil {
+            slog.Warn("collections still running at shutdown", "err", err)
         }
     case err := <-errCh:
         if err != nil {
@@ -0,0 +1,36 @@
+package main
+
+import (
+    "log/slog"
+    "time"
+
+    "github.com/coreos/go-systemd/v22/daemon"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/collector"
+)
+
+// sdNotify sends a state to systemd; it does nothing when NOTIFY_SOCKET is unset.
+func sdNotify(state string) {
+    if _, err := daemon.SdNotify(false, state); err != nil {
+        slog.Warn("sd_notify", "state", state, "err", err)
+    }
+}
+
+// startWatchdog pings systemd at half of WatchdogSec as long as no collection of c has been
+// running for longer than the watchdog interval, so a wedged sensors binary gets the service
+// restarted. It is a no-op when WATCHDOG_USEC is unset.
+func startWatchdog(c *collector.Collector) {
+    interval, err := daemon.SdWatchdogEnabled(false)
+    if err != nil || interval == 0 {
+        return
+    }
+    go func() {
+        for range time.Tick(interval / 2) {
+            if running := c.RunningFor(); running > interval {
+                slog.Warn("collection still running, watchdog not notified", "running", running.Round(time.Second))
+                continue
+            }
+            sdNotify(daemon.SdNotifyWatchdog)
+        }
+    }()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+    "fmt"
+    "net"
+    "net/http"
+    "net/http/pprof"
+)
+
+// pprofHandler serves the net/http/pprof profiles under /debug/pprof/. They are registered on
+// their own mux rather than through http.DefaultServeMux, which the exporter never serves.
+func pprofHandler() http.Handler {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/debug/pprof/", pprof.Index)
+    mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+    mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+    mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+    mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+    return mux
+}
+
+// listenPprof binds the -pprof-listen address, which must be a loopback one: profiles expose
+// the command line and memory contents, and --web.config.file does not cover this listener
+func listenPprof(addr string) (net.Listener, error) {
+    host, _, err := net.SplitHostPort(addr)
+    if err != nil {
+        return nil, err
+    }
+    if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+        return nil, fmt.Errorf("%s n'est pas une adresse locale (127.0.0.1, ::1 ou localhost)", addr)
+    }
+    return net.Listen("tcp", addr)
+}
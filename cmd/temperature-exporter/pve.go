@@ -0,0 +1,28 @@
+package main
+
+import (
+    "encoding/json"
+    "os"
+)
+
+// pveMembersPath is maintained by pmxcfs on every Proxmox VE node, clustered or not
+const pveMembersPath = "/etc/pve/.members"
+
+// readPVEInfo returns the local node name and, when the node is part of a cluster, the cluster
+// name. ok is false on machines without /etc/pve.
+func readPVEInfo(path string) (node, cluster string, ok bool) {
+    raw, err := os.ReadFile(path)
+    if err != nil {
+        return "", "", false
+    }
+    var members struct {
+        Nodename string `json:"nodename"`
+        Cluster  struct {
+            Name string `json:"name"`
+        } `json:"cluster"`
+    }
+    if err := json.Unmarshal(raw, &members); err != nil || members.Nodename == "" {
+        return "", "", false
+    }
+    return members.Nodename, members.Cluster.Name, true
+}
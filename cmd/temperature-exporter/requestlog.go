@@ -0,0 +1,110 @@
+package main
+
+import (
+    "fmt"
+    "log/slog"
+    "net"
+    "net/http"
+    "net/netip"
+    "slices"
+    "strings"
+    "time"
+)
+
+// loggingResponseWriter wraps http.ResponseWriter to capture the status code and the body size
+type loggingResponseWriter struct {
+    http.ResponseWriter
+    status int // 0 until WriteHeader or the first Write
+    bytes  int
+}
+
+func (lrw *loggingResponseWriter) WriteHeader(code int) {
+    if lrw.status == 0 {
+        lrw.status = code
+    }
+    lrw.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the implicit 200 net/http sends when the handler never called WriteHeader
+func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
+    if lrw.status == 0 {
+        lrw.status = http.StatusOK
+    }
+    n, err := lrw.ResponseWriter.Write(b)
+    lrw.bytes += n
+    return n, err
+}
+
+// Unwrap lets http.ResponseController reach the connection (Flush, deadlines)
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+    return lrw.ResponseWriter
+}
+
+// withRequestLogging logs every request but those for the exclude paths (load balancer probes).
+// The client is the peer address, or the X-Forwarded-For address added by the trusted proxies.
+func withRequestLogging(next http.Handler, exclude []string, trusted []netip.Prefix) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if slices.Contains(exclude, r.URL.Path) {
+            next.ServeHTTP(w, r)
+            return
+        }
+        start := time.Now()
+        lrw := &loggingResponseWriter{ResponseWriter: w}
+        next.ServeHTTP(lrw, r)
+        if lrw.status == 0 {
+            lrw.status = http.StatusOK
+        }
+        slog.Info("requête HTTP", "method", r.Method, "path", r.URL.Path, "status", lrw.status, "bytes", lrw.bytes,
+            "duration_ms", float64(time.Since(start).Microseconds())/1000, "remote", clientAddr(r, trusted), "user_agent", r.UserAgent())
+    })
+}
+
+// clientAddr walks X-Forwarded-For from the right while the hop is a trusted proxy, so a client
+// cannot spoof its address by sending the header itself
+func clientAddr(r *http.Request, trusted []netip.Prefix) string {
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    var hops []string
+    for _, h := range r.Header.Values("X-Forwarded-For") {
+        for _, p := range strings.Split(h, ",") {
+            hops = append(hops, strings.TrimSpace(p))
+        }
+    }
+    for len(hops) > 0 && isTrusted(host, trusted) {
+        host, hops = hops[len(hops)-1], hops[:len(hops)-1]
+    }
+    return host
+}
+
+func isTrusted(host string, trusted []netip.Prefix) bool {
+    ip, err := netip.ParseAddr(host)
+    if err != nil {
+        return false
+    }
+    ip = ip.Unmap()
+    for _, p := range trusted {
+        if p.Contains(ip) {
+            return true
+        }
+    }
+    return false
+}
+
+// parseTrustedProxies reads the -trusted-proxies list of addresses and CIDR ranges
+func parseTrustedProxies(list []string) ([]netip.Prefix, error) {
+    var out []netip.Prefix
+    for _, s := range list {
+        if p, err := netip.ParsePrefix(s); err == nil {
+            out = append(out, p.Masked())
+            continue
+        }
+        ip, err := netip.ParseAddr(s)
+        if err != nil {
+            return nil, fmt.Errorf("%q n'est ni une adresse IP ni une plage CIDR", s)
+        }
+        out = append(out, netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()))
+    }
+    return out, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+    "crypto/tls"
+    "fmt"
+    "log/slog"
+    "os"
+    "sync"
+    "time"
+)
+
+// certReloader serves the key pair from disk and re-reads it when either file changes,
+// so a Let's Encrypt renewal is picked up without a restart.
+type certReloader struct {
+    certFile string
+    keyFile  string
+    mu       sync.Mutex
+    cert     *tls.Certificate
+    modTime  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+    cr := &certReloader{certFile: certFile, keyFile: keyFile}
+    if err := cr.load(); err != nil {
+        return nil, err
+    }
+    return cr, nil
+}
+
+// latestModTime returns the most recent modification time of the certificate and key
+func (cr *certReloader) latestModTime() (time.Time, error) {
+    var latest time.Time
+    for _, f := range []string{cr.certFile, cr.keyFile} {
+        st, err := os.Stat(f)
+        if err != nil {
+            return time.Time{}, err
+        }
+        if st.ModTime().After(latest) {
+            latest = st.ModTime()
+        }
+    }
+    return latest, nil
+}
+
+func (cr *certReloader) load() error {
+    mod, err := cr.latestModTime()
+    if err != nil {
+        return err
+    }
+    cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+    if err != nil {
+        return err
+    }
+    cr.cert, cr.modTime = &cert, mod
+    return nil
+}
+
+// getCertificate is the tls.Config hook; a broken renewal keeps serving the previous pair.
+func (cr *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+    cr.mu.Lock()
+    defer cr.mu.Unlock()
+    if mod, err := cr.latestModTime(); err == nil && mod.After(cr.modTime) {
+        if err := cr.load(); err != nil {
+            slog.Warn("TLS: rechargement du certificat impossible, l'ancien reste utilisé", "err", err)
+        } else {
+            slog.Info("TLS: certificat rechargé", "file", cr.certFile)
+        }
+    }
+    return cr.cert, nil
+}
+
+// tlsVersion maps the -tls-min-version flag to its crypto/tls constant
+func tlsVersion(v string) (uint16, error) {
+    switch v {
+    case "1.2":
+        return tls.VersionTLS12, nil
+    case "1.3":
+        return tls.VersionTLS13, nil
+    }
+    return 0, fmt.Errorf("version %q inconnue (attendu: 1.2 ou 1.3)", v)
+}
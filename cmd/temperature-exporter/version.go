@@ -0,0 +1,26 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "runtime"
+)
+
+// versionInfo is the -version-json output, stable for configuration management tools
+type versionInfo struct {
+    Version   string `json:"version"`
+    Commit    string `json:"commit"`
+    Date      string `json:"date"`
+    GoVersion string `json:"go_version"`
+}
+
+// printVersion writes the build information set through -ldflags, as text or as JSON
+func printVersion(w io.Writer, asJSON bool) error {
+    info := versionInfo{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+    if asJSON {
+        return json.NewEncoder(w).Encode(info)
+    }
+    _, err := fmt.Fprintf(w, "temperature-exporter %s (commit %s, built %s, %s)\n", info.Version, info.Commit, info.Date, info.GoVersion)
+    return err
+}
@@ -0,0 +1,285 @@
+package collector
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log/slog"
+    "math"
+    "net/http"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultAlertHysteresis is how far below its threshold a firing sensor must drop to resolve,
+// when the rule leaves the column empty
+const defaultAlertHysteresis = 2.0
+
+// alertRule sets warn and crit thresholds on the temperatures whose chip and label match.
+// An unset threshold is NaN.
+type alertRule struct {
+    chip       *regexp.Regexp
+    label      *regexp.Regexp
+    warn       float64
+    crit       float64
+    hold       time.Duration
+    hysteresis float64
+}
+
+// loadAlertRules reads a "chip_regex,label_regex,warn,crit,hold,hysteresis" CSV. Regexes are
+// anchored and an empty one matches anything; warn or crit may be left empty, hold is a Go
+// duration (empty for none) and hysteresis is in °C. Lines starting with # are comments.
+func loadAlertRules(path string) ([]alertRule, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    r := csv.NewReader(f)
+    r.Comment = '#'
+    r.FieldsPerRecord = 6
+    records, err := r.ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    var rules []alertRule
+    for i, rec := range records {
+        for j := range rec {
+            rec[j] = strings.TrimSpace(rec[j])
+        }
+        rule := alertRule{warn: math.NaN(), crit: math.NaN(), hysteresis: defaultAlertHysteresis}
+        for col, re := range []**regexp.Regexp{&rule.chip, &rule.label} {
+            expr := rec[col]
+            if expr == "" {
+                expr = ".*"
+            }
+            if *re, err = regexp.Compile("^(?:" + expr + ")$"); err != nil {
+                return nil, fmt.Errorf("%s: ligne %d: %v", path, i+1, err)
+            }
+        }
+        for col, v := range map[int]*float64{2: &rule.warn, 3: &rule.crit, 5: &rule.hysteresis} {
+            if rec[col] == "" {
+                continue
+            }
+            if *v, err = strconv.ParseFloat(rec[col], 64); err != nil {
+                return nil, fmt.Errorf("%s: ligne %d: %q n'est pas un nombre", path, i+1, rec[col])
+            }
+        }
+        if math.IsNaN(rule.warn) && math.IsNaN(rule.crit) {
+            return nil, fmt.Errorf("%s: ligne %d: ni seuil warn ni seuil crit", path, i+1)
+        }
+        if rec[4] != "" {
+            if rule.hold, err = time.ParseDuration(rec[4]); err != nil {
+                return nil, fmt.Errorf("%s: ligne %d: hold: %v", path, i+1, err)
+            }
+        }
+        rules = append(rules, rule)
+    }
+    return rules, nil
+}
+
+// matchAlertRule returns the first rule applying to a temperature. The label expression is matched
+// against the sensor name when the reading has no label.
+func matchAlertRule(rules []alertRule, r reading) *alertRule {
+    label := r.label
+    if label == "" {
+        label = r.name
+    }
+    for i := range rules {
+        if rules[i].chip.MatchString(r.chip) && rules[i].label.MatchString(label) {
+            return &rules[i]
+        }
+    }
+    return nil
+}
+
+// alertState tracks one sensor and severity: pending until the hold duration has elapsed above
+// the threshold, then firing until the value drops below threshold - hysteresis
+type alertState struct {
+    since     time.Time
+    threshold float64
+    firing    bool
+}
+
+// alertNotification is the JSON body POSTed to the webhook on every state change
+type alertNotification struct {
+    State     string    `json:"state"` // firing or resolved
+    Severity  string    `json:"severity"`
+    Host      string    `json:"host"`
+    Source    string    `json:"source"`
+    Chip      string    `json:"chip"`
+    Sensor    string    `json:"sensor"`
+    Label     string    `json:"label"`
+    Value     float64   `json:"value"`
+    Threshold float64   `json:"threshold"`
+    Since     time.Time `json:"since"`
+    Timestamp time.Time `json:"timestamp"`
+}
+
+// Alerter evaluates the alert rules on its own timer, so nodes nobody scrapes still alert.
+// Only state changes are notified: a sensor staying hot sends a single firing message.
+type Alerter struct {
+    c        *Collector
+    url      string
+    host     string
+    interval time.Duration
+    client   *http.Client
+    states   map[string]*alertState
+    firing   *prometheus.GaugeVec
+    failed   prometheus.Counter
+}
+
+// NewAlerter posts the alerts of c to the Alertmanager at url every interval
+func NewAlerter(c *Collector, url, host string, interval, timeout time.Duration) *Alerter {
+    a := &Alerter{
+        c:        c,
+        url:      url,
+        host:     host,
+        interval: interval,
+        client:   &http.Client{Timeout: timeout},
+        states:   make(map[string]*alertState),
+        firing: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+            Namespace: c.Namespace,
+            Name:      "alerts_firing",
+            Help:      "Nombre d'alertes de température en cours par sévérité (-alert-rules-file).",
+        }, []string{"severity"}),
+        failed: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: c.Namespace,
+            Name:      "alert_notifications_failed_total",
+            Help:      "Nombre de notifications d'alerte que le webhook n'a pas acceptées.",
+        }),
+    }
+    a.firing.WithLabelValues("warn").Set(0)
+    a.firing.WithLabelValues("crit").Set(0)
+    return a
+}
+
+// Collectors returns the metrics of the alerter, to register next to the collector
+func (a *Alerter) Collectors() []prometheus.Collector {
+    return []prometheus.Collector{a.firing, a.failed}
+}
+
+// Run evaluates the rules every interval until ctx is cancelled
+func (a *Alerter) Run(ctx context.Context) {
+    t := time.NewTicker(a.interval)
+    defer t.Stop()
+    for {
+        a.evaluate(ctx, a.c.current(), time.Now())
+        select {
+        case <-t.C:
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// evaluate advances the state of every temperature against its rule. A sensor missing from the
+// collection keeps its state; one no longer matched by any rule (after a reload) is resolved.
+func (a *Alerter) evaluate(ctx context.Context, readings []reading, now time.Time) {
+    rules := a.c.rules.Load().alertRules
+    for _, r := range readings {
+        if r.kind != kindTemperature {
+            continue
+        }
+        rule := matchAlertRule(rules, r)
+        warn, crit := math.NaN(), math.NaN()
+        if rule != nil {
+            warn, crit = rule.warn, rule.crit
+        }
+        for _, lvl := range []struct {
+            severity  string
+            threshold float64
+        }{{"warn", warn}, {"crit", crit}} {
+            key := strings.Join([]string{r.source, r.target, r.chip, r.name, r.label, r.pkg, r.device, lvl.severity}, "\xff")
+            st := a.states[key]
+            switch {
+            case math.IsNaN(lvl.threshold):
+                if st != nil && st.firing {
+                    a.notify(ctx, "resolved", lvl.severity, r, st.threshold, st.since, now)
+                }
+                delete(a.states, key)
+            case r.value >= lvl.threshold:
+                if st == nil {
+                    st = &alertState{since: now, threshold: lvl.threshold}
+                    a.states[key] = st
+                }
+                if !st.firing && now.Sub(st.since) >= rule.hold {
+                    st.firing = true
+                    a.notify(ctx, "firing", lvl.severity, r, lvl.threshold, st.since, now)
+                }
+            case st == nil:
+            case !st.firing:
+                // back under the threshold before the hold duration: start over next time
+                delete(a.states, key)
+            case r.value < lvl.threshold-rule.hysteresis:
+                a.notify(ctx, "resolved", lvl.severity, r, lvl.threshold, st.since, now)
+                delete(a.states, key)
+            }
+        }
+    }
+    counts := map[string]float64{"warn": 0, "crit": 0}
+    for key, st := range a.states {
+        if st.firing {
+            counts[key[strings.LastIndexByte(key, '\xff')+1:]]++
+        }
+    }
+    for severity, n := range counts {
+        a.firing.WithLabelValues(severity).Set(n)
+    }
+}
+
+// notify logs the state change and POSTs it to the webhook when one is configured
+func (a *Alerter) notify(ctx context.Context, state, severity string, r reading, threshold float64, since, now time.Time) {
+    level := slog.LevelWarn
+    if state == "resolved" {
+        level = slog.LevelInfo
+    }
+    slog.Log(ctx, level, "alerte "+state, "severity", severity, "chip", r.chip, "sensor", r.name, "label", r.label, "value", r.value, "threshold", threshold)
+    if a.url == "" {
+        return
+    }
+    body, _ := json.Marshal(alertNotification{
+        State:     state,
+        Severity:  severity,
+        Host:      a.host,
+        Source:    r.source,
+        Chip:      r.chip,
+        Sensor:    r.name,
+        Label:     r.label,
+        Value:     r.value,
+        Threshold: threshold,
+        Since:     since,
+        Timestamp: now,
+    })
+    if err := a.post(ctx, body); err != nil {
+        a.failed.Inc()
+        slog.Warn("alert webhook: notification refusée", "err", err)
+    }
+}
+
+func (a *Alerter) post(ctx context.Context, body []byte) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("User-Agent", "temperature-exporter/"+Version)
+    resp, err := a.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode/100 != 2 {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+    }
+    return nil
+}
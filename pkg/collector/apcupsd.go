@@ -0,0 +1,93 @@
+package collector
+
+import (
+    "context"
+    "encoding/binary"
+    "fmt"
+    "io"
+    "net"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// discoverApcupsd asks an apcupsd Network Information Server for its status and returns
+// the UPS internal temperature plus line voltage and load when reported.
+func discoverApcupsd(ctx context.Context, addr string, timeout time.Duration) ([]reading, error) {
+    dialer := net.Dialer{Timeout: timeout}
+    conn, err := dialer.DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close()
+    if timeout > 0 {
+        if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+            return nil, err
+        }
+    }
+    status, err := apcupsdStatus(conn)
+    if err != nil {
+        return nil, err
+    }
+    ups := status["UPSNAME"]
+    if ups == "" {
+        ups = addr
+    }
+    var res []reading
+    if v, ok := apcupsdValue(status["ITEMP"]); ok {
+        res = append(res, reading{chip: "apcupsd", name: ups, value: v})
+    }
+    if v, ok := apcupsdValue(status["LINEV"]); ok {
+        res = append(res, reading{chip: "apcupsd", name: ups, value: v, kind: kindUPSLineVoltage})
+    }
+    if v, ok := apcupsdValue(status["LOADPCT"]); ok {
+        res = append(res, reading{chip: "apcupsd", name: ups, value: v, kind: kindUPSLoad})
+    }
+    return res, nil
+}
+
+// apcupsdStatus speaks the NIS protocol: every message is a 2-byte big-endian length followed by
+// the payload, and the server ends its answer with an empty message.
+func apcupsdStatus(rw io.ReadWriter) (map[string]string, error) {
+    cmd := []byte("status")
+    msg := make([]byte, 2+len(cmd))
+    binary.BigEndian.PutUint16(msg, uint16(len(cmd)))
+    copy(msg[2:], cmd)
+    if _, err := rw.Write(msg); err != nil {
+        return nil, err
+    }
+    status := map[string]string{}
+    for {
+        var n uint16
+        if err := binary.Read(rw, binary.BigEndian, &n); err != nil {
+            return nil, err
+        }
+        if n == 0 {
+            break
+        }
+        buf := make([]byte, n)
+        if _, err := io.ReadFull(rw, buf); err != nil {
+            return nil, err
+        }
+        // lines look like "ITEMP    : 29.1 C"
+        key, val, ok := strings.Cut(string(buf), ":")
+        if !ok {
+            continue
+        }
+        status[strings.TrimSpace(key)] = strings.TrimSpace(val)
+    }
+    if len(status) == 0 {
+        return nil, fmt.Errorf("apcupsd: empty status")
+    }
+    return status, nil
+}
+
+// apcupsdValue keeps the leading number of values like "29.1 C" or "230.0 Volts"
+func apcupsdValue(s string) (float64, bool) {
+    fields := strings.Fields(s)
+    if len(fields) == 0 {
+        return 0, false
+    }
+    v, err := strconv.ParseFloat(fields[0], 64)
+    return v, err == nil
+}
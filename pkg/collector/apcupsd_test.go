@@ -0,0 +1,99 @@
+package collector
+
+import (
+    "context"
+    "encoding/binary"
+    "net"
+    "slices"
+    "testing"
+    "time"
+)
+
+// serveOnce listens on a local port and lets answer handle the first connection
+func serveOnce(t *testing.T, answer func(conn net.Conn)) string {
+    t.Helper()
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { ln.Close() })
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        answer(conn)
+    }()
+    return ln.Addr().String()
+}
+
+// nisServer answers the status request with lines framed like apcupsd's NIS
+func nisServer(t *testing.T, lines []string) string {
+    return serveOnce(t, func(conn net.Conn) {
+        req := make([]byte, 2+len("status"))
+        if _, err := conn.Read(req); err != nil {
+            return
+        }
+        for _, l := range append(lines, "") {
+            msg := binary.BigEndian.AppendUint16(nil, uint16(len(l)))
+            conn.Write(append(msg, l...))
+        }
+    })
+}
+
+func TestDiscoverApcupsd(t *testing.T) {
+    tests := []struct {
+        name    string
+        lines   []string
+        want    []string
+        wantErr bool
+    }{
+        {
+            name: "Smart-UPS",
+            lines: []string{
+                "APC      : 001,036,0879\n",
+                "UPSNAME  : rack-ups\n",
+                "STATUS   : ONLINE \n",
+                "LINEV    : 230.0 Volts\n",
+                "LOADPCT  : 17.0 Percent\n",
+                "BATTV    : 27.1 Volts\n",
+                "ITEMP    : 29.1 C\n",
+            },
+            want: []string{
+                "apcupsd/rack-ups/ temperature=29.1",
+                "apcupsd/rack-ups/ ups_line_voltage=230",
+                "apcupsd/rack-ups/ ups_load=17",
+            },
+        },
+        {
+            // Back-UPS models have no internal temperature
+            name:  "without ITEMP",
+            lines: []string{"UPSNAME  : office\n", "LINEV    : 228.0 Volts\n", "LOADPCT  : N/A\n"},
+            want:  []string{"apcupsd/office/ ups_line_voltage=228"},
+        },
+        {name: "empty status", wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rs, err := discoverApcupsd(context.Background(), nisServer(t, tt.lines), time.Second)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+            }
+            if got := describe(rs); !slices.Equal(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestApcupsdUPSNameDefaultsToAddress(t *testing.T) {
+    addr := nisServer(t, []string{"ITEMP    : 31.5 C\n"})
+    rs, err := discoverApcupsd(context.Background(), addr, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got, want := describe(rs), []string{"apcupsd/" + addr + "/ temperature=31.5"}; !slices.Equal(got, want) {
+        t.Errorf("got %q, want %q", got, want)
+    }
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// apiTemperature is one element of the /api/v1/temperatures array
+type apiTemperature struct {
+    Source    string  `json:"source"`
+    Chip      string  `json:"chip"`
+    Sensor    string  `json:"sensor"`
+    Label     string  `json:"label"`
+    Package   string  `json:"package,omitempty"`
+    Core      string  `json:"core,omitempty"`
+    CCD       string  `json:"ccd,omitempty"`
+    Celsius   float64 `json:"celsius"`
+    Timestamp int64   `json:"timestamp"`
+}
+
+// TemperaturesHandler serves the temperatures of a fresh collection (the background snapshot with
+// -collect-interval) as JSON, for tools that do not want to parse the exposition format.
+// ?chip= (regular expression) and ?min= (°C) filter the list.
+func (c *Collector) TemperaturesHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet && r.Method != http.MethodHead {
+            w.Header().Set("Allow", "GET, HEAD")
+            apiError(w, http.StatusMethodNotAllowed, "méthode non autorisée")
+            return
+        }
+        q := r.URL.Query()
+        var chipRe *regexp.Regexp
+        if expr := q.Get("chip"); expr != "" {
+            re, err := regexp.Compile(expr)
+            if err != nil {
+                apiError(w, http.StatusBadRequest, fmt.Sprintf("chip: %v", err))
+                return
+            }
+            chipRe = re
+        }
+        minC := 0.0
+        hasMin := q.Has("min")
+        if hasMin {
+            v, err := strconv.ParseFloat(q.Get("min"), 64)
+            if err != nil {
+                apiError(w, http.StatusBadRequest, fmt.Sprintf("min: %q n'est pas un nombre", q.Get("min")))
+                return
+            }
+            minC = v
+        }
+
+        c.inflight.Add(1)
+        readings, stats := c.latest(c.ctx, time.Now())
+        c.inflight.Done()
+        if failed := failedSources(stats); len(failed) == len(stats) && len(stats) > 0 {
+            apiError(w, http.StatusServiceUnavailable, "aucune source n'a pu être lue: "+strings.Join(failed, ", "))
+            return
+        }
+        now := time.Now().Unix()
+        list := []apiTemperature{}
+        for _, rd := range readings {
+            if rd.kind != kindTemperature {
+                continue
+            }
+            if chipRe != nil && !chipRe.MatchString(rd.chip) {
+                continue
+            }
+            if hasMin && rd.value < minC {
+                continue
+            }
+            list = append(list, apiTemperature{Source: rd.source, Chip: rd.chip, Sensor: rd.name, Label: rd.label, Package: rd.pkg, Core: rd.core, CCD: rd.ccd, Celsius: rd.value, Timestamp: now})
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("Cache-Control", "no-store")
+        _ = json.NewEncoder(w).Encode(list)
+    })
+}
+
+// failedSources lists the sources whose last collection failed
+func failedSources(stats []sourceStats) []string {
+    var failed []string
+    for _, s := range stats {
+        if !s.success {
+            failed = append(failed, s.source)
+        }
+    }
+    return failed
+}
+
+// apiError answers with a {"error": msg} JSON object
+func apiError(w http.ResponseWriter, status int, msg string) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Cache-Control", "no-store")
+    w.WriteHeader(status)
+    _ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,131 @@
+package collector
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "log/slog"
+    "os"
+    "regexp"
+    "strings"
+    "sync"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// defaultBlocklist lists sensors known to report nothing useful, matched against blocklistKey.
+var defaultBlocklist = []string{
+    `^acpitz/`,              // ACPI thermal zone stuck at 27.8°C on most consumer boards
+    `^nct67\d\d/AUXTIN\d+$`, // unwired Super I/O auxiliary inputs reading -62°C or 110°C
+}
+
+// blocklist suppresses sensors before their files are read. suppressed remembers which rule
+// hid which sensor so each one is logged only once.
+type blocklist struct {
+    rules      []*regexp.Regexp
+    log        bool // -log-filtered: log at info instead of debug
+    mu         sync.Mutex
+    suppressed map[string]string
+}
+
+// newBlocklist compiles the built-in rules (unless disabled) and those of file, one regex per line.
+// Blank lines and lines starting with # are ignored.
+func newBlocklist(useDefault bool, file string) (*blocklist, error) {
+    var exprs []string
+    if useDefault {
+        exprs = append(exprs, defaultBlocklist...)
+    }
+    if file != "" {
+        f, err := os.Open(file)
+        if err != nil {
+            return nil, err
+        }
+        defer f.Close()
+        sc := bufio.NewScanner(f)
+        for sc.Scan() {
+            line := strings.TrimSpace(sc.Text())
+            if line == "" || strings.HasPrefix(line, "#") {
+                continue
+            }
+            exprs = append(exprs, line)
+        }
+        if err := sc.Err(); err != nil {
+            return nil, err
+        }
+    }
+    b := &blocklist{suppressed: map[string]string{}}
+    for _, e := range exprs {
+        re, err := regexp.Compile(e)
+        if err != nil {
+            return nil, fmt.Errorf("%s: %v", file, err)
+        }
+        b.rules = append(b.rules, re)
+    }
+    return b, nil
+}
+
+// blocklistKey builds the "chip/label" string rules match against, the same for every source:
+// thermal zones use their type as chip, lm-sensors chips lose their bus suffix and unlabeled
+// sensors fall back to the sensor name.
+func blocklistKey(source, chip, name, label string) string {
+    switch source {
+    case "thermal":
+        chip = name
+    case "sensors-cli":
+        chip = lmSensorsChipPrefix(chip)
+        if label == "" {
+            label = name
+        }
+    }
+    return chip + "/" + label
+}
+
+// match returns the first rule matching a sensor, "" when none does.
+func (b *blocklist) match(source, chip, name, label string) string {
+    key := blocklistKey(source, chip, name, label)
+    for _, re := range b.rules {
+        if re.MatchString(key) {
+            return re.String()
+        }
+    }
+    return ""
+}
+
+// blocked reports whether a sensor matches a rule and records it the first time.
+func (b *blocklist) blocked(source, chip, name, label string) bool {
+    rule := b.match(source, chip, name, label)
+    if rule == "" {
+        return false
+    }
+    key := blocklistKey(source, chip, name, label)
+    b.mu.Lock()
+    if _, seen := b.suppressed[source+" "+key]; !seen {
+        b.suppressed[source+" "+key] = rule
+        slog.Log(context.Background(), skipLevel(b.log), "blocklisted", "rule", rule, "source", source, "sensor", key)
+    }
+    b.mu.Unlock()
+    return true
+}
+
+// filterSensors drops blocklisted sysfs sensors so their files are never read. sensors is left
+// untouched: it can be the discovery cache and is counted for sensors_discovered afterwards.
+func (b *blocklist) filterSensors(source string, sensors []sources.Sensor) []sources.Sensor {
+    var kept []sources.Sensor
+    for _, s := range sensors {
+        if !b.blocked(source, s.Chip, s.Name, s.Label) {
+            kept = append(kept, s)
+        }
+    }
+    return kept
+}
+
+// filterReadings is the equivalent for command based sources, which return values directly.
+func (b *blocklist) filterReadings(source string, readings []reading) []reading {
+    kept := readings[:0]
+    for _, r := range readings {
+        if !b.blocked(source, r.chip, r.name, r.label) {
+            kept = append(kept, r)
+        }
+    }
+    return kept
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// TestBlocklistKeepsDiscoveredSensors filters a discovery result holding blocklisted sensors:
+// the result must stay as discovered, it is counted afterwards and may be the discovery cache
+func TestBlocklistKeepsDiscoveredSensors(t *testing.T) {
+    b, err := newBlocklist(true, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    discovered := []sources.Sensor{
+        {Chip: "acpitz", Name: "acpitz", Path: "/sys/class/hwmon/hwmon0/temp1_input"},
+        {Chip: "acpitz", Name: "acpitz", Path: "/sys/class/hwmon/hwmon0/temp1_crit", Kind: sources.KindCrit},
+        {Chip: "k10temp", Name: "k10temp", Label: "Tctl", Path: "/sys/class/hwmon/hwmon1/temp1_input"},
+        {Chip: "nct6798", Name: "nct6798", Label: "AUXTIN3", Path: "/sys/class/hwmon/hwmon2/temp7_input"},
+        {Chip: "nct6798", Name: "nct6798", Label: "SYSTIN", Path: "/sys/class/hwmon/hwmon2/temp1_input"},
+    }
+    before := append([]sources.Sensor(nil), discovered...)
+    kept := b.filterSensors("hwmon", discovered)
+    if want := []sources.Sensor{before[2], before[4]}; !reflect.DeepEqual(kept, want) {
+        t.Errorf("filterSensors() =\n%s\nwant\n%s", sensorPaths(kept), sensorPaths(want))
+    }
+    if !reflect.DeepEqual(discovered, before) {
+        t.Errorf("filterSensors() modified its argument:\n%s\nwas\n%s", sensorPaths(discovered), sensorPaths(before))
+    }
+}
+
+// TestSensorsDiscoveredCountsBlocklisted checks sensors_discovered counts what discovery found,
+// blocklisted chips (acpitz with the default rules) included, on every scrape
+func TestSensorsDiscoveredCountsBlocklisted(t *testing.T) {
+    // acpitz discovered first, so an in-place filter moves the kept sensors over it
+    files := map[string]string{
+        "hwmon0/name":        "acpitz\n",
+        "hwmon0/temp1_input": "16800\n",
+        "hwmon0/temp1_crit":  "20800\n",
+        "hwmon0/temp2_input": "16800\n",
+    }
+    // the other chips of fakeHwmonFiles move to hwmon1..hwmon3
+    for path, content := range fakeHwmonFiles {
+        if !strings.HasPrefix(path, "hwmon3/") {
+            files["hwmon"+string(path[5]+1)+path[6:]] = content
+        }
+    }
+    c, err := New(Options{
+        EnableHwmon:      true,
+        HwmonPaths:       []string{writeTree(t, files)},
+        HwmonCache:       time.Hour,
+        DefaultBlocklist: true,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    for scrape := 1; scrape <= 3; scrape++ {
+        discovered := gather(t, c, "sensors_discovered")
+        if len(discovered) != 1 || discovered[0].GetGauge().GetValue() != 6 {
+            t.Fatalf("scrape %d: sensors_discovered = %v, want hwmon 6", scrape, discovered)
+        }
+        if got := chips(t, c); len(got) != 4 {
+            t.Errorf("scrape %d: chips = %q, want the 4 inputs left after acpitz", scrape, got)
+        }
+    }
+}
+
+// sensorPaths formats sensors one path per line for failure messages
+func sensorPaths(sensors []sources.Sensor) string {
+    var s string
+    for _, sensor := range sensors {
+        s += "  " + sensor.Path + "\n"
+    }
+    return s
+}
@@ -0,0 +1,70 @@
+package collector
+
+import "math"
+
+// sentinelTemps are the values, in °C once the raw value is converted, that sysfs drivers
+// report for "no reading" rather than a temperature: 0 K (-273150 millidegrees), the minimum of
+// a signed byte (-128) and the -127 of a disconnected DS18B20 in several 1-wire stacks. Saturated
+// highs are left to -max-valid-temp: 127 °C can be a failing part really reading that.
+var sentinelTemps = []float64{-273.15, -128, -127}
+
+// isSentinel reports whether a hwmon or thermal temperature, before calibration, is one of
+// sentinelTemps; other sources have their own way of saying a value is missing. Raw values
+// come from integers, the tolerance only absorbs the millidegree conversion.
+func isSentinel(r reading) bool {
+    if r.kind != kindTemperature || (r.source != "hwmon" && r.source != "thermal") {
+        return false
+    }
+    for _, s := range sentinelTemps {
+        if math.Abs(r.value-s) < 1e-6 {
+            return true
+        }
+    }
+    return false
+}
+
+// dropSentinels removes the temperatures isSentinel recognizes, counting them with
+// reason="sentinel". It runs before calibration, which would move them off the known values,
+// and whatever -min-valid-temp, so genuine negatives below the sentinels stay possible.
+func (c *Collector) dropSentinels(readings []reading) []reading {
+    kept := readings[:0]
+    for _, r := range readings {
+        if isSentinel(r) {
+            c.discarded.WithLabelValues(r.chip, "sentinel").Inc()
+            continue
+        }
+        kept = append(kept, r)
+    }
+    return kept
+}
+
+// dropInvalid removes temperatures outside [minValidTemp, maxValidTemp] (already in °C) and,
+// with -drop-zero, exact zeros from hwmon, counting each discarded reading by chip and reason.
+// Thresholds, fans and voltages are left alone.
+func (c *Collector) dropInvalid(readings []reading) []reading {
+    kept := readings[:0]
+    for _, r := range readings {
+        if reason := c.invalidReason(r); reason != "" {
+            c.discarded.WithLabelValues(r.chip, reason).Inc()
+            continue
+        }
+        kept = append(kept, r)
+    }
+    return kept
+}
+
+// invalidReason returns why dropInvalid discards a reading, or "" to keep it.
+func (c *Collector) invalidReason(r reading) string {
+    if r.kind != kindTemperature {
+        return ""
+    }
+    switch {
+    case r.value < c.MinValidTemp:
+        return "below_min"
+    case r.value > c.MaxValidTemp:
+        return "above_max"
+    case c.DropZero && r.source == "hwmon" && r.value == 0:
+        return "zero"
+    }
+    return ""
+}
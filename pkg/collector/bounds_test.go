@@ -0,0 +1,65 @@
+package collector
+
+import (
+    "testing"
+
+    "github.com/prometheus/client_golang/prometheus"
+    dto "github.com/prometheus/client_model/go"
+)
+
+func TestIsSentinel(t *testing.T) {
+    tests := []struct {
+        name   string
+        source string
+        raw    float64 // as read from the driver
+        factor float64
+        kind   metricKind
+        want   bool
+    }{
+        {"0 K in millidegrees", "hwmon", -273150, 0.001, kindTemperature, true},
+        {"signed byte minimum", "hwmon", -128000, 0.001, kindTemperature, true},
+        {"disconnected DS18B20", "hwmon", -127000, 0.001, kindTemperature, true},
+        {"thermal zone at 0 K", "thermal", -273150, 0.001, kindTemperature, true},
+        {"genuine outdoor negative", "hwmon", -40000, 0.001, kindTemperature, false},
+        {"one millidegree off", "hwmon", -127001, 0.001, kindTemperature, false},
+        {"failing part at 127", "hwmon", 127000, 0.001, kindTemperature, false},
+        {"saturated unsigned byte", "hwmon", 255000, 0.001, kindTemperature, false},
+        {"threshold", "hwmon", -128000, 0.001, kindLowCrit, false},
+        {"sensors-cli", "sensors-cli", -127, 0, kindTemperature, false},
+        {"ipmi", "ipmi", -128, 0, kindTemperature, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            v := tt.raw
+            if tt.factor != 0 {
+                v *= tt.factor
+            }
+            r := reading{source: tt.source, chip: "w1_slave_temp", value: v, factor: tt.factor, kind: tt.kind}
+            if got := isSentinel(r); got != tt.want {
+                t.Errorf("isSentinel(%v %s) = %v, want %v", v, tt.source, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestDropSentinelsCounts(t *testing.T) {
+    c := &Collector{discarded: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "discarded"}, []string{"chip", "reason"})}
+    readings := []reading{
+        {source: "hwmon", chip: "w1_slave_temp", value: -127},
+        {source: "hwmon", chip: "w1_slave_temp", value: -12.5},
+        {source: "thermal", chip: "thermal", value: -273.15},
+    }
+    kept := c.dropSentinels(readings)
+    if len(kept) != 1 || kept[0].value != -12.5 {
+        t.Fatalf("dropSentinels() kept %+v, want only the -12.5 reading", kept)
+    }
+    for chip, want := range map[string]float64{"w1_slave_temp": 1, "thermal": 1} {
+        var m dto.Metric
+        if err := c.discarded.WithLabelValues(chip, "sentinel").Write(&m); err != nil {
+            t.Fatal(err)
+        }
+        if got := m.GetCounter().GetValue(); got != want {
+            t.Errorf("discarded{chip=%q, reason=\"sentinel\"} = %v, want %v", chip, got, want)
+        }
+    }
+}
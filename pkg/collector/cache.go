@@ -0,0 +1,46 @@
+package collector
+
+import (
+    "context"
+    "sync"
+    "time"
+)
+
+// readingCache keeps the result of a slow source (BMC, RAID controller...) for a fixed TTL,
+// independently of how often Prometheus scrapes.
+type readingCache struct {
+    mu       sync.Mutex
+    ttl      time.Duration
+    fetched  time.Time
+    readings []reading
+    err      error
+}
+
+func newReadingCache(ttl time.Duration) *readingCache {
+    return &readingCache{ttl: ttl}
+}
+
+// get returns the cached readings while they are fresh, otherwise calls fetch and stores its result.
+// Errors are cached too so a broken BMC is not hammered on every scrape, except when ctx, the
+// one fetch runs under, ended: a shutdown or a scraper giving up says nothing about the source.
+func (rc *readingCache) get(ctx context.Context, fetch func() ([]reading, error)) ([]reading, error) {
+    rc.mu.Lock()
+    defer rc.mu.Unlock()
+    if rc.ttl > 0 && !rc.fetched.IsZero() && time.Since(rc.fetched) < rc.ttl {
+        return rc.readings, rc.err
+    }
+    readings, err := fetch()
+    if ctx.Err() != nil {
+        return readings, err
+    }
+    // cached readings age from their fetch, for -stale-after; they are stamped on a copy since
+    // the slice fetch returned may be shared
+    fetched := time.Now()
+    stamped := make([]reading, len(readings))
+    for i, r := range readings {
+        r.at = fetched
+        stamped[i] = r
+    }
+    rc.readings, rc.err, rc.fetched = stamped, err, fetched
+    return stamped, err
+}
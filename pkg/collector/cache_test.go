@@ -0,0 +1,40 @@
+package collector
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestReadingCacheSkipsAbortedFetches(t *testing.T) {
+    rc := newReadingCache(time.Minute)
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, err := rc.get(ctx, func() ([]reading, error) { return nil, ctx.Err() }); !errors.Is(err, context.Canceled) {
+        t.Fatalf("get() error = %v, want context.Canceled", err)
+    }
+    calls := 0
+    rs, err := rc.get(context.Background(), func() ([]reading, error) {
+        calls++
+        return []reading{{chip: "ipmi", name: "Inlet Temp", value: 22}}, nil
+    })
+    if err != nil || calls != 1 || len(rs) != 1 {
+        t.Fatalf("get() after an aborted fetch = %v, %v with %d fetches, want a fresh fetch", rs, err, calls)
+    }
+}
+
+func TestReadingCacheStampsACopy(t *testing.T) {
+    rc := newReadingCache(time.Minute)
+    shared := []reading{{chip: "ipmi", name: "Inlet Temp", value: 22}}
+    rs, err := rc.get(context.Background(), func() ([]reading, error) { return shared, nil })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if rs[0].at.IsZero() {
+        t.Error("cached reading not stamped with its fetch time")
+    }
+    if !shared[0].at.IsZero() {
+        t.Error("get() stamped the slice returned by fetch")
+    }
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+    "encoding/csv"
+    "fmt"
+    "log/slog"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// calibrationEntry corrects one sensor as value*scale+offset
+type calibrationEntry struct {
+    chip   string
+    label  string
+    offset float64
+    scale  float64
+}
+
+// matches compares against the hwmon chip name or the lm-sensors chip without its bus suffix,
+// and against the label, or the sensor name for unlabeled sensors.
+func (e calibrationEntry) matches(r reading) bool {
+    if e.chip != r.chip && e.chip != lmSensorsChipPrefix(r.chip) {
+        return false
+    }
+    label := r.label
+    if label == "" {
+        label = r.name
+    }
+    return e.label == label
+}
+
+// loadCalibration reads a "chip,label,offset,scale" CSV. Lines starting with # are comments
+// and an empty scale means 1.
+func loadCalibration(path string) ([]calibrationEntry, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    r := csv.NewReader(f)
+    r.Comment = '#'
+    r.FieldsPerRecord = -1
+    r.TrimLeadingSpace = true
+    records, err := r.ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    var entries []calibrationEntry
+    for i, rec := range records {
+        if len(rec) < 3 || len(rec) > 4 {
+            return nil, fmt.Errorf("%s: ligne %d: attendu chip,label,offset[,scale]", path, i+1)
+        }
+        e := calibrationEntry{chip: strings.TrimSpace(rec[0]), label: strings.TrimSpace(rec[1]), scale: 1}
+        if e.offset, err = strconv.ParseFloat(strings.TrimSpace(rec[2]), 64); err != nil {
+            return nil, fmt.Errorf("%s: ligne %d: offset: %v", path, i+1, err)
+        }
+        if len(rec) == 4 && strings.TrimSpace(rec[3]) != "" {
+            if e.scale, err = strconv.ParseFloat(strings.TrimSpace(rec[3]), 64); err != nil {
+                return nil, fmt.Errorf("%s: ligne %d: scale: %v", path, i+1, err)
+            }
+        }
+        entries = append(entries, e)
+    }
+    return entries, nil
+}
+
+// calibrate applies the first matching entry to every temperature input; thresholds are
+// reported by the chip itself and are left untouched.
+func calibrate(readings []reading, entries []calibrationEntry) []reading {
+    if len(entries) == 0 {
+        return readings
+    }
+    for i, r := range readings {
+        if r.kind != kindTemperature {
+            continue
+        }
+        for _, e := range entries {
+            if e.matches(r) {
+                readings[i].value = r.value*e.scale + e.offset
+                break
+            }
+        }
+    }
+    return readings
+}
+
+// reportUnmatchedCalibration logs, once at startup, the entries that match none of the current readings.
+func reportUnmatchedCalibration(readings []reading, entries []calibrationEntry) {
+    for _, e := range entries {
+        found := false
+        for _, r := range readings {
+            if r.kind == kindTemperature && e.matches(r) {
+                found = true
+                break
+            }
+        }
+        if !found {
+            slog.Warn("calibration: entry matches no sensor (vérifiez -calibration-file)", "chip", e.chip, "label", e.label)
+        }
+    }
+}
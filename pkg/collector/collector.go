@@ -0,0 +1,1391 @@
+// Package collector exports the readings of the sources package, and of the collectors of its own
+// (IPMI, GPUs, RAPL, UPS...), as Prometheus metrics. It also holds the optional push outputs
+// (remote_write, InfluxDB, Graphite, MQTT) and the alert rules, which all share one Collector.
+package collector
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "math"
+    "os"
+    "os/exec"
+    "runtime/debug"
+    "slices"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// Version is the exporter version sent in the User-Agent of the push outputs and in the Home
+// Assistant device; the binary sets it from its build information
+var Version = "dev"
+
+// DefaultReadConcurrency is the number of sensor files read in parallel unless Options says otherwise
+const DefaultReadConcurrency = 8
+
+// DefaultMinValidTemp and DefaultMaxValidTemp bound the plausible temperatures, in °C, unless
+// Options says otherwise
+const (
+    DefaultMinValidTemp = -60
+    DefaultMaxValidTemp = 150
+)
+
+// Options holds the collector settings, mostly one field per command line flag. The zero value
+// of a field turns its feature off, or picks the default where off makes no sense; a zero
+// timeout means none.
+type Options struct {
+    Namespace        string
+    HwmonPaths       []string // -hwmon directories, scanned one after the other
+    ThermalPaths     []string
+    FS               sources.FS // filesystem holding the sysfs paths (hwmon, thermal, rapl, cpu), the host when nil
+    EnableHwmon      bool
+    HwmonCache       time.Duration
+    ReadConcurrency  int           // DefaultReadConcurrency when 0
+    ReadTimeout      time.Duration // per sensor file, 0 for none
+    IncludeDisabled  bool          // keep the hwmon channels whose *_enable reads 0
+    HwmonTimeout     time.Duration
+    EnableThermal    bool
+    ThermalTimeout   time.Duration
+    EnableSensorsCli bool
+    SensorsCliPath   string
+    SensorsCliFormat string // auto, json or raw
+    SensorsCliConfig string
+    SensorsCliArgs   []string
+    SensorsTimeout   time.Duration
+    EnableIPMI       bool
+    IPMIBackend      string // "ipmitool" or "freeipmi"
+    IPMIPath         string
+    IPMISensorsPath  string
+    IPMISDRCacheDir  string
+    IPMITimeout      time.Duration
+    IPMICache        time.Duration
+    EnableNvidia     bool
+    NvidiaSmiPath    string
+    NvidiaTimeout    time.Duration
+    EnableSysctl     bool // FreeBSD dev.cpu.N and hw.acpi.thermal sysctls
+    SSHTargetsFile   string // YAML list of remote hosts read over SSH, with a target label
+    EnableVcgencmd   bool
+    VcgencmdPath     string
+    VcgencmdTimeout  time.Duration
+    EnableRapl       bool
+    RaplPath         string
+    EnableThrottle   bool
+    CpuPath          string
+    EnableStorcli    bool
+    StorcliPath      string
+    StorcliTimeout   time.Duration
+    StorcliCache     time.Duration
+    ApcupsdAddress   string
+    ApcupsdTimeout   time.Duration
+    NutUPS           []string
+    NutTimeout       time.Duration
+    EnableLiquidctl  bool
+    LiquidctlPath    string
+    LiquidctlTimeout time.Duration
+    SourceLabel      bool
+    Fahrenheit       bool // -units: temperature_fahrenheit next to temperature_celsius
+    Kelvin           bool
+    PeakInterval     time.Duration // 0 disables temperature_peak_celsius
+    CollectInterval  time.Duration // 0 collects on scrape, otherwise scrapes serve the snapshot
+    StaleAfter       time.Duration // readings older than this are not exported, 0 for automatic, negative for never
+    HistogramBuckets []float64     // nil disables temperature_celsius_histogram
+    HistogramOnly    bool          // drop the per-sensor temperature series, keep the histogram
+    Timestamps       bool          // readings measured before the scrape (snapshot, cache) carry their time
+    Round            bool          // -precision: values are rounded to Precision decimal places
+    Precision        int
+    ReadyThreshold   int           // failed collections in a row before /readyz answers 503, 0 never
+    CorrectTctl      bool          // -k10temp-correct-tctl: report the die temperature instead of the offset Tctl
+    DedupeCli        bool
+    Dedupe           bool
+    SourcePriority   []string
+    CalibrationFile  string
+    RenameFile       string
+    AlertRulesFile   string
+    MinValidTemp     float64 // both 0 for DefaultMinValidTemp and DefaultMaxValidTemp
+    MaxValidTemp     float64
+    DropZero         bool
+    HwmonChips       []string // per source allow-lists of globs or /RE2/, applied before ChipInclude
+    ThermalZones     []string
+    SensorsCliChips  []string
+    ChipInclude      []string // RE2 expressions matched against the chip, none keeps every chip
+    ChipExclude      []string
+    SensorInclude    []string // matched against the sensor and label
+    SensorExclude    []string
+    DefaultBlocklist bool
+    BlocklistFile    string
+    LogFiltered      bool
+}
+
+// Collector implements prometheus.Collector
+type Collector struct {
+    Options
+    sensors    *prometheus.Desc
+    sensorsF   *prometheus.Desc
+    sensorsK   *prometheus.Desc
+    chipMax    *prometheus.Desc
+    nodeMax    *prometheus.Desc
+    peak       *prometheus.Desc
+    overCrit   *prometheus.Desc
+    overMax    *prometheus.Desc
+    snapAge    *prometheus.Desc
+    max        *prometheus.Desc
+    crit       *prometheus.Desc
+    critHyst   *prometheus.Desc
+    lcrit      *prometheus.Desc
+    chipInfo   *prometheus.Desc
+    sensorInfo *prometheus.Desc
+    lastRead   *prometheus.Desc
+    intrusion  *prometheus.Desc
+    amdgpuInfo *prometheus.Desc
+    amdgpuCap  *prometheus.Desc
+    fanSpeed   *prometheus.Desc
+    voltage    *prometheus.Desc
+    energy     *prometheus.Desc
+    upsLineV   *prometheus.Desc
+    upsLoad    *prometheus.Desc
+    apcErrors  prometheus.Counter
+    reloadOK   prometheus.Gauge
+    discarded  *prometheus.CounterVec
+    collisions *prometheus.CounterVec
+    timeouts   *prometheus.CounterVec
+    panics     *prometheus.CounterVec
+    errors     *prometheus.CounterVec
+    readErrors *prometheus.CounterVec
+    abandoned  *prometheus.CounterVec
+    failures   *prometheus.CounterVec
+    stale      *prometheus.CounterVec
+    raplEnergy *prometheus.Desc
+    throttle   *prometheus.Desc
+    scrapeTime *prometheus.Desc
+    success    *prometheus.Desc
+    discovered *prometheus.Desc
+    exported   *prometheus.Desc
+    sourceTime *prometheus.Desc
+    sshUp      *prometheus.Desc
+    sshTime    *prometheus.Desc
+    sshErrors  *prometheus.CounterVec
+    hwmon      *sources.Hwmon
+    thermal    *sources.Thermal
+    sensorsCli *sources.SensorsCLI
+    sysctl     *sources.Sysctl
+    ssh        []*sshTarget // -ssh-targets-file, each read with a target label
+    reader     *sources.FileReader
+    ipmi       *readingCache
+    storcli    *readingCache
+    rapl       *raplCounters
+    joules     *energyCounters
+    tctlOffset float64 // Tctl offset of the CPU model, for kernels without Tdie
+    filter     *readingFilter
+    peaks      *peakTracker // nil unless -peak-sample-interval is set
+    histogram  *prometheus.HistogramVec // nil unless -histogram is set
+    rules      atomic.Pointer[ruleSet]
+    // collecting holds the UnixNano start of the running gather, 0 when idle (watchdog); set by
+    // pipeline under mu
+    collecting atomic.Int64
+    // ctx is cancelled at shutdown to kill running commands; inflight tracks the collections using it
+    ctx        context.Context
+    cancel     context.CancelFunc
+    inflight   sync.WaitGroup
+    // mu serializes gathers; lastReadings is handed to scrapes that waited on a running gather
+    mu           sync.Mutex
+    lastReadings []reading
+    lastStats    []sourceStats
+    lastGather   time.Time
+    // snapshot is the last background collection, nil unless -collect-interval is set
+    snapshot atomic.Pointer[snapshot]
+    // recent is the last full collection, on scrape or in the background, for /healthz
+    recent atomic.Pointer[snapshot]
+    // ready is the /readyz state; readyFailures counts failed collections in a row, under mu
+    ready         atomic.Bool
+    readyFailures int
+}
+
+var sensorsCliWarned bool
+
+var sysctlWarned bool
+
+// New returns the collector of the sensors selected by cfg, ready to be registered. It checks
+// cfg, loads the rule files and sets up the enabled sources; nothing is read before the first
+// collection or Discover. The command line of the source that is not installed is only logged;
+// invalid settings and rule files that cannot be loaded are errors.
+func New(cfg Options) (*Collector, error) {
+    // device keeps apart physically distinct chips of the same name (two identical NICs or GPUs)
+    labels := []string{"chip", "sensor", "label", "package", "core", "ccd", "device"}
+    if cfg.SourceLabel {
+        labels = append(labels, "source")
+    }
+    // the target label only exists when there are remote readings to tell apart
+    var targets []*sshTarget
+    if cfg.SSHTargetsFile != "" {
+        var err error
+        if targets, err = loadSSHTargets(cfg.SSHTargetsFile); err != nil {
+            return nil, fmt.Errorf("-ssh-targets-file: %v", err)
+        }
+        labels = append(labels, "target")
+    }
+    if cfg.ReadConcurrency == 0 {
+        cfg.ReadConcurrency = DefaultReadConcurrency
+    }
+    if cfg.MinValidTemp == 0 && cfg.MaxValidTemp == 0 {
+        cfg.MinValidTemp, cfg.MaxValidTemp = DefaultMinValidTemp, DefaultMaxValidTemp
+    }
+    if cfg.StaleAfter == 0 {
+        cfg.StaleAfter = autoStaleAfter(cfg)
+    }
+    ctx, cancel := context.WithCancel(context.Background())
+    c := &Collector{
+        Options: cfg,
+        ctx:    ctx,
+        cancel: cancel,
+        sensors: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_celsius"),
+            "Température en degrés Celsius lue depuis les capteurs système (hwmon, thermal, lm-sensors).",
+            labels, nil,
+        ),
+        sensorsF: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_fahrenheit"),
+            "Température en degrés Fahrenheit, convertie depuis temperature_celsius (-units).",
+            labels, nil,
+        ),
+        sensorsK: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_kelvin"),
+            "Température en kelvins, convertie depuis temperature_celsius (-units).",
+            labels, nil,
+        ),
+        chipMax: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_max_per_chip_celsius"),
+            "Température la plus haute parmi les capteurs exportés de chaque chip.",
+            []string{"chip"}, nil,
+        ),
+        nodeMax: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_node_max_celsius"),
+            "Température la plus haute parmi tous les capteurs exportés du nœud.",
+            nil, nil,
+        ),
+        peak: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_peak_celsius"),
+            "Température la plus haute observée depuis le démarrage ou la dernière remise à zéro (-peak-sample-interval).",
+            labels, nil,
+        ),
+        overCrit: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "sensors_over_crit"),
+            "Nombre de capteurs du chip dont la température atteint leur propre seuil critique (temp*_crit).",
+            []string{"chip"}, nil,
+        ),
+        overMax: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "sensors_over_max"),
+            "Nombre de capteurs du chip dont la température atteint leur propre seuil haut (temp*_max).",
+            []string{"chip"}, nil,
+        ),
+        snapAge: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "snapshot_age_seconds"),
+            "Âge de la collecte en arrière-plan servie par ce scrape (-collect-interval).",
+            nil, nil,
+        ),
+        max: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_max_celsius"),
+            "Seuil haut (max) en degrés Celsius annoncé par le capteur.",
+            labels, nil,
+        ),
+        critHyst: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_crit_hyst_celsius"),
+            "Hystérésis du seuil critique en degrés Celsius annoncée par le capteur.",
+            labels, nil,
+        ),
+        crit: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_crit_celsius"),
+            "Seuil critique haut en degrés Celsius annoncé par le capteur.",
+            labels, nil,
+        ),
+        lcrit: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "temperature_lcrit_celsius"),
+            "Seuil critique bas en degrés Celsius annoncé par le capteur.",
+            labels, nil,
+        ),
+        chipInfo: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "sensors_chip_info"),
+            "Adaptateur lm-sensors de chaque chip (PCI adapter, ISA adapter, Virtual device...), toujours 1.",
+            []string{"chip", "adapter"}, nil,
+        ),
+        sensorInfo: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "sensor_info"),
+            "Driver noyau (lien device/driver) et fichier sysfs de chaque capteur hwmon et thermal, driver vide pour les chips virtuels, toujours 1.",
+            append(slices.Clip(labels), "driver", "path"), nil,
+        ),
+        lastRead: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "sensor_last_read_timestamp_seconds"),
+            "Horodatage Unix de la dernière lecture réussie de chaque température, plus ancien que le scrape pour les valeurs en cache (IPMI, storcli, -collect-interval).",
+            labels, nil,
+        ),
+        intrusion: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "intrusion_alarm"),
+            "Alarme d'intrusion châssis (intrusionN_alarm) des chips Super I/O: 1 si le boîtier a été ouvert, jusqu'à son effacement.",
+            []string{"chip", "sensor"}, nil,
+        ),
+        amdgpuInfo: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "amdgpu_card_info"),
+            "Correspondance entre une carte amdgpu (card0, card1...) et son adresse PCI, le device de temperature_sensor_info, toujours 1.",
+            []string{"card", "pci_address"}, nil,
+        ),
+        amdgpuCap: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "amdgpu_power_cap_watts"),
+            "Limite de puissance (power1_cap) de la carte amdgpu en watts.",
+            []string{"card"}, nil,
+        ),
+        scrapeTime: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "scrape_duration_seconds"),
+            "Durée de la dernière collecte des températures.",
+            nil, nil,
+        ),
+        sourceTime: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "source_scrape_duration_seconds"),
+            "Durée de collecte de chaque source lors de la dernière collecte.",
+            []string{"source"}, nil,
+        ),
+        sshUp: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "ssh_target_up"),
+            "1 si la cible SSH a été lue sans erreur lors de la dernière collecte, 0 sinon.",
+            []string{"target"}, nil,
+        ),
+        sshTime: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "ssh_target_duration_seconds"),
+            "Durée de collecte de chaque cible SSH lors de la dernière collecte, connexion comprise.",
+            []string{"target"}, nil,
+        ),
+        success: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "collector_success"),
+            "1 si la source (hwmon, thermal, sensors-cli...) a été collectée sans erreur lors de la dernière collecte, 0 sinon.",
+            []string{"source"}, nil,
+        ),
+        discovered: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "sensors_discovered"),
+            "Nombre de capteurs trouvés par la source lors de la dernière collecte, avant blocklist et filtres (seuils non compris).",
+            []string{"source"}, nil,
+        ),
+        exported: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "readings_exported"),
+            "Nombre de valeurs de capteurs exportées lors de la dernière collecte, après filtres et dédoublonnage (seuils non compris).",
+            nil, nil,
+        ),
+        fanSpeed: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "fan_speed_rpm"),
+            "Vitesse des ventilateurs et pompes en tours par minute.",
+            labels, nil,
+        ),
+        voltage: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "voltage_volts"),
+            "Tensions mesurées par les capteurs (in*_input) en volts.",
+            labels, nil,
+        ),
+        energy: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "energy_joules_total"),
+            "Énergie cumulée des compteurs hwmon energy*_input en joules, qui continue de croître quand le compteur du pilote repart de zéro.",
+            labels, nil,
+        ),
+        upsLineV: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "ups_line_voltage_volts"),
+            "Tension d'entrée de l'onduleur en volts.",
+            labels, nil,
+        ),
+        upsLoad: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "ups_load_percent"),
+            "Charge de l'onduleur en pourcentage de sa capacité.",
+            labels, nil,
+        ),
+        apcErrors: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "apcupsd_errors_total",
+            Help:      "Nombre d'échecs d'interrogation du serveur NIS apcupsd.",
+        }),
+        reloadOK: prometheus.NewGauge(prometheus.GaugeOpts{
+            Namespace: cfg.Namespace,
+            Name:      "config_last_reload_successful",
+            Help:      "Indique si le dernier rechargement de la configuration (SIGHUP) a réussi.",
+        }),
+        discarded: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "readings_discarded_total",
+            Help:      "Nombre de températures écartées car valeurs sentinelles des pilotes (sentinel), hors des bornes valides (below_min, above_max) ou nulles (zero).",
+        }, []string{"chip", "reason"}),
+        collisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "label_collisions_total",
+            Help:      "Nombre de lectures dont les labels étaient identiques à ceux d'une autre lecture de la même collecte, exportées avec un libellé complété (temp1, temp2…).",
+        }, []string{"chip"}),
+        timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "source_timeout_total",
+            Help:      "Nombre de collectes où une source (hwmon, thermal) a dépassé son délai et n'a exporté qu'une partie de ses capteurs.",
+        }, []string{"source"}),
+        panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "collection_panics_total",
+            Help:      "Nombre de panics rattrapées pendant la collecte d'une source; les autres sources sont exportées normalement.",
+        }, []string{"source"}),
+        sshErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "ssh_target_errors_total",
+            Help:      "Nombre de collectes en échec par cible SSH (connexion, authentification, délai, commande distante).",
+        }, []string{"target"}),
+        errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "collection_errors_total",
+            Help:      "Nombre d'échecs de découverte ou d'exécution d'une source (répertoire illisible, commande en erreur, démon injoignable).",
+        }, []string{"source"}),
+        readErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "read_errors_total",
+            Help:      "Nombre de fichiers capteurs (hwmon, thermal) impossibles à lire ou à interpréter.",
+        }, []string{"source"}),
+        abandoned: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "sensor_reads_abandoned_total",
+            Help:      "Nombre de lectures de fichiers capteurs abandonnées après -sensor-read-timeout (pilote bloqué dans le noyau).",
+        }, []string{"source"}),
+        failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "sensor_read_failures_total",
+            Help:      "Nombre d'échecs de lecture par capteur et par raison (permission, not_found, parse, timeout, io).",
+        }, []string{"chip", "sensor", "label", "reason"}),
+        stale: prometheus.NewCounterVec(prometheus.CounterOpts{
+            Namespace: cfg.Namespace,
+            Name:      "stale_readings_total",
+            Help:      "Nombre de lectures écartées car plus anciennes que -stale-after (collecte en arrière-plan bloquée, cache périmé).",
+        }, []string{"chip"}),
+        raplEnergy: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "rapl_energy_joules_total"),
+            "Énergie consommée par domaine RAPL (package, core, uncore, dram) en joules.",
+            []string{"package", "domain"}, nil,
+        ),
+        throttle: prometheus.NewDesc(
+            prometheus.BuildFQName(cfg.Namespace, "", "cpu_throttle_events_total"),
+            "Nombre de ralentissements thermiques du CPU (thermal_throttle), par cœur (scope=\"core\", cpu = premier CPU logique du cœur) et par package (scope=\"package\", cpu vide).",
+            []string{"cpu", "package", "scope"}, nil,
+        ),
+        reader:  sources.NewFileReader(cfg.ReadTimeout),
+        ipmi:    newReadingCache(cfg.IPMICache),
+        storcli: newReadingCache(cfg.StorcliCache),
+        rapl:    newRaplCounters(),
+        joules:  newEnergyCounters(),
+    }
+    c.hwmon = &sources.Hwmon{Paths: cfg.HwmonPaths, IncludeDisabled: cfg.IncludeDisabled, Workers: cfg.ReadConcurrency, Reader: c.reader, Cache: sources.NewDiscoveryCache(cfg.HwmonCache), FS: cfg.FS}
+    c.thermal = &sources.Thermal{Paths: cfg.ThermalPaths, Workers: cfg.ReadConcurrency, Reader: c.reader, FS: cfg.FS}
+    c.sensorsCli = &sources.SensorsCLI{Path: cfg.SensorsCliPath, Format: cfg.SensorsCliFormat, Config: cfg.SensorsCliConfig, Args: cfg.SensorsCliArgs, Timeout: cfg.SensorsTimeout}
+    c.sysctl = &sources.Sysctl{}
+    c.ssh = targets
+    if cfg.PeakInterval > 0 {
+        c.peaks = newPeakTracker()
+    }
+    if cfg.HistogramBuckets != nil {
+        c.histogram = newTemperatureHistogram(cfg.Namespace, cfg.HistogramBuckets)
+    }
+    switch c.SensorsCliFormat {
+    case "", "auto", "json", "raw":
+    default:
+        return nil, fmt.Errorf("-sensors-cli-format: format %q inconnu (attendu: auto, json ou raw)", c.SensorsCliFormat)
+    }
+    if c.ReadConcurrency < 0 {
+        return nil, fmt.Errorf("-read-concurrency: doit être au moins 1 (reçu %d)", c.ReadConcurrency)
+    }
+    var err error
+    if c.filter, err = newReadingFilter(cfg.ChipInclude, cfg.ChipExclude, cfg.SensorInclude, cfg.SensorExclude); err != nil {
+        return nil, err
+    }
+    if c.CorrectTctl {
+        model := cpuModel("/proc/cpuinfo")
+        c.tctlOffset = tctlOffset(model)
+        slog.Info("k10temp: correction du Tctl", "cpu", model, "offset", c.tctlOffset)
+    }
+    if c.EnableSensorsCli && c.SensorsCliConfig != "" {
+        if _, err := os.Stat(c.SensorsCliConfig); err != nil {
+            return nil, fmt.Errorf("-sensors-cli-config: %v", err)
+        }
+    }
+    if err := checkSources(c.SourcePriority); err != nil {
+        return nil, fmt.Errorf("-source-priority: %v", err)
+    }
+    if c.EnableIPMI {
+        if err := c.checkIPMI(); err != nil {
+            return nil, fmt.Errorf("IPMI: %v", err)
+        }
+    }
+    if c.EnableNvidia {
+        nvidiaWarned = missingBinary(c.NvidiaSmiPath, "-enable-nvidia")
+    }
+    if c.EnableVcgencmd {
+        vcgencmdWarned = missingBinary(c.VcgencmdPath, "-enable-vcgencmd")
+    }
+    if c.EnableStorcli {
+        storcliWarned = missingBinary(c.StorcliPath, "-enable-storcli")
+    }
+    if c.EnableLiquidctl {
+        liquidctlWarned = missingBinary(c.LiquidctlPath, "-enable-liquidctl")
+    }
+    rules, err := c.loadRules()
+    if err != nil {
+        return nil, err
+    }
+    c.rules.Store(rules)
+    c.reloadOK.Set(1)
+    return c, nil
+}
+
+// descs lists the per-scrape families, built from the gathered readings on every Collect
+func (c *Collector) descs() []*prometheus.Desc {
+    return []*prometheus.Desc{c.sensors, c.sensorsF, c.sensorsK, c.chipMax, c.nodeMax, c.peak, c.overCrit, c.overMax, c.snapAge, c.max, c.crit, c.critHyst, c.lcrit, c.chipInfo, c.sensorInfo, c.lastRead, c.intrusion, c.amdgpuInfo, c.amdgpuCap, c.fanSpeed, c.voltage, c.energy, c.upsLineV, c.upsLoad, c.raplEnergy, c.throttle, c.scrapeTime, c.sourceTime, c.sshUp, c.sshTime, c.success, c.discovered, c.exported}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+    for _, d := range c.descs() {
+        ch <- d
+    }
+    c.apcErrors.Describe(ch)
+    c.discarded.Describe(ch)
+    c.collisions.Describe(ch)
+    c.timeouts.Describe(ch)
+    c.panics.Describe(ch)
+    c.errors.Describe(ch)
+    c.sshErrors.Describe(ch)
+    c.readErrors.Describe(ch)
+    c.abandoned.Describe(ch)
+    c.failures.Describe(ch)
+    c.stale.Describe(ch)
+    c.reloadOK.Describe(ch)
+    if c.histogram != nil {
+        c.histogram.Describe(ch)
+    }
+}
+
+// metricKind selects the metric family a reading is exported to; the kinds are those of the sources
+type metricKind = sources.Kind
+
+const (
+    kindTemperature    = sources.KindTemperature
+    kindMax            = sources.KindMax
+    kindCrit           = sources.KindCrit
+    kindCritHyst       = sources.KindCritHyst
+    kindLowCrit        = sources.KindLowCrit
+    kindFan            = sources.KindFan
+    kindVoltage        = sources.KindVoltage
+    kindUPSLineVoltage = sources.KindUPSLineVoltage
+    kindUPSLoad        = sources.KindUPSLoad
+    kindEnergy         = sources.KindEnergy
+)
+
+// countReadings counts the sensors a source returned, leaving out their thresholds
+func countReadings(readings []reading) int {
+    n := 0
+    for _, r := range readings {
+        if !r.kind.Threshold() {
+            n++
+        }
+    }
+    return n
+}
+
+// reading is a single value ready to be exported, whatever source produced it
+type reading struct {
+    source  string // hwmon, thermal, sensors-cli, ipmi...
+    target  string // remote host of the ssh source, empty for local readings
+    path    string // sysfs file for file based sources
+    chip    string
+    name    string
+    label   string
+    value   float64
+    factor  float64    // multiplier applied to the raw sysfs value, 0 for other sources
+    kind    metricKind // zero value is a plain temperature
+    adapter string     // lm-sensors adapter, empty for other sources
+    driver  string     // kernel driver of the sysfs device, empty for other sources and virtual chips
+    device  string     // sysfs device (PCI address, USB path...), empty for other sources and virtual chips
+    pkg     string     // coretemp package id, empty for other chips
+    core    string     // coretemp core number, empty for the package sensor and other chips
+    ccd     string     // k10temp die number of the Tccd sensors
+    at      time.Time  // when the value was read, for -stale-after
+}
+
+// withSource returns a copy of rs tagged with source (cached slices are shared between scrapes)
+func withSource(source string, rs []reading) []reading {
+    out := make([]reading, len(rs))
+    for i, r := range rs {
+        r.source = source
+        out[i] = r
+    }
+    return out
+}
+
+// fromSources converts the readings of a sources package source
+func fromSources(rs []sources.Reading) []reading {
+    out := make([]reading, len(rs))
+    for i, r := range rs {
+        out[i] = reading{source: r.Source, target: r.Target, path: r.Path, chip: r.Chip, name: r.Name, label: r.Label, value: r.Value, factor: r.Factor, kind: r.Kind, adapter: r.Adapter, driver: r.Driver, device: r.Device, at: r.At}
+    }
+    return out
+}
+
+// sourceContext derives the deadline of one source from the collection context, 0 meaning none
+func sourceContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+    if timeout <= 0 {
+        return context.WithCancel(ctx)
+    }
+    return context.WithTimeout(ctx, timeout)
+}
+
+// checkTimeout counts and logs a source whose own deadline expired; a shutdown or a scraper
+// giving up (errScrapeTimeout) is not a timeout of the source
+func (c *Collector) checkTimeout(ctx context.Context, source string, timeout time.Duration) {
+    if errors.Is(ctx.Err(), context.DeadlineExceeded) && context.Cause(ctx) == context.DeadlineExceeded {
+        c.timeouts.WithLabelValues(source).Inc()
+        slog.Warn("délai dépassé, capteurs restants ignorés pour cette collecte", "source", source, "timeout", timeout)
+    }
+}
+
+// discoverySummary formats the sensor count of each source for the startup log and sums them
+func discoverySummary(stats []sourceStats) (string, int) {
+    if len(stats) == 0 {
+        return "aucune source activée", 0
+    }
+    parts := make([]string, len(stats))
+    total := 0
+    for i, st := range stats {
+        parts[i] = fmt.Sprintf("%s=%d", st.source, st.discovered)
+        if !st.success {
+            parts[i] += " (erreur)"
+        }
+        total += st.discovered
+    }
+    return strings.Join(parts, ", "), total
+}
+
+// sourceStats records how one source fared during a gather
+type sourceStats struct {
+    source     string
+    success    bool // ran to completion without error or panic
+    discovered int  // sensors found, before blocklist and filters
+    duration   time.Duration
+    targets    []targetStats // per host outcome of the ssh source
+    metrics    []sideMetric  // series of the source besides its readings
+}
+
+// sideMetric is a series a source exports besides its readings: the RAPL and throttle counters,
+// the intrusion alarms and amdgpu cards of hwmon. They are gathered with the readings so that
+// scrapes serving a snapshot do not read sysfs again.
+type sideMetric struct {
+    desc   *prometheus.Desc
+    vt     prometheus.ValueType
+    value  float64
+    labels []string
+}
+
+// hwmonSideMetrics reads the amdgpu cards and the chassis intrusion alarms of the hwmon chips
+func (c *Collector) hwmonSideMetrics(ctx context.Context) []sideMetric {
+    var metrics []sideMetric
+    if c.filter.drop(reading{chip: "amdgpu", name: "amdgpu"}) == "" {
+        for _, card := range sources.DiscoverAmdgpuCards(c.FS, c.HwmonPaths) {
+            metrics = append(metrics, sideMetric{c.amdgpuInfo, prometheus.GaugeValue, 1, []string{card.Card, card.PCIAddress}})
+            if card.HasPowerCap {
+                metrics = append(metrics, sideMetric{c.amdgpuCap, prometheus.GaugeValue, card.PowerCap, []string{card.Card}})
+            }
+        }
+    }
+    for _, a := range discoverIntrusions(ctx, c.FS, c.HwmonPaths) {
+        if c.filter.drop(reading{chip: a.chip, name: a.sensor}) != "" {
+            continue
+        }
+        metrics = append(metrics, sideMetric{c.intrusion, prometheus.GaugeValue, a.value, []string{a.chip, a.sensor}})
+    }
+    return metrics
+}
+
+// runSource runs the collection of one source and counts its error. A panic is logged with
+// its stack and counted so a misbehaving backend only loses its own readings for this scrape.
+func (c *Collector) runSource(source string, fn func() (int, error)) (st sourceStats) {
+    st.source = source
+    start := time.Now()
+    defer func() {
+        st.duration = time.Since(start)
+        if r := recover(); r != nil {
+            c.panics.WithLabelValues(source).Inc()
+            slog.Error("panic pendant la collecte", "source", source, "panic", r, "stack", string(debug.Stack()))
+        }
+    }()
+    n, err := fn()
+    st.discovered = n
+    if err != nil {
+        c.errors.WithLabelValues(source).Inc()
+        return st
+    }
+    st.success = true
+    return st
+}
+
+// sourceNames lists the enabled sources, which are the valid collect[] values
+func (c *Collector) sourceNames() []string {
+    var names []string
+    for _, s := range []struct {
+        name    string
+        enabled bool
+    }{
+        {"hwmon", c.EnableHwmon},
+        {"thermal", c.EnableThermal},
+        {"sensors-cli", c.EnableSensorsCli},
+        {"sysctl", c.EnableSysctl},
+        {"ssh", len(c.ssh) > 0},
+        {"ipmi", c.EnableIPMI},
+        {"storcli", c.EnableStorcli},
+        {"nvidia", c.EnableNvidia},
+        {"vcgencmd", c.EnableVcgencmd},
+        {"apcupsd", c.ApcupsdAddress != ""},
+        {"nut", len(c.NutUPS) > 0},
+        {"liquidctl", c.EnableLiquidctl},
+        {"rapl", c.EnableRapl},
+        {"throttle", c.EnableThrottle},
+    } {
+        if s.enabled {
+            names = append(names, s.name)
+        }
+    }
+    return names
+}
+
+// selected reports whether source is part of a collect[] selection, nil selecting every source
+func selected(selection map[string]bool, source string) bool {
+    return selection == nil || selection[source]
+}
+
+// gather runs every enabled source, or only those of sources when not nil, and returns their
+// readings tagged with the source name, along with the outcome of each source
+func (c *Collector) gather(ctx context.Context, rules *ruleSet, selection map[string]bool) ([]reading, []sourceStats) {
+    var (
+        readings []reading
+        stats    []sourceStats
+    )
+    // hwmon discovery is cached, refreshed on hotplug events or after -hwmon-discovery-ttl;
+    // a sensor that vanished fails its read and disappears from the next scrape anyway.
+    // Both sysfs sources run under their own deadline and keep what they read before it.
+    if c.EnableHwmon && selected(selection, "hwmon") {
+        var metrics []sideMetric
+        st := c.runSource("hwmon", func() (int, error) {
+            sctx, cancel := sourceContext(ctx, c.HwmonTimeout)
+            defer cancel()
+            s, err := c.hwmon.Discover(sctx)
+            if err != nil && sctx.Err() == nil {
+                slog.Warn("discoverSensors error", "err", err)
+            }
+            rs, failures := c.hwmon.Read(sctx, rules.blocklist.filterSensors("hwmon", rules.chips.filterSensors("hwmon", s)))
+            readings = append(readings, fromSources(rs)...)
+            c.recordFailures("hwmon", failures)
+            metrics = c.hwmonSideMetrics(sctx)
+            c.checkTimeout(sctx, "hwmon", c.HwmonTimeout)
+            return sources.CountSensors(s), err
+        })
+        st.metrics = metrics
+        stats = append(stats, st)
+    }
+    // RAPL and the throttle counters are plain sysfs files too, read under -hwmon-timeout
+    if c.EnableRapl && selected(selection, "rapl") {
+        var metrics []sideMetric
+        st := c.runSource("rapl", func() (int, error) {
+            sctx, cancel := sourceContext(ctx, c.HwmonTimeout)
+            defer cancel()
+            domains, err := discoverRAPL(sctx, c.FS, c.RaplPath)
+            if err != nil {
+                slog.Warn("discoverRAPL error", "err", err)
+                return 0, err
+            }
+            for _, d := range domains {
+                metrics = append(metrics, sideMetric{c.raplEnergy, prometheus.CounterValue, c.rapl.update(d), []string{d.pkg, d.domain}})
+            }
+            c.checkTimeout(sctx, "rapl", c.HwmonTimeout)
+            return len(domains), nil
+        })
+        st.metrics = metrics
+        stats = append(stats, st)
+    }
+    if c.EnableThrottle && selected(selection, "throttle") {
+        var metrics []sideMetric
+        st := c.runSource("throttle", func() (int, error) {
+            sctx, cancel := sourceContext(ctx, c.HwmonTimeout)
+            defer cancel()
+            counts, err := discoverThrottle(sctx, c.FS, c.CpuPath)
+            if err != nil {
+                slog.Warn("discoverThrottle error", "err", err)
+                return 0, err
+            }
+            for _, t := range counts {
+                metrics = append(metrics, sideMetric{c.throttle, prometheus.CounterValue, t.count, []string{t.cpu, t.pkg, t.scope}})
+            }
+            c.checkTimeout(sctx, "throttle", c.HwmonTimeout)
+            return len(counts), nil
+        })
+        st.metrics = metrics
+        stats = append(stats, st)
+    }
+    if c.EnableThermal && selected(selection, "thermal") {
+        stats = append(stats, c.runSource("thermal", func() (int, error) {
+            sctx, cancel := sourceContext(ctx, c.ThermalTimeout)
+            defer cancel()
+            s, err := c.thermal.Discover(sctx)
+            if err != nil && sctx.Err() == nil {
+                slog.Warn("discoverThermalSensors error", "err", err)
+            }
+            rs, failures := c.thermal.Read(sctx, rules.blocklist.filterSensors("thermal", rules.chips.filterSensors("thermal", s)))
+            readings = append(readings, fromSources(rs)...)
+            c.recordFailures("thermal", failures)
+            c.checkTimeout(sctx, "thermal", c.ThermalTimeout)
+            return sources.CountSensors(s), err
+        }))
+    }
+
+    // Also collect via sensors -j if enabled
+    if c.EnableSensorsCli && selected(selection, "sensors-cli") {
+        stats = append(stats, c.runSource("sensors-cli", func() (int, error) {
+            srs, err := c.sensorsCli.Readings(ctx)
+            failures, err := sources.SplitErrors(err)
+            c.recordFailures("sensors-cli", failures)
+            rs := fromSources(srs)
+            if err == nil {
+                readings = append(readings, withSource("sensors-cli", rules.blocklist.filterReadings("sensors-cli", rules.chips.filterReadings("sensors-cli", rs)))...)
+            } else if !sensorsCliWarned {
+                slog.Warn("discoverSensorsCLI error (désactivez -enable-sensors-cli ou installez lm-sensors)", "err", err)
+                sensorsCliWarned = true
+            }
+            return countReadings(rs), err
+        }))
+    }
+
+    // FreeBSD has no /sys, its drivers publish the temperatures as sysctls
+    if c.EnableSysctl && selected(selection, "sysctl") {
+        stats = append(stats, c.runSource("sysctl", func() (int, error) {
+            srs, err := c.sysctl.Readings(ctx)
+            failures, err := sources.SplitErrors(err)
+            c.recordFailures("sysctl", failures)
+            rs := fromSources(srs)
+            if err == nil {
+                readings = append(readings, rules.blocklist.filterReadings("sysctl", rs)...)
+            } else if !sysctlWarned {
+                slog.Warn("sysctl error (désactivez -enable-sysctl hors FreeBSD)", "err", err)
+                sysctlWarned = true
+            }
+            return countReadings(rs), err
+        }))
+    }
+
+    // remote hosts are read in parallel, each under its own timeout
+    if len(c.ssh) > 0 && selected(selection, "ssh") {
+        var targets []targetStats
+        st := c.runSource("ssh", func() (int, error) {
+            rs, ts, err := c.gatherSSH(ctx, rules)
+            readings = append(readings, rs...)
+            targets = ts
+            return countReadings(rs), err
+        })
+        st.targets = targets
+        stats = append(stats, st)
+    }
+
+    // IPMI readings come from the BMC and are cached between scrapes
+    if c.EnableIPMI && selected(selection, "ipmi") {
+        stats = append(stats, c.runSource("ipmi", func() (int, error) {
+            rs, err := c.ipmi.get(ctx, func() ([]reading, error) {
+                return c.discoverIPMI(ctx)
+            })
+            if err == nil {
+                readings = append(readings, withSource("ipmi", rs)...)
+            } else if !ipmiWarned {
+                slog.Warn("discoverIPMI error (désactivez -enable-ipmi ou installez la commande)", "backend", c.IPMIBackend, "command", c.ipmiBin(), "err", err)
+                ipmiWarned = true
+            }
+            return countReadings(rs), err
+        }))
+    }
+
+    // storcli takes a few seconds, so like IPMI it is served from cache
+    if c.EnableStorcli && selected(selection, "storcli") {
+        stats = append(stats, c.runSource("storcli", func() (int, error) {
+            rs, err := c.storcli.get(ctx, func() ([]reading, error) {
+                return discoverStorcli(ctx, c.StorcliPath, c.StorcliTimeout)
+            })
+            if err == nil {
+                readings = append(readings, withSource("storcli", rs)...)
+            } else if !storcliWarned {
+                slog.Warn("discoverStorcli error (désactivez -enable-storcli ou vérifiez -storcli-path)", "err", err)
+                storcliWarned = true
+            }
+            return countReadings(rs), err
+        }))
+    }
+
+    if c.EnableNvidia && selected(selection, "nvidia") {
+        stats = append(stats, c.runSource("nvidia", func() (int, error) {
+            rs, err := discoverNvidia(ctx, c.NvidiaSmiPath, c.NvidiaTimeout)
+            if err == nil {
+                readings = append(readings, withSource("nvidia", rs)...)
+            } else if !nvidiaWarned {
+                slog.Warn("discoverNvidia error (désactivez -enable-nvidia ou vérifiez le pilote NVIDIA)", "err", err)
+                nvidiaWarned = true
+            }
+            return countReadings(rs), err
+        }))
+    }
+
+    if c.EnableVcgencmd && selected(selection, "vcgencmd") {
+        stats = append(stats, c.runSource("vcgencmd", func() (int, error) {
+            rs, err := discoverVcgencmd(ctx, c.VcgencmdPath, c.VcgencmdTimeout)
+            if err == nil {
+                readings = append(readings, withSource("vcgencmd", rs)...)
+            } else if !vcgencmdWarned {
+                slog.Warn("discoverVcgencmd error (désactivez -enable-vcgencmd hors Raspberry Pi)", "err", err)
+                vcgencmdWarned = true
+            }
+            return countReadings(rs), err
+        }))
+    }
+
+    // an unreachable UPS daemon only drops its series and bumps the error counters
+    if c.ApcupsdAddress != "" && selected(selection, "apcupsd") {
+        stats = append(stats, c.runSource("apcupsd", func() (int, error) {
+            rs, err := discoverApcupsd(ctx, c.ApcupsdAddress, c.ApcupsdTimeout)
+            if err == nil {
+                readings = append(readings, withSource("apcupsd", rs)...)
+            } else {
+                c.apcErrors.Inc()
+                slog.Warn("discoverApcupsd error", "err", err)
+            }
+            return countReadings(rs), err
+        }))
+    }
+
+    // readings of the reachable UPSes are kept even when another one fails
+    if len(c.NutUPS) > 0 && selected(selection, "nut") {
+        stats = append(stats, c.runSource("nut", func() (int, error) {
+            rs, err := discoverNut(ctx, c.NutUPS, c.NutTimeout)
+            readings = append(readings, withSource("nut", rs)...)
+            return countReadings(rs), err
+        }))
+    }
+
+    if c.EnableLiquidctl && selected(selection, "liquidctl") {
+        stats = append(stats, c.runSource("liquidctl", func() (int, error) {
+            rs, err := discoverLiquidctl(ctx, c.LiquidctlPath, c.LiquidctlTimeout)
+            if err == nil {
+                readings = append(readings, withSource("liquidctl", rs)...)
+            } else if !liquidctlWarned {
+                slog.Warn("discoverLiquidctl error (désactivez -enable-liquidctl ou installez liquidctl)", "err", err)
+                liquidctlWarned = true
+            }
+            return countReadings(rs), err
+        }))
+    }
+    // cached sources keep the time of their fetch
+    now := time.Now()
+    for i := range readings {
+        if readings[i].at.IsZero() {
+            readings[i].at = now
+        }
+    }
+    sanitizeReadings(readings)
+    annotateCoretemp(readings)
+    annotateK10temp(readings)
+    if c.CorrectTctl {
+        correctTctl(readings, c.tctlOffset)
+    }
+    return readings, stats
+}
+
+// Collect implements prometheus.Collector with every source
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+    c.collect(c.ctx, ch, nil)
+}
+
+// scrapeView binds the collector to one /metrics request: its deadline and, for the collect[]
+// parameter, the sources it asked for (nil for every source)
+type scrapeView struct {
+    *Collector
+    ctx       context.Context
+    selection map[string]bool
+}
+
+func (v scrapeView) Collect(ch chan<- prometheus.Metric) {
+    v.Collector.collect(v.ctx, ch, v.selection)
+}
+
+// collect exports the readings of every enabled source, or only those of sources when not nil.
+// Sources still running when ctx expires are cut short.
+func (c *Collector) collect(ctx context.Context, ch chan<- prometheus.Metric, selection map[string]bool) {
+    start := time.Now()
+    c.inflight.Add(1)
+    defer c.inflight.Done()
+    var (
+        readings []reading
+        stats    []sourceStats
+    )
+    if selection == nil {
+        readings, stats = c.latest(ctx, start)
+    } else {
+        readings, stats = c.selectedReadings(ctx, selection)
+    }
+
+    // every series is built from this scrape's readings, so concurrent scrapes never share
+    // state and sensors that disappeared are simply not emitted
+    var ms metricSet
+    // maxima come from the exported readings, so filtered or blocked sensors cannot skew them;
+    // they describe this node, so the remote hosts of the ssh source stay out
+    chipMax := make(map[string]float64)
+    for _, r := range readings {
+        if r.kind == kindTemperature && r.target == "" {
+            if m, ok := chipMax[r.chip]; !ok || r.value > m {
+                chipMax[r.chip] = r.value
+            }
+        }
+        // -histogram-only keeps fans, voltages... but none of the per-sensor temperature series
+        if c.HistogramOnly && (r.kind == kindTemperature || r.kind.Threshold()) {
+            continue
+        }
+        lv := c.labelValues(r)
+        vt := prometheus.GaugeValue
+        if r.kind == kindEnergy {
+            vt = prometheus.CounterValue
+        }
+        // readings taken during this scrape keep the scrape time, as without -timestamps
+        var at time.Time
+        if c.Timestamps && r.at.Before(start) {
+            at = r.at
+        }
+        ms.addAt(at, c.readingDesc(r.kind), vt, r.value, lv...)
+        if r.kind == kindTemperature {
+            ms.addAt(at, c.lastRead, prometheus.GaugeValue, float64(r.at.UnixNano())/1e9, lv...)
+            if c.Fahrenheit {
+                ms.addAt(at, c.sensorsF, prometheus.GaugeValue, c.round(celsiusToFahrenheit(r.value)), lv...)
+            }
+            if c.Kelvin {
+                ms.addAt(at, c.sensorsK, prometheus.GaugeValue, c.round(celsiusToKelvin(r.value)), lv...)
+            }
+        }
+        if r.adapter != "" {
+            ms.add(c.chipInfo, prometheus.GaugeValue, 1, r.chip, r.adapter)
+        }
+        if r.kind == kindTemperature && r.path != "" {
+            ms.add(c.sensorInfo, prometheus.GaugeValue, 1, append(slices.Clip(lv), r.driver, r.path)...)
+        }
+    }
+    if len(chipMax) > 0 {
+        nodeMax := math.Inf(-1)
+        for chip, m := range chipMax {
+            ms.add(c.chipMax, prometheus.GaugeValue, m, chip)
+            nodeMax = max(nodeMax, m)
+        }
+        ms.add(c.nodeMax, prometheus.GaugeValue, nodeMax)
+    }
+    for chip, n := range overThreshold(readings, kindCrit) {
+        ms.add(c.overCrit, prometheus.GaugeValue, float64(n), chip)
+    }
+    for chip, n := range overThreshold(readings, kindMax) {
+        ms.add(c.overMax, prometheus.GaugeValue, float64(n), chip)
+    }
+    if c.peaks != nil {
+        c.peaks.update(readings, c.labelValues)
+    }
+    if c.peaks != nil && !c.HistogramOnly {
+        for _, p := range c.peaks.snapshot(selection) {
+            ms.add(c.peak, prometheus.GaugeValue, p.value, p.labels...)
+        }
+    }
+    // disabled sources have no entry, so they export no success series at all
+    for _, st := range stats {
+        for _, m := range st.metrics {
+            ms.add(m.desc, m.vt, m.value, m.labels...)
+        }
+        success := 0.0
+        if st.success {
+            success = 1
+        }
+        ms.add(c.success, prometheus.GaugeValue, success, st.source)
+        ms.add(c.discovered, prometheus.GaugeValue, float64(st.discovered), st.source)
+        ms.add(c.sourceTime, prometheus.GaugeValue, st.duration.Seconds(), st.source)
+        for _, t := range st.targets {
+            up := 0.0
+            if t.success {
+                up = 1
+            }
+            ms.add(c.sshUp, prometheus.GaugeValue, up, t.target)
+            ms.add(c.sshTime, prometheus.GaugeValue, t.duration.Seconds(), t.target)
+        }
+    }
+    ms.add(c.exported, prometheus.GaugeValue, float64(countReadings(readings)))
+    if snap := c.snapshot.Load(); snap != nil && selection == nil {
+        ms.add(c.snapAge, prometheus.GaugeValue, time.Since(snap.at).Seconds())
+    }
+
+    // export metrics
+    ms.send(ch)
+    if c.ApcupsdAddress != "" {
+        c.apcErrors.Collect(ch)
+    }
+    c.discarded.Collect(ch)
+    c.collisions.Collect(ch)
+    c.timeouts.Collect(ch)
+    c.panics.Collect(ch)
+    c.errors.Collect(ch)
+    if len(c.ssh) > 0 {
+        c.sshErrors.Collect(ch)
+    }
+    c.readErrors.Collect(ch)
+    c.abandoned.Collect(ch)
+    c.failures.Collect(ch)
+    c.stale.Collect(ch)
+    c.reloadOK.Collect(ch)
+    if c.histogram != nil {
+        c.histogram.Collect(ch)
+    }
+    ch <- prometheus.MustNewConstMetric(c.scrapeTime, prometheus.GaugeValue, time.Since(start).Seconds())
+}
+
+// readings runs the whole gather pipeline. Gathers are serialized: a scrape arriving while
+// another one is gathering waits for it and reuses its result rather than running every
+// command a second time. The returned slice is shared and must not be modified. A scrape whose
+// deadline expired while waiting gets nothing: its scraper has already given up.
+func (c *Collector) readings(ctx context.Context, since time.Time) ([]reading, []sourceStats) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.lastGather.After(since) {
+        return c.lastReadings, c.lastStats
+    }
+    if ctx.Err() != nil {
+        return nil, nil
+    }
+    readings, stats := c.pipeline(ctx, nil)
+    c.lastReadings, c.lastStats, c.lastGather = readings, stats, time.Now()
+    c.record(&snapshot{readings: readings, stats: stats, at: c.lastGather})
+    return readings, stats
+}
+
+// selectedReadings runs the pipeline for the sources of a collect[] scrape. It waits for a
+// running gather like readings does but neither reuses nor replaces the shared result.
+func (c *Collector) selectedReadings(ctx context.Context, selection map[string]bool) ([]reading, []sourceStats) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if ctx.Err() != nil {
+        return nil, nil
+    }
+    readings, stats := c.pipeline(ctx, selection)
+    return c.dropStale(readings, time.Now()), stats
+}
+
+// pipeline gathers the sources and applies calibration, bounds, deduplication, filters and
+// renames; c.mu must be held.
+func (c *Collector) pipeline(ctx context.Context, selection map[string]bool) ([]reading, []sourceStats) {
+    // set here rather than by the callers: scrapes queued on c.mu behind a stuck gather must not
+    // move its start forward, nor clear it when they give up
+    c.collecting.Store(time.Now().UnixNano())
+    defer c.collecting.Store(0)
+    rs := c.rules.Load()
+    readings, stats := c.gather(ctx, rs, selection)
+    c.joules.accumulate(readings)
+    readings = c.dropInvalid(calibrate(c.dropSentinels(readings), rs.calibration))
+    if c.Round {
+        roundReadings(readings, c.Precision)
+    }
+    if c.DedupeCli && c.EnableHwmon && c.EnableSensorsCli {
+        readings = dropDuplicateCLIReadings(readings)
+    }
+    if c.Dedupe {
+        readings = dedupeReadings(readings, c.SourcePriority)
+    }
+    readings = c.filter.apply(readings, !filterLogged, skipLevel(c.LogFiltered))
+    filterLogged = true
+    readings = rename(readings, rs.renameRules)
+    readings = c.resolveCollisions(readings)
+    // observed here, once per gather: scrapes serving the same readings must not count them again
+    if c.histogram != nil {
+        for _, r := range readings {
+            if r.kind == kindTemperature {
+                c.histogram.WithLabelValues(r.source).Observe(r.value)
+            }
+        }
+    }
+    return readings, stats
+}
+
+// current returns the readings for a push target: the snapshot with -collect-interval, otherwise
+// a gather tracked like a scrape for shutdown
+func (c *Collector) current() []reading {
+    c.inflight.Add(1)
+    defer c.inflight.Done()
+    readings, _ := c.latest(c.ctx, time.Now())
+    return readings
+}
+
+// Wait blocks until every in-flight collection has returned or ctx expires
+func (c *Collector) Wait(ctx context.Context) error {
+    done := make(chan struct{})
+    go func() {
+        c.inflight.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// overThreshold counts, per chip, the temperatures at or above their own threshold of the given
+// kind. Chips with such a threshold are always listed, with 0 when no sensor reaches it; sensors
+// without one, and those of remote ssh targets, do not take part.
+func overThreshold(readings []reading, kind metricKind) map[string]int {
+    type sensorKey struct{ source, chip, name, label, pkg, device string }
+    temps := make(map[sensorKey]float64)
+    for _, r := range readings {
+        if r.kind == kindTemperature && r.target == "" {
+            temps[sensorKey{r.source, r.chip, r.name, r.label, r.pkg, r.device}] = r.value
+        }
+    }
+    counts := make(map[string]int)
+    for _, r := range readings {
+        if r.kind != kind {
+            continue
+        }
+        t, ok := temps[sensorKey{r.source, r.chip, r.name, r.label, r.pkg, r.device}]
+        if !ok {
+            continue
+        }
+        n := counts[r.chip]
+        if t >= r.value {
+            n++
+        }
+        counts[r.chip] = n
+    }
+    return counts
+}
+
+// labelValues returns the chip, sensor, label (and source) label values of a reading
+func (c *Collector) labelValues(r reading) []string {
+    lv := []string{r.chip, r.name, r.label, r.pkg, r.core, r.ccd, r.device}
+    if c.SourceLabel {
+        lv = append(lv, r.source)
+    }
+    if len(c.ssh) > 0 {
+        lv = append(lv, r.target)
+    }
+    return lv
+}
+
+// readingDesc returns the metric family matching a reading's kind
+func (c *Collector) readingDesc(kind metricKind) *prometheus.Desc {
+    switch kind {
+    case kindMax:
+        return c.max
+    case kindCrit:
+        return c.crit
+    case kindCritHyst:
+        return c.critHyst
+    case kindLowCrit:
+        return c.lcrit
+    case kindFan:
+        return c.fanSpeed
+    case kindVoltage:
+        return c.voltage
+    case kindUPSLineVoltage:
+        return c.upsLineV
+    case kindUPSLoad:
+        return c.upsLoad
+    case kindEnergy:
+        return c.energy
+    }
+    return c.sensors
+}
+
+// missingBinary logs a one-time startup warning when an opt-in source's command is not installed.
+// The caller stores the result in the source's warned flag so Collect stays quiet afterwards.
+func missingBinary(bin, flagName string) bool {
+    if _, err := exec.LookPath(bin); err != nil {
+        slog.Warn("commande introuvable, désactivez l'option ou installez la commande", "flag", flagName, "command", bin, "err", err)
+        return true
+    }
+    return false
+}
+
+// Discover runs the first gather at startup: it logs what each source found, warns about the
+// calibration entries matching no sensor (a typo in chip or label) and returns the summary
+// with the number of sensors found
+func (c *Collector) Discover() (string, int) {
+    rules := c.rules.Load()
+    readings, stats := c.gather(c.ctx, rules, nil)
+    summary, total := discoverySummary(stats)
+    slog.Info("capteurs découverts", "sources", summary)
+    c.mu.Lock()
+    c.observe(stats)
+    c.mu.Unlock()
+    if len(rules.calibration) > 0 {
+        reportUnmatchedCalibration(readings, rules.calibration)
+    }
+    return summary, total
+}
+
+// Start launches the background work: with -collect-interval the first snapshot is taken
+// before returning, so scrapes never wait on a gather, then refreshed on its own timer; the
+// peaks are sampled between scrapes and the hwmon discovery cache follows hotplug events.
+func (c *Collector) Start() {
+    if c.CollectInterval > 0 {
+        c.refresh()
+        go c.collectLoop(c.ctx, c.CollectInterval)
+    }
+    if c.peaks != nil {
+        go c.samplePeaks(c.ctx, c.PeakInterval)
+    }
+    if c.EnableHwmon && c.HwmonCache > 0 {
+        for _, dir := range c.HwmonPaths {
+            c.hwmon.Cache.Watch(c.ctx, dir)
+        }
+    }
+}
+
+// Context is cancelled by Stop; the push outputs run until then
+func (c *Collector) Context() context.Context {
+    return c.ctx
+}
+
+// Stop kills the running commands, so in-flight scrapes return, and ends the background work
+func (c *Collector) Stop() {
+    c.cancel()
+    for _, t := range c.ssh {
+        t.Close()
+    }
+}
+
+// Ready is the /readyz state: a sensor was found and the last collections did not all fail
+func (c *Collector) Ready() bool {
+    return c.ready.Load()
+}
+
+// ResetPeaks clears temperature_peak_celsius, like POST /-/reset-peaks
+func (c *Collector) ResetPeaks() {
+    if c.peaks != nil {
+        c.peaks.reset()
+    }
+}
+
+// RunningFor returns how long the running collection has been going, 0 when idle
+func (c *Collector) RunningFor() time.Duration {
+    start := c.collecting.Load()
+    if start == 0 {
+        return 0
+    }
+    return time.Since(time.Unix(0, start))
+}
+
+// WriteInventory prints every value a fresh gather finds and what the pipeline does with it,
+// as a table, json or csv
+func (c *Collector) WriteInventory(w io.Writer, format string) error {
+    return printInventory(w, c.inventory(), format)
+}
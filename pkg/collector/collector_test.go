@@ -0,0 +1,82 @@
+package collector
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/prometheus/client_golang/prometheus"
+    dto "github.com/prometheus/client_model/go"
+)
+
+// writeTree creates files (path relative to the returned temp dir → content)
+func writeTree(t testing.TB, files map[string]string) string {
+    t.Helper()
+    root := t.TempDir()
+    writeTreeAt(t, root, files)
+    return root
+}
+
+// writeTreeAt creates files under root
+func writeTreeAt(t testing.TB, root string, files map[string]string) {
+    t.Helper()
+    for path, content := range files {
+        p := filepath.Join(root, path)
+        if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+// fakeHwmonFiles is the hwmon class of the host the sensors fixtures of pkg/sources come from
+var fakeHwmonFiles = map[string]string{
+    "hwmon0/name":        "k10temp\n",
+    "hwmon0/temp1_input": "45250\n",
+    "hwmon0/temp1_label": "Tctl\n",
+    "hwmon0/temp3_input": "41000\n",
+    "hwmon0/temp3_label": "Tccd1\n",
+    "hwmon1/name":        "nvme\n",
+    "hwmon1/temp1_input": "38850\n",
+    "hwmon1/temp1_label": "Composite\n",
+    "hwmon1/temp1_max":   "81850\n",
+    "hwmon1/temp1_crit":  "84850\n",
+    "hwmon2/name":        "amdgpu\n",
+    "hwmon2/temp1_input": "49000\n",
+    "hwmon2/temp1_label": "edge\n",
+    "hwmon2/temp1_crit":  "100000\n",
+    "hwmon3/name":        "acpitz\n",
+    "hwmon3/temp1_input": "16800\n",
+    "hwmon3/temp1_crit":  "20800\n",
+}
+
+// gather registers c in a fresh registry and returns the metrics of family name
+func gather(t testing.TB, c prometheus.Collector, name string) []*dto.Metric {
+    t.Helper()
+    reg := prometheus.NewPedanticRegistry()
+    if err := reg.Register(c); err != nil {
+        t.Fatal(err)
+    }
+    families, err := reg.Gather()
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, f := range families {
+        if f.GetName() == name {
+            return f.GetMetric()
+        }
+    }
+    return nil
+}
+
+// seriesCount returns the number of series of family name in families
+func seriesCount(families []*dto.MetricFamily, name string) int {
+    for _, f := range families {
+        if f.GetName() == name {
+            return len(f.GetMetric())
+        }
+    }
+    return 0
+}
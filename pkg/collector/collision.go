@@ -0,0 +1,79 @@
+package collector
+
+import (
+    "log/slog"
+    "path"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// collisionLogged remembers the label sets whose collision was already logged
+var collisionLogged sync.Map
+
+// channelRe extracts the channel of a sysfs file, temp3 from temp3_input or temp3_crit
+var channelRe = regexp.MustCompile(`^([a-z]+[0-9]+)_`)
+
+// resolveCollisions gives distinct labels to readings that would export the same series, which
+// the last one would otherwise silently replace: each reading sharing its labels with another
+// gets its sysfs channel (temp1, temp2...) appended to the label, or its position among the
+// readings of its kind for the sources without sysfs path. Thresholds share the labels of their
+// temperature and are renamed along with it. Readings keep their order from one collection to
+// the next, so the chosen labels are stable. Collisions are counted per chip and logged once.
+func (c *Collector) resolveCollisions(readings []reading) []reading {
+    groups := make(map[string][]int)
+    var keys []string
+    for i, r := range readings {
+        key := strings.Join(c.labelValues(r), "\xff")
+        if _, ok := groups[key]; !ok {
+            keys = append(keys, key)
+        }
+        groups[key] = append(groups[key], i)
+    }
+    for _, key := range keys {
+        idx := groups[key]
+        perKind := make(map[metricKind]int)
+        collided := 0
+        for _, i := range idx {
+            if perKind[readings[i].kind]++; perKind[readings[i].kind] > 1 {
+                collided++
+            }
+        }
+        if collided == 0 {
+            continue
+        }
+        first := readings[idx[0]]
+        // the channel only tells readings apart when those of each kind have distinct ones
+        suffixes := make([]string, len(idx))
+        seen := make(map[string]bool)
+        for n, i := range idx {
+            m := channelRe.FindStringSubmatch(path.Base(readings[i].path))
+            k := strconv.Itoa(int(readings[i].kind)) + "/"
+            if m == nil || seen[k+m[1]] {
+                suffixes = nil
+                break
+            }
+            seen[k+m[1]] = true
+            suffixes[n] = m[1]
+        }
+        position := make(map[metricKind]int)
+        for n, i := range idx {
+            position[readings[i].kind]++
+            suffix := strconv.Itoa(position[readings[i].kind])
+            if suffixes != nil {
+                suffix = suffixes[n]
+            }
+            if readings[i].label == "" {
+                readings[i].label = suffix
+            } else {
+                readings[i].label += "_" + suffix
+            }
+        }
+        c.collisions.WithLabelValues(first.chip).Add(float64(collided))
+        if _, logged := collisionLogged.LoadOrStore(key, struct{}{}); !logged {
+            slog.Warn("lectures avec les mêmes labels, libellés complétés", "source", first.source, "chip", first.chip, "sensor", first.name, "label", first.label, "count", collided+1)
+        }
+    }
+    return readings
+}
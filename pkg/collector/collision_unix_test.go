@@ -0,0 +1,43 @@
+//go:build unix
+
+package collector
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+// TestCollisionLabelsStable scrapes sensors -j output where two inputs of one section export the
+// same labels: the positional suffixes must stick to the same input on every scrape
+func TestCollisionLabelsStable(t *testing.T) {
+    sensors := fakeSensorsOutput(t, []byte(`{
+   "it8686-isa-0a40":{
+      "Adapter": "ISA adapter",
+      "CPU":{
+         "temp1_input": 40.000,
+         "temp2_input": 55.000,
+         "temp3_input": 61.000,
+         "temp3_crit": 90.000
+      }
+   }
+}`))
+    c, err := New(Options{EnableSensorsCli: true, SensorsCliPath: sensors, SensorsCliFormat: "json", SensorsTimeout: 5 * time.Second})
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]float64{"1": 40, "2": 55, "3": 61}
+    for scrape := 1; scrape <= 20; scrape++ {
+        got := map[string]float64{}
+        for _, m := range gather(t, c, "temperature_celsius") {
+            for _, l := range m.GetLabel() {
+                if l.GetName() == "label" {
+                    got[l.GetValue()] = m.GetGauge().GetValue()
+                }
+            }
+        }
+        if !reflect.DeepEqual(got, want) {
+            t.Fatalf("scrape %d: temperature_celsius by label = %v, want %v", scrape, got, want)
+        }
+    }
+}
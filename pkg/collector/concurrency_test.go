@@ -0,0 +1,81 @@
+package collector
+
+import (
+    "strings"
+    "sync"
+    "testing"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// TestConcurrentGathers scrapes from many goroutines at once, as Prometheus and a curl do:
+// every scrape must hold the whole set of series, never a half-built or duplicated one
+func TestConcurrentGathers(t *testing.T) {
+    c, err := New(Options{EnableHwmon: true, HwmonPaths: []string{writeTree(t, fakeHwmonFiles)}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    reg := prometheus.NewPedanticRegistry()
+    if err := reg.Register(c); err != nil {
+        t.Fatal(err)
+    }
+    var wg sync.WaitGroup
+    for g := 0; g < 16; g++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := 0; i < 20; i++ {
+                families, err := reg.Gather()
+                if err != nil {
+                    t.Error(err)
+                    return
+                }
+                if n := seriesCount(families, "temperature_celsius"); n != 5 {
+                    t.Errorf("scrape with %d temperature_celsius series, want 5", n)
+                    return
+                }
+            }
+        }()
+    }
+    wg.Wait()
+}
+
+// TestConcurrentCollects calls Collect 50 times at once on one collector, each with its own
+// channel, and checks each call emits every sensor
+func TestConcurrentCollects(t *testing.T) {
+    c, err := New(Options{EnableHwmon: true, HwmonPaths: []string{writeTree(t, fakeHwmonFiles)}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    const scrapes = 50
+    counts := make([]int, scrapes)
+    start := make(chan struct{})
+    var wg sync.WaitGroup
+    for i := 0; i < scrapes; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            ch := make(chan prometheus.Metric)
+            done := make(chan struct{})
+            go func() {
+                defer close(done)
+                for m := range ch {
+                    if strings.Contains(m.Desc().String(), `"temperature_celsius"`) {
+                        counts[i]++
+                    }
+                }
+            }()
+            <-start
+            c.Collect(ch)
+            close(ch)
+            <-done
+        }(i)
+    }
+    close(start)
+    wg.Wait()
+    for i, n := range counts {
+        if n != 5 {
+            t.Errorf("Collect %d emitted %d temperature_celsius series, want 5", i, n)
+        }
+    }
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+    "path/filepath"
+    "regexp"
+    "strings"
+)
+
+// coretempLabel matches the labels of Intel's coretemp driver: "Package id N" (or "Physical id N"
+// on older kernels) for the package sensor, "Core N" for each core, P and E cores alike
+var coretempLabel = regexp.MustCompile(`^(?:(?:Package|Physical) id (\d+)|Core (\d+))$`)
+
+// isCoretemp reports whether chip is the coretemp driver, as a hwmon name or an lm-sensors chip
+// (coretemp-isa-0000)
+func isCoretemp(chip string) bool {
+    return chip == "coretemp" || strings.HasPrefix(chip, "coretemp-")
+}
+
+// labelOrName is the hwmon label of a reading, or its lm-sensors section name (sensors -j leaves
+// the label empty)
+func labelOrName(r reading) string {
+    if r.label != "" {
+        return r.label
+    }
+    return r.name
+}
+
+// annotateCoretemp fills the package and core of coretemp readings. Core numbers restart on every
+// socket, so each core takes the package id of the sensor sharing its hwmon directory (or its
+// lm-sensors chip): that keeps "Core 0" of both sockets apart.
+func annotateCoretemp(readings []reading) {
+    type instance struct{ source, chip, dir string }
+    inst := func(r reading) instance {
+        dir := ""
+        if r.path != "" {
+            dir = filepath.Dir(r.path)
+        }
+        return instance{r.source, r.chip, dir}
+    }
+    packages := make(map[instance]string)
+    for _, r := range readings {
+        if !isCoretemp(r.chip) {
+            continue
+        }
+        if m := coretempLabel.FindStringSubmatch(labelOrName(r)); m != nil && m[1] != "" {
+            packages[inst(r)] = m[1]
+        }
+    }
+    for i := range readings {
+        r := &readings[i]
+        if !isCoretemp(r.chip) {
+            continue
+        }
+        m := coretempLabel.FindStringSubmatch(labelOrName(*r))
+        if m == nil {
+            continue
+        }
+        r.pkg = packages[inst(*r)]
+        r.core = m[2]
+    }
+}
@@ -0,0 +1,162 @@
+package collector
+
+import (
+    "fmt"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "testing"
+)
+
+// coretempReadings builds the hwmon readings of one coretemp instance from its labels
+func coretempReadings(dir string, labels ...string) []reading {
+    var rs []reading
+    for i, l := range labels {
+        rs = append(rs, reading{source: "hwmon", chip: "coretemp", name: "coretemp", label: l, path: fmt.Sprintf("%s/temp%d_input", dir, i+1)})
+    }
+    return rs
+}
+
+// annotations formats the package and core given to readings as "dir label package/core"
+func annotations(readings []reading) []string {
+    var got []string
+    for _, r := range readings {
+        got = append(got, fmt.Sprintf("%s %s %s/%s", filepath.Dir(r.path), labelOrName(r), r.pkg, r.core))
+    }
+    sort.Strings(got)
+    return got
+}
+
+func TestAnnotateCoretemp(t *testing.T) {
+    const h0, h1 = "/sys/class/hwmon/hwmon0", "/sys/class/hwmon/hwmon1"
+    tests := []struct {
+        name     string
+        readings []reading
+        want     []string
+    }{
+        {
+            name:     "single socket",
+            readings: coretempReadings(h0, "Package id 0", "Core 0", "Core 1", "Core 2", "Core 3"),
+            want: []string{
+                "/sys/class/hwmon/hwmon0 Core 0 0/0",
+                "/sys/class/hwmon/hwmon0 Core 1 0/1",
+                "/sys/class/hwmon/hwmon0 Core 2 0/2",
+                "/sys/class/hwmon/hwmon0 Core 3 0/3",
+                "/sys/class/hwmon/hwmon0 Package id 0 0/",
+            },
+        },
+        {
+            // core numbers restart on the second socket, only the package tells them apart
+            name: "dual socket",
+            readings: append(coretempReadings(h0, "Package id 0", "Core 0", "Core 1"),
+                coretempReadings(h1, "Package id 1", "Core 0", "Core 1")...),
+            want: []string{
+                "/sys/class/hwmon/hwmon0 Core 0 0/0",
+                "/sys/class/hwmon/hwmon0 Core 1 0/1",
+                "/sys/class/hwmon/hwmon0 Package id 0 0/",
+                "/sys/class/hwmon/hwmon1 Core 0 1/0",
+                "/sys/class/hwmon/hwmon1 Core 1 1/1",
+                "/sys/class/hwmon/hwmon1 Package id 1 1/",
+            },
+        },
+        {
+            // Alder Lake: P-cores numbered by 4, then the E-cores in a block, all in one package
+            name:     "hybrid",
+            readings: coretempReadings(h0, "Package id 0", "Core 0", "Core 4", "Core 8", "Core 32", "Core 33"),
+            want: []string{
+                "/sys/class/hwmon/hwmon0 Core 0 0/0",
+                "/sys/class/hwmon/hwmon0 Core 32 0/32",
+                "/sys/class/hwmon/hwmon0 Core 33 0/33",
+                "/sys/class/hwmon/hwmon0 Core 4 0/4",
+                "/sys/class/hwmon/hwmon0 Core 8 0/8",
+                "/sys/class/hwmon/hwmon0 Package id 0 0/",
+            },
+        },
+        {
+            // older kernels, and a core whose package sensor was not exposed
+            name: "physical id and no package",
+            readings: append(coretempReadings(h0, "Physical id 0", "Core 0"),
+                coretempReadings(h1, "Core 0")...),
+            want: []string{
+                "/sys/class/hwmon/hwmon0 Core 0 0/0",
+                "/sys/class/hwmon/hwmon0 Physical id 0 0/",
+                "/sys/class/hwmon/hwmon1 Core 0 /0",
+            },
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            annotateCoretemp(tt.readings)
+            if got := annotations(tt.readings); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("annotateCoretemp() =\n  %q\nwant\n  %q", got, tt.want)
+            }
+        })
+    }
+}
+
+// TestAnnotateCoretempSensorsCli checks the lm-sensors chips of two sockets are kept apart too,
+// and that other chips with core-like labels are left alone
+func TestAnnotateCoretempSensorsCli(t *testing.T) {
+    readings := []reading{
+        {source: "sensors-cli", chip: "coretemp-isa-0000", name: "Package id 0"},
+        {source: "sensors-cli", chip: "coretemp-isa-0000", name: "Core 0"},
+        {source: "sensors-cli", chip: "coretemp-isa-0000", name: "Core 0", kind: kindCrit},
+        {source: "sensors-cli", chip: "coretemp-isa-0001", name: "Package id 1"},
+        {source: "sensors-cli", chip: "coretemp-isa-0001", name: "Core 0"},
+        {source: "sensors-cli", chip: "k10temp-pci-00c3", name: "Core 0"},
+    }
+    annotateCoretemp(readings)
+    var got []string
+    for _, r := range readings {
+        got = append(got, r.chip+" "+r.name+" "+r.pkg+"/"+r.core)
+    }
+    want := []string{
+        "coretemp-isa-0000 Package id 0 0/",
+        "coretemp-isa-0000 Core 0 0/0",
+        "coretemp-isa-0000 Core 0 0/0",
+        "coretemp-isa-0001 Package id 1 1/",
+        "coretemp-isa-0001 Core 0 1/0",
+        "k10temp-pci-00c3 Core 0 /",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("annotateCoretemp() =\n  %q\nwant\n  %q", got, want)
+    }
+}
+
+// TestDualSocketCoretempSeries exports two coretemp hwmon directories with the same labels:
+// every series must stay distinct through the package and core labels
+func TestDualSocketCoretempSeries(t *testing.T) {
+    files := map[string]string{}
+    for socket := 0; socket < 2; socket++ {
+        dir := fmt.Sprintf("hwmon%d/", socket)
+        files[dir+"name"] = "coretemp\n"
+        files[dir+"temp1_label"] = fmt.Sprintf("Package id %d\n", socket)
+        files[dir+"temp1_input"] = "50000\n"
+        for core := 0; core < 2; core++ {
+            ch := fmt.Sprintf("temp%d_", core+2)
+            files[dir+ch+"label"] = fmt.Sprintf("Core %d\n", core)
+            files[dir+ch+"input"] = fmt.Sprintf("%d\n", 40000+socket*1000+core)
+        }
+    }
+    c, err := New(Options{EnableHwmon: true, HwmonPaths: []string{writeTree(t, files)}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    var got []string
+    for _, m := range gather(t, c, "temperature_celsius") {
+        labels := map[string]string{}
+        for _, l := range m.GetLabel() {
+            labels[l.GetName()] = l.GetValue()
+        }
+        got = append(got, fmt.Sprintf("%s %s/%s %g", labels["label"], labels["package"], labels["core"], m.GetGauge().GetValue()))
+    }
+    sort.Strings(got)
+    want := []string{
+        "Core 0 0/0 40", "Core 0 1/0 41",
+        "Core 1 0/1 40.001", "Core 1 1/1 41.001",
+        "Package id 0 0/ 50", "Package id 1 1/ 50",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("temperature_celsius series =\n  %q\nwant\n  %q", got, want)
+    }
+}
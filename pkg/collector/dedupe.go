@@ -0,0 +1,139 @@
+package collector
+
+import (
+    "fmt"
+    "path/filepath"
+    "regexp"
+    "strings"
+)
+
+// knownSources lists the values of the source label, in the order sources are gathered
+var knownSources = []string{"hwmon", "thermal", "sensors-cli", "sysctl", "ssh", "ipmi", "storcli", "nvidia", "vcgencmd", "apcupsd", "nut", "liquidctl"}
+
+// checkSources rejects source names that no collector produces (typos in -source-priority)
+func checkSources(names []string) error {
+    for _, n := range names {
+        found := false
+        for _, k := range knownSources {
+            if n == k {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return fmt.Errorf("source %q inconnue (valeurs possibles: %s)", n, strings.Join(knownSources, ", "))
+        }
+    }
+    return nil
+}
+
+// readingKey identifies a logical sensor independently of the source that produced it
+type readingKey struct {
+    kind   metricKind
+    target string // a remote host never duplicates the local one
+    chip   string
+    name   string
+    label  string
+    pkg    string // coretemp cores of different sockets share their label
+}
+
+// logicalKey returns the readingKey of r. Backends name the same input differently: hwmon
+// reports chip k10temp, sensor k10temp, label Tctl where sensors -j reports chip
+// k10temp-pci-00c3 and section Tctl, so like dropDuplicateCLIReadings the lm-sensors chip loses
+// its bus suffix and both end up as the chip and its label (tempN when unlabeled).
+func logicalKey(r reading) readingKey {
+    k := readingKey{kind: r.kind, target: r.target, chip: r.chip, name: r.name, label: r.label, pkg: r.pkg}
+    switch r.source {
+    case "hwmon":
+        k.name, k.label = hwmonLogicalLabel(r), ""
+    case "sensors-cli":
+        k.chip = lmSensorsChipPrefix(r.chip)
+    }
+    return k
+}
+
+// dedupeReadings keeps, for each logical sensor, only the readings of the source listed first in
+// priority; sources missing from priority rank after all listed ones. Readings of the same source
+// are all kept: two identical devices are not duplicates, resolveCollisions tells them apart.
+func dedupeReadings(readings []reading, priority []string) []reading {
+    rank := func(source string) int {
+        for i, p := range priority {
+            if p == source {
+                return i
+            }
+        }
+        return len(priority)
+    }
+    best := map[readingKey]string{}
+    for _, r := range readings {
+        k := logicalKey(r)
+        if s, ok := best[k]; !ok || rank(r.source) < rank(s) {
+            best[k] = r.source
+        }
+    }
+    out := make([]reading, 0, len(readings))
+    for _, r := range readings {
+        if best[logicalKey(r)] == r.source {
+            out = append(out, r)
+        }
+    }
+    return out
+}
+
+// lm-sensors names chips "<prefix>-<bus>-<address>", e.g. k10temp-pci-00c3 or coretemp-isa-0000
+var lmSensorsChipRe = regexp.MustCompile(`^(.+?)-(?:isa|pci|i2c|spi|virtual|acpi|hid|mdio|scsi|sdio|platform)-`)
+
+// lmSensorsChipPrefix returns the driver prefix of an lm-sensors chip name, which is the hwmon chip name
+func lmSensorsChipPrefix(chip string) string {
+    if m := lmSensorsChipRe.FindStringSubmatch(chip); m != nil {
+        return m[1]
+    }
+    return chip
+}
+
+// hwmonLogicalLabel is the name lm-sensors gives the same input: its label, or tempN when unlabeled
+func hwmonLogicalLabel(r reading) string {
+    if r.label != "" {
+        return r.label
+    }
+    // temp3_input, temp3_crit_hyst... -> temp3
+    name, _, _ := strings.Cut(filepath.Base(r.path), "_")
+    return name
+}
+
+// dropDuplicateCLIReadings removes sensors -j readings that hwmon already reported.
+// sensors reads the same sysfs files, so a CLI reading whose chip prefix and section name match an
+// hwmon chip and label is the same logical sensor. Matching is count aware: with two nvme drives
+// but a single hwmon reading, only one CLI reading is dropped. Thresholds follow their input, so
+// they are dropped together with it and both sources never mix for one logical sensor.
+func dropDuplicateCLIReadings(readings []reading) []reading {
+    type logicalKey struct {
+        chip  string
+        label string
+    }
+    hwmon := map[logicalKey]int{}
+    for _, r := range readings {
+        if r.source == "hwmon" && r.kind == kindTemperature {
+            hwmon[logicalKey{r.chip, hwmonLogicalLabel(r)}]++
+        }
+    }
+    out := make([]reading, 0, len(readings))
+    var dropped *reading
+    for i, r := range readings {
+        if r.source == "sensors-cli" {
+            if r.kind == kindTemperature {
+                dropped = nil
+                k := logicalKey{lmSensorsChipPrefix(r.chip), r.name}
+                if hwmon[k] > 0 {
+                    hwmon[k]--
+                    dropped = &readings[i]
+                    continue
+                }
+            } else if dropped != nil && r.chip == dropped.chip && r.name == dropped.name && r.label == dropped.label {
+                continue
+            }
+        }
+        out = append(out, r)
+    }
+    return out
+}
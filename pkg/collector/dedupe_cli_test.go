@@ -0,0 +1,88 @@
+//go:build unix
+
+package collector
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "testing"
+    "time"
+)
+
+// fakeSensors writes a sensors script printing the sensors -j fixture of pkg/sources
+func fakeSensors(t *testing.T, fixture string) string {
+    t.Helper()
+    out, err := os.ReadFile(filepath.Join("..", "sources", "testdata", fixture))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return fakeSensorsOutput(t, out)
+}
+
+// fakeSensorsOutput writes a sensors script printing out
+func fakeSensorsOutput(t *testing.T, out []byte) string {
+    t.Helper()
+    dir := t.TempDir()
+    data := filepath.Join(dir, "sensors.out")
+    if err := os.WriteFile(data, out, 0o644); err != nil {
+        t.Fatal(err)
+    }
+    script := filepath.Join(dir, "sensors")
+    if err := os.WriteFile(script, []byte("#!/bin/sh\nexec cat '"+data+"'\n"), 0o755); err != nil {
+        t.Fatal(err)
+    }
+    return script
+}
+
+// TestDedupeCliAgainstHwmon runs hwmon and sensors -j over the same chips: with DedupeCli only
+// the chips lm-sensors alone knows (nct6798 here) come from the CLI.
+func TestDedupeCliAgainstHwmon(t *testing.T) {
+    hwmon := writeTree(t, fakeHwmonFiles)
+    sensors := fakeSensors(t, "sensors-full.json")
+    tests := []struct {
+        name      string
+        dedupeCli bool
+        want      []string
+    }{
+        {"dedupe", true, []string{
+            "hwmon acpitz", "hwmon amdgpu", "hwmon k10temp", "hwmon k10temp", "hwmon nvme",
+            "sensors-cli nct6798-isa-0290", "sensors-cli nct6798-isa-0290",
+        }},
+        {"opt-out", false, []string{
+            "hwmon acpitz", "hwmon amdgpu", "hwmon k10temp", "hwmon k10temp", "hwmon nvme",
+            "sensors-cli acpitz-acpi-0", "sensors-cli amdgpu-pci-0b00", "sensors-cli k10temp-pci-00c3", "sensors-cli k10temp-pci-00c3",
+            "sensors-cli nct6798-isa-0290", "sensors-cli nct6798-isa-0290", "sensors-cli nvme-pci-0100",
+        }},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c, err := New(Options{
+                EnableHwmon:      true,
+                HwmonPaths:       []string{hwmon},
+                EnableSensorsCli: true,
+                SensorsCliPath:   sensors,
+                SensorsCliFormat: "json",
+                SensorsTimeout:   5 * time.Second,
+                DedupeCli:        tt.dedupeCli,
+                SourceLabel:      true,
+            })
+            if err != nil {
+                t.Fatal(err)
+            }
+            var got []string
+            for _, m := range gather(t, c, "temperature_celsius") {
+                labels := map[string]string{}
+                for _, l := range m.GetLabel() {
+                    labels[l.GetName()] = l.GetValue()
+                }
+                got = append(got, labels["source"]+" "+labels["chip"])
+            }
+            sort.Strings(got)
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("temperature_celsius series by source and chip =\n  %q\nwant\n  %q", got, tt.want)
+            }
+        })
+    }
+}
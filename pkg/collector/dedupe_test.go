@@ -0,0 +1,49 @@
+package collector
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestDedupeReadingsAcrossBackends(t *testing.T) {
+    hwmonTctl := reading{source: "hwmon", path: "/sys/class/hwmon/hwmon1/temp1_input", chip: "k10temp", name: "k10temp", label: "Tctl", value: 45}
+    hwmonTccd := reading{source: "hwmon", path: "/sys/class/hwmon/hwmon1/temp3_input", chip: "k10temp", name: "k10temp", label: "Tccd1", value: 41}
+    hwmonNvme := reading{source: "hwmon", path: "/sys/class/hwmon/hwmon2/temp1_input", chip: "nvme", name: "nvme", value: 38}
+    hwmonNvmeCrit := reading{source: "hwmon", path: "/sys/class/hwmon/hwmon2/temp1_crit", chip: "nvme", name: "nvme", value: 84.85, kind: kindCrit}
+    cliTctl := reading{source: "sensors-cli", chip: "k10temp-pci-00c3", name: "Tctl", value: 45.1}
+    cliTccd := reading{source: "sensors-cli", chip: "k10temp-pci-00c3", name: "Tccd1", value: 41.1}
+    cliNvme := reading{source: "sensors-cli", chip: "nvme-pci-0100", name: "temp1", value: 38.1}
+    cliNvmeCrit := reading{source: "sensors-cli", chip: "nvme-pci-0100", name: "temp1", value: 84.85, kind: kindCrit}
+    cliOnly := reading{source: "sensors-cli", chip: "nct6798-isa-0290", name: "SYSTIN", value: 30}
+    readings := []reading{hwmonTctl, hwmonTccd, hwmonNvme, hwmonNvmeCrit, cliTctl, cliTccd, cliNvme, cliNvmeCrit, cliOnly}
+
+    tests := []struct {
+        name     string
+        priority []string
+        want     []reading
+    }{
+        {"hwmon first", []string{"hwmon", "sensors-cli"}, []reading{hwmonTctl, hwmonTccd, hwmonNvme, hwmonNvmeCrit, cliOnly}},
+        {"sensors-cli first", []string{"sensors-cli", "hwmon"}, []reading{cliTctl, cliTccd, cliNvme, cliNvmeCrit, cliOnly}},
+        {"unlisted sources rank last", []string{"sensors-cli"}, []reading{cliTctl, cliTccd, cliNvme, cliNvmeCrit, cliOnly}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := dedupeReadings(append([]reading(nil), readings...), tt.priority)
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("dedupeReadings() =\n%+v\nwant\n%+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestDedupeReadingsKeepsIdenticalDevices(t *testing.T) {
+    readings := []reading{
+        {source: "hwmon", path: "/sys/class/hwmon/hwmon3/temp1_input", chip: "mlx5", name: "mlx5", device: "0000:41:00.0", value: 50},
+        {source: "hwmon", path: "/sys/class/hwmon/hwmon4/temp1_input", chip: "mlx5", name: "mlx5", device: "0000:41:00.1", value: 52},
+        {source: "thermal", chip: "thermal", name: "x86_pkg_temp", label: "thermal_zone0", value: 40},
+    }
+    got := dedupeReadings(append([]reading(nil), readings...), []string{"hwmon", "sensors-cli", "thermal"})
+    if !reflect.DeepEqual(got, readings) {
+        t.Errorf("dedupeReadings() =\n%+v\nwant every reading kept\n%+v", got, readings)
+    }
+}
@@ -0,0 +1,42 @@
+package collector
+
+import "sync"
+
+// energyCounters turns the energy*_input registers of hwmon into joule counters. Drivers reset
+// them on reload and narrow ones wrap without announcing their range, so a value going
+// backwards is counted from zero, as raplCounters does without max_energy_range_uj, instead of
+// making the counter decrease.
+type energyCounters struct {
+    mu    sync.Mutex
+    last  map[string]float64
+    total map[string]float64
+}
+
+func newEnergyCounters() *energyCounters {
+    return &energyCounters{last: map[string]float64{}, total: map[string]float64{}}
+}
+
+// accumulate replaces the raw value of the energy readings, in joules, by their accumulated total
+func (ec *energyCounters) accumulate(readings []reading) {
+    ec.mu.Lock()
+    defer ec.mu.Unlock()
+    for i := range readings {
+        r := &readings[i]
+        if r.kind != kindEnergy {
+            continue
+        }
+        // the same sysfs path exists on every ssh target, and under hwmon and ssh alike
+        key := r.source + "\xff" + r.target + "\xff" + r.path
+        last, seen := ec.last[key]
+        switch {
+        case !seen:
+            ec.total[key] = r.value
+        case r.value >= last:
+            ec.total[key] += r.value - last
+        default:
+            ec.total[key] += r.value
+        }
+        ec.last[key] = r.value
+        r.value = ec.total[key]
+    }
+}
@@ -0,0 +1,26 @@
+package collector
+
+import "testing"
+
+// TestEnergyCountersPerTarget feeds the same sysfs path from the local hwmon source and two ssh
+// targets: each keeps its own counter, and a reset on one target does not bump the others
+func TestEnergyCountersPerTarget(t *testing.T) {
+    const path = "/sys/class/hwmon/hwmon2/energy1_input"
+    ec := newEnergyCounters()
+    scrape := func(local, pve2, pve3 float64) []reading {
+        rs := []reading{
+            {source: "hwmon", path: path, kind: kindEnergy, value: local},
+            {source: "ssh", target: "pve2", path: path, kind: kindEnergy, value: pve2},
+            {source: "ssh", target: "pve3", path: path, kind: kindEnergy, value: pve3},
+        }
+        ec.accumulate(rs)
+        return rs
+    }
+    scrape(100, 500, 10)
+    rs := scrape(110, 20, 15)
+    for i, want := range []float64{110, 520, 15} {
+        if rs[i].value != want {
+            t.Errorf("%s %s: total = %v, want %v", rs[i].source, rs[i].target, rs[i].value, want)
+        }
+    }
+}
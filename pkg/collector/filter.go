@@ -0,0 +1,108 @@
+package collector
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "regexp"
+)
+
+// readingFilter keeps or drops readings by chip and sensor name. An empty include list keeps
+// everything and an exclude match always wins over an include match.
+type readingFilter struct {
+    chipInclude   []*regexp.Regexp
+    chipExclude   []*regexp.Regexp
+    sensorInclude []*regexp.Regexp
+    sensorExclude []*regexp.Regexp
+}
+
+var filterLogged bool
+
+// newReadingFilter compiles the RE2 expressions given on the command line.
+func newReadingFilter(chipInclude, chipExclude, sensorInclude, sensorExclude []string) (*readingFilter, error) {
+    f := &readingFilter{}
+    for _, l := range []struct {
+        flag  string
+        exprs []string
+        dst   *[]*regexp.Regexp
+    }{
+        {"chip-include", chipInclude, &f.chipInclude},
+        {"chip-exclude", chipExclude, &f.chipExclude},
+        {"sensor-include", sensorInclude, &f.sensorInclude},
+        {"sensor-exclude", sensorExclude, &f.sensorExclude},
+    } {
+        for _, e := range l.exprs {
+            re, err := regexp.Compile(e)
+            if err != nil {
+                return nil, fmt.Errorf("-%s: %v", l.flag, err)
+            }
+            *l.dst = append(*l.dst, re)
+        }
+    }
+    return f, nil
+}
+
+func matchAny(res []*regexp.Regexp, values ...string) bool {
+    return firstMatch(res, values...) != nil
+}
+
+// firstMatch returns the first expression matching one of values, nil when none does.
+func firstMatch(res []*regexp.Regexp, values ...string) *regexp.Regexp {
+    for _, re := range res {
+        for _, v := range values {
+            if re.MatchString(v) {
+                return re
+            }
+        }
+    }
+    return nil
+}
+
+// drop returns why a reading is filtered out, or "" to keep it.
+// Sensor expressions are matched against both the sensor name and its label.
+func (f *readingFilter) drop(r reading) string {
+    reason, _ := f.why(r)
+    return reason
+}
+
+// why is drop with the excluding expression; an include miss has no single expression to blame.
+func (f *readingFilter) why(r reading) (reason, rule string) {
+    if re := firstMatch(f.chipExclude, r.chip); re != nil {
+        return "chip-exclude", re.String()
+    }
+    if re := firstMatch(f.sensorExclude, r.name, r.label); re != nil {
+        return "sensor-exclude", re.String()
+    }
+    switch {
+    case len(f.chipInclude) > 0 && !matchAny(f.chipInclude, r.chip):
+        return "chip-include", ""
+    case len(f.sensorInclude) > 0 && !matchAny(f.sensorInclude, r.name, r.label):
+        return "sensor-include", ""
+    }
+    return "", ""
+}
+
+// apply filters readings in place; logDropped logs every dropped reading at level to help tune
+// the expressions.
+func (f *readingFilter) apply(readings []reading, logDropped bool, level slog.Level) []reading {
+    kept := readings[:0]
+    for _, r := range readings {
+        if reason := f.drop(r); reason != "" {
+            if logDropped {
+                slog.Log(context.Background(), level, "filtered", "reason", reason, "source", r.source, "chip", r.chip, "sensor", r.name, "label", r.label)
+            }
+            continue
+        }
+        kept = append(kept, r)
+    }
+    return kept
+}
+
+// skipLevel is the level of the messages explaining why a sensor is not exported (blocklist,
+// filters): debug, raised to info by -log-filtered
+func skipLevel(logFiltered bool) slog.Level {
+    if logFiltered {
+        return slog.LevelInfo
+    }
+    return slog.LevelDebug
+}
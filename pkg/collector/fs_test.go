@@ -0,0 +1,58 @@
+package collector
+
+import (
+    "testing"
+    "testing/fstest"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// TestSysfsSourcesReadFS serves hwmon, intrusion, RAPL and thermal_throttle from an in-memory
+// tree: none of them may fall back to the host /sys
+func TestSysfsSourcesReadFS(t *testing.T) {
+    tree := fstest.MapFS{
+        "sys/class/hwmon/hwmon0/name":             {Data: []byte("nct6798\n")},
+        "sys/class/hwmon/hwmon0/temp1_input":      {Data: []byte("31000\n")},
+        "sys/class/hwmon/hwmon0/intrusion0_alarm": {Data: []byte("1\n")},
+        "sys/class/powercap/intel-rapl:0/name":      {Data: []byte("package-0\n")},
+        "sys/class/powercap/intel-rapl:0/energy_uj": {Data: []byte("5000000\n")},
+        "sys/devices/system/cpu/cpu0/thermal_throttle/core_throttle_count":    {Data: []byte("7\n")},
+        "sys/devices/system/cpu/cpu0/thermal_throttle/package_throttle_count": {Data: []byte("9\n")},
+        "sys/devices/system/cpu/cpu0/topology/physical_package_id":           {Data: []byte("0\n")},
+        "sys/devices/system/cpu/cpu0/topology/core_id":                       {Data: []byte("0\n")},
+    }
+    c, err := New(Options{
+        FS:             sources.FromFS(tree),
+        EnableHwmon:    true,
+        HwmonPaths:     []string{"/sys/class/hwmon"},
+        EnableRapl:     true,
+        RaplPath:       "/sys/class/powercap",
+        EnableThrottle: true,
+        CpuPath:        "/sys/devices/system/cpu",
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(c.Stop)
+    if v := onlyValue(t, c, "temperature_celsius"); v != 31 {
+        t.Errorf("temperature_celsius = %v, want 31", v)
+    }
+    if v := onlyValue(t, c, "intrusion_alarm"); v != 1 {
+        t.Errorf("intrusion_alarm = %v, want 1", v)
+    }
+    if v := onlyValue(t, c, "rapl_energy_joules_total"); v != 5 {
+        t.Errorf("rapl_energy_joules_total = %v, want 5", v)
+    }
+    want := map[string]float64{"core": 7, "package": 9}
+    metrics := gather(t, c, "cpu_throttle_events_total")
+    if len(metrics) != len(want) {
+        t.Fatalf("cpu_throttle_events_total has %d series, want %d", len(metrics), len(want))
+    }
+    for _, m := range metrics {
+        for _, l := range m.GetLabel() {
+            if l.GetName() == "scope" && m.GetCounter().GetValue() != want[l.GetValue()] {
+                t.Errorf("cpu_throttle_events_total{scope=%q} = %v, want %v", l.GetValue(), m.GetCounter().GetValue(), want[l.GetValue()])
+            }
+        }
+    }
+}
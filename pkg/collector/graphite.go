@@ -0,0 +1,169 @@
+package collector
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "log/slog"
+    "net"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// graphiteMaxBackoff bounds the wait between two connection attempts to a down server
+const graphiteMaxBackoff = 5 * time.Minute
+
+// GraphiteConfig holds the -graphite-* flags
+type GraphiteConfig struct {
+    Address  string
+    Protocol string // tcp or udp
+    Prefix   string
+    Host     string
+    Interval time.Duration
+    Timeout  time.Duration
+    DryRun   bool
+}
+
+// GraphiteWriter writes the temperatures in Graphite's plaintext protocol on its own timer.
+// The connection is kept between intervals and re-established with exponential backoff.
+type GraphiteWriter struct {
+    GraphiteConfig
+    c        *Collector
+    conn     net.Conn
+    backoff  time.Duration
+    nextDial time.Time
+    failed   prometheus.Counter
+}
+
+// NewGraphiteWriter sends the temperatures of c to the server of cfg
+func NewGraphiteWriter(cfg GraphiteConfig, c *Collector) *GraphiteWriter {
+    return &GraphiteWriter{
+        GraphiteConfig: cfg,
+        c:              c,
+        failed: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: c.Namespace,
+            Name:      "graphite_failed_writes_total",
+            Help:      "Nombre d'envois vers Graphite en échec (connexion ou écriture).",
+        }),
+    }
+}
+
+// Collectors returns the failure counter of the writer
+func (gw *GraphiteWriter) Collectors() []prometheus.Collector {
+    return []prometheus.Collector{gw.failed}
+}
+
+// Run writes every interval until ctx is cancelled
+func (gw *GraphiteWriter) Run(ctx context.Context) {
+    t := time.NewTicker(gw.Interval)
+    defer t.Stop()
+    defer func() {
+        if gw.conn != nil {
+            gw.conn.Close()
+        }
+    }()
+    for {
+        if lines := graphiteLines(gw.c.current(), gw.Prefix, gw.Host, time.Now()); len(lines) > 0 {
+            if gw.DryRun {
+                _, _ = io.WriteString(os.Stdout, strings.Join(lines, ""))
+            } else if err := gw.write(ctx, lines); err != nil {
+                gw.failed.Inc()
+                slog.Warn("graphite", "err", err)
+            }
+        }
+        select {
+        case <-t.C:
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// write sends the lines over the open connection, dialing first when needed. Any error drops
+// the connection and delays the next attempt.
+func (gw *GraphiteWriter) write(ctx context.Context, lines []string) error {
+    if gw.conn == nil {
+        if time.Now().Before(gw.nextDial) {
+            return fmt.Errorf("%s injoignable, nouvelle tentative après %s", gw.Address, gw.nextDial.Format(time.TimeOnly))
+        }
+        d := net.Dialer{Timeout: gw.Timeout}
+        conn, err := d.DialContext(ctx, gw.Protocol, gw.Address)
+        if err != nil {
+            gw.delay()
+            return err
+        }
+        gw.conn = conn
+    }
+    if err := gw.conn.SetWriteDeadline(time.Now().Add(gw.Timeout)); err != nil {
+        return gw.fail(err)
+    }
+    // with UDP every line is its own datagram so none gets truncated
+    if gw.Protocol == "udp" {
+        for _, l := range lines {
+            if _, err := io.WriteString(gw.conn, l); err != nil {
+                return gw.fail(err)
+            }
+        }
+    } else if _, err := io.WriteString(gw.conn, strings.Join(lines, "")); err != nil {
+        return gw.fail(err)
+    }
+    gw.backoff = 0
+    return nil
+}
+
+func (gw *GraphiteWriter) fail(err error) error {
+    gw.conn.Close()
+    gw.conn = nil
+    gw.delay()
+    return err
+}
+
+// delay doubles the wait before the next dial, starting at one second
+func (gw *GraphiteWriter) delay() {
+    switch {
+    case gw.backoff == 0:
+        gw.backoff = time.Second
+    case gw.backoff < graphiteMaxBackoff:
+        gw.backoff = min(2*gw.backoff, graphiteMaxBackoff)
+    }
+    gw.nextDial = time.Now().Add(gw.backoff)
+}
+
+// graphiteLines formats the temperatures as "prefix.host.chip.sensor.label value timestamp\n".
+// Empty path components are left out.
+func graphiteLines(readings []reading, prefix, host string, now time.Time) []string {
+    ts := strconv.FormatInt(now.Unix(), 10)
+    var lines []string
+    for _, r := range readings {
+        if r.kind != kindTemperature {
+            continue
+        }
+        var path []string
+        if prefix != "" {
+            path = append(path, prefix)
+        }
+        for _, p := range []string{host, r.chip, r.name, r.label} {
+            if p = graphiteSanitize(p); p != "" {
+                path = append(path, p)
+            }
+        }
+        lines = append(lines, strings.Join(path, ".")+" "+strconv.FormatFloat(r.value, 'f', -1, 64)+" "+ts+"\n")
+    }
+    return lines
+}
+
+// graphiteSanitize replaces what would split or break a path component (dots, spaces, slashes...)
+// with underscores.
+func graphiteSanitize(s string) string {
+    return strings.Map(func(r rune) rune {
+        switch {
+        case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+            return r
+        }
+        return '_'
+    }, s)
+}
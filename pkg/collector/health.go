@@ -0,0 +1,98 @@
+package collector
+
+import (
+    "encoding/json"
+    "log/slog"
+    "net/http"
+    "time"
+)
+
+// healthSource is the outcome of one source in the /healthz body
+type healthSource struct {
+    Source     string `json:"source"`
+    Success    bool   `json:"success"`
+    Discovered int    `json:"discovered"`
+}
+
+// healthStatus is the /healthz body
+type healthStatus struct {
+    Status         string         `json:"status"` // ok or unhealthy
+    Reason         string         `json:"reason,omitempty"`
+    LastCollection time.Time      `json:"last_collection"`
+    Readings       int            `json:"readings"`
+    Sources        []healthSource `json:"sources"`
+    Failing        []string       `json:"failing,omitempty"`
+}
+
+// HealthHandler answers 503 when the last full collection, scrape or background, had every
+// enabled source fail or, with requireReadings, exported nothing. Before the first scrape it
+// collects once itself. /livez keeps the old process-up behavior.
+func (c *Collector) HealthHandler(requireReadings bool) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        last := c.recent.Load()
+        if last == nil {
+            c.inflight.Add(1)
+            c.latest(c.ctx, time.Now())
+            c.inflight.Done()
+            last = c.recent.Load()
+        }
+        st := healthStatus{Status: "ok", Sources: []healthSource{}}
+        if last != nil {
+            st.LastCollection = last.at
+            st.Readings = len(last.readings)
+            for _, s := range last.stats {
+                st.Sources = append(st.Sources, healthSource{Source: s.source, Success: s.success, Discovered: s.discovered})
+            }
+            st.Failing = failedSources(last.stats)
+        }
+        switch {
+        case len(st.Sources) > 0 && len(st.Failing) == len(st.Sources):
+            st.Status, st.Reason = "unhealthy", "toutes les sources activées sont en erreur"
+        case requireReadings && st.Readings == 0:
+            st.Status, st.Reason = "unhealthy", "la dernière collecte n'a produit aucune lecture"
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("Cache-Control", "no-store")
+        if st.Status != "ok" {
+            w.WriteHeader(http.StatusServiceUnavailable)
+        }
+        _ = json.NewEncoder(w).Encode(st)
+    })
+}
+
+// record keeps a full collection for /healthz and updates the readiness; c.mu must be held
+func (c *Collector) record(s *snapshot) {
+    c.recent.Store(s)
+    c.observe(s.stats)
+}
+
+// observe updates the readiness after a full collection. The exporter becomes ready once a
+// collection finds a sensor, and with -ready-failure-threshold turns unready again after that
+// many collections in a row failed (every source in error or no sensor found). c.mu must be held.
+func (c *Collector) observe(stats []sourceStats) {
+    _, discovered := discoverySummary(stats)
+    if discovered > 0 && len(failedSources(stats)) < len(stats) {
+        c.readyFailures = 0
+        if !c.ready.Swap(true) {
+            slog.Info("exporteur prêt (/readyz)", "sensors", discovered)
+        }
+        return
+    }
+    c.readyFailures++
+    if c.ReadyThreshold > 0 && c.readyFailures >= c.ReadyThreshold && c.ready.Swap(false) {
+        slog.Warn("exporteur plus prêt (/readyz): collectes en échec", "consecutive", c.readyFailures)
+    }
+}
+
+// ReadyHandler answers 200 once ready, 503 before the first sensor was found or after
+// -ready-failure-threshold failed collections
+func (c *Collector) ReadyHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+        w.Header().Set("Cache-Control", "no-store")
+        if !c.ready.Load() {
+            http.Error(w, "not ready", http.StatusServiceUnavailable)
+            return
+        }
+        _, _ = w.Write([]byte("ready"))
+    })
+}
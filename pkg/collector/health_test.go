@@ -0,0 +1,89 @@
+package collector
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// readyCode returns the status /readyz answers
+func readyCode(c *Collector) int {
+    rec := httptest.NewRecorder()
+    c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+    return rec.Code
+}
+
+// TestReadinessConcurrentCollections refreshes the snapshot, scrapes, runs discovery and asks
+// /readyz all at once under -race, then checks the failure threshold still counts right
+func TestReadinessConcurrentCollections(t *testing.T) {
+    hwmon := writeTree(t, fakeHwmonFiles)
+    c, err := New(Options{
+        EnableHwmon:     true,
+        HwmonPaths:      []string{hwmon},
+        CollectInterval: time.Hour, // the loop never ticks, refreshes are driven by the test
+        ReadyThreshold:  3,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(c.Stop)
+    if code := readyCode(c); code != http.StatusServiceUnavailable {
+        t.Fatalf("/readyz before the first collection = %d, want 503", code)
+    }
+    c.Start()
+    if code := readyCode(c); code != http.StatusOK {
+        t.Fatalf("/readyz after the first collection = %d, want 200", code)
+    }
+
+    reg := prometheus.NewRegistry()
+    if err := reg.Register(c); err != nil {
+        t.Fatal(err)
+    }
+    var wg sync.WaitGroup
+    run := func(n int, f func()) {
+        for g := 0; g < n; g++ {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                for i := 0; i < 10; i++ {
+                    f()
+                }
+            }()
+        }
+    }
+    run(4, c.refresh)
+    run(2, func() { c.Discover() })
+    run(4, func() {
+        if _, err := reg.Gather(); err != nil {
+            t.Error(err)
+        }
+    })
+    run(4, func() {
+        if code := readyCode(c); code != http.StatusOK {
+            t.Errorf("/readyz during healthy collections = %d, want 200", code)
+        }
+    })
+    wg.Wait()
+
+    // the sensors go away: ready until the third failed collection in a row
+    if err := os.RemoveAll(hwmon); err != nil {
+        t.Fatal(err)
+    }
+    for i := 1; i <= 3; i++ {
+        c.refresh()
+        if want := i < 3; c.Ready() != want {
+            t.Fatalf("Ready() after %d failed collections = %v, want %v", i, c.Ready(), want)
+        }
+    }
+    // and one good collection is enough to be ready again
+    writeTreeAt(t, hwmon, fakeHwmonFiles)
+    c.refresh()
+    if !c.Ready() {
+        t.Error("Ready() = false after the sensors came back")
+    }
+}
@@ -0,0 +1,20 @@
+package collector
+
+import (
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// newTemperatureHistogram records every temperature of every collection, labeled by source only,
+// so long-term storage can keep the distribution without the per-sensor series. Scrapers that
+// negotiate protobuf also get native buckets.
+func newTemperatureHistogram(namespace string, buckets []float64) *prometheus.HistogramVec {
+    return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace:                      namespace,
+        Name:                           "temperature_celsius_histogram",
+        Help:                           "Distribution des températures lues à chaque collecte, par source (-histogram).",
+        Buckets:                        buckets,
+        NativeHistogramBucketFactor:    1.1,
+        NativeHistogramMaxBucketNumber: 100,
+    }, []string{"source"})
+}
@@ -0,0 +1,39 @@
+package collector
+
+import (
+    "testing"
+    "time"
+)
+
+// TestHistogramObservesEachGatherOnce scrapes one snapshot several times: the histogram must
+// count the temperatures of each collection once, whatever the scrape rate
+func TestHistogramObservesEachGatherOnce(t *testing.T) {
+    c, err := New(Options{
+        EnableHwmon:      true,
+        HwmonPaths:       []string{writeTree(t, fakeHwmonFiles)},
+        HistogramBuckets: []float64{30, 50, 70},
+        CollectInterval:  time.Hour,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(c.Stop)
+    c.Start()
+    count := func() uint64 {
+        t.Helper()
+        var n uint64
+        for _, m := range gather(t, c, "temperature_celsius_histogram") {
+            n += m.GetHistogram().GetSampleCount()
+        }
+        return n
+    }
+    for scrape := 1; scrape <= 3; scrape++ {
+        if n := count(); n != 5 {
+            t.Errorf("scrape %d: %d observations, want the 5 temperatures of the one collection", scrape, n)
+        }
+    }
+    c.refresh()
+    if n := count(); n != 10 {
+        t.Errorf("%d observations after a second collection, want 10", n)
+    }
+}
@@ -0,0 +1,86 @@
+package collector
+
+import (
+    "encoding/json"
+    "strings"
+)
+
+// haConfig is the Home Assistant MQTT discovery payload of one temperature sensor
+type haConfig struct {
+    Name              string   `json:"name"`
+    UniqueID          string   `json:"unique_id"`
+    StateTopic        string   `json:"state_topic"`
+    ValueTemplate     string   `json:"value_template"`
+    DeviceClass       string   `json:"device_class"`
+    StateClass        string   `json:"state_class"`
+    UnitOfMeasurement string   `json:"unit_of_measurement"`
+    ExpireAfter       int      `json:"expire_after"`
+    Device            haDevice `json:"device"`
+}
+
+// haDevice groups every sensor of a node under one Home Assistant device
+type haDevice struct {
+    Identifiers []string `json:"identifiers"`
+    Name        string   `json:"name"`
+    Model       string   `json:"model"`
+    SWVersion   string   `json:"sw_version"`
+}
+
+// discovery returns the config messages of sensors not announced yet, followed by empty retained
+// messages clearing the sensors that disappeared, and the set of announced sensors once they are
+// published. A collection without any temperature clears nothing: it is more likely a failed
+// source than every sensor being removed.
+func (mw *MQTTWriter) discovery(states []mqttMessage) ([]mqttMessage, map[string]string) {
+    announced := make(map[string]string, len(states))
+    var msgs []mqttMessage
+    for _, m := range states {
+        id := haUniqueID(mw.Host, m.chip, m.label)
+        if _, dup := announced[id]; dup {
+            continue
+        }
+        topic := mw.HAPrefix + "/sensor/" + id + "/config"
+        announced[id] = topic
+        if _, ok := mw.announced[id]; ok {
+            continue
+        }
+        payload, _ := json.Marshal(haConfig{
+            Name:              strings.TrimSpace(m.chip + " " + m.label),
+            UniqueID:          id,
+            StateTopic:        m.topic,
+            ValueTemplate:     "{{ value_json.value }}",
+            DeviceClass:       "temperature",
+            StateClass:        "measurement",
+            UnitOfMeasurement: "°C",
+            // the sensor shows as unavailable once the exporter misses a few publications
+            ExpireAfter: int(3 * mw.Interval.Seconds()),
+            Device: haDevice{
+                Identifiers: []string{"temperature-exporter-" + mw.Host},
+                Name:        mw.Host,
+                Model:       "temperature-exporter",
+                SWVersion:   Version,
+            },
+        })
+        msgs = append(msgs, mqttMessage{topic: topic, payload: payload})
+    }
+    if len(states) == 0 {
+        return msgs, nil
+    }
+    for id, topic := range mw.announced {
+        if _, ok := announced[id]; !ok {
+            msgs = append(msgs, mqttMessage{topic: topic})
+        }
+    }
+    return msgs, announced
+}
+
+// haUniqueID derives a stable identifier from host, chip and label, limited to the characters
+// Home Assistant accepts in a discovery topic.
+func haUniqueID(host, chip, label string) string {
+    var parts []string
+    for _, p := range []string{"temperature_exporter", host, chip, label} {
+        if p = graphiteSanitize(strings.ToLower(p)); p != "" {
+            parts = append(parts, p)
+        }
+    }
+    return strings.Join(parts, "_")
+}
@@ -0,0 +1,160 @@
+package collector
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "log/slog"
+    "net"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// influxMaxDatagram keeps UDP packets under a 1500 bytes MTU, like Proxmox's own InfluxDB plugin
+const influxMaxDatagram = 1400
+
+// InfluxConfig holds the -influxdb-* flags. Either URL (HTTP v2 API) or UDPAddr is set.
+type InfluxConfig struct {
+    URL      string
+    Token    string
+    Org      string
+    Bucket   string
+    UDPAddr  string
+    Host     string
+    Interval time.Duration
+    Timeout  time.Duration
+}
+
+// InfluxWriter sends the temperatures to InfluxDB in line protocol on its own timer, so a
+// slow or unreachable server never delays a Prometheus scrape.
+type InfluxWriter struct {
+    InfluxConfig
+    c      *Collector
+    client *http.Client
+    failed prometheus.Counter
+}
+
+// NewInfluxWriter sends the temperatures of c to the database of cfg
+func NewInfluxWriter(cfg InfluxConfig, c *Collector) *InfluxWriter {
+    return &InfluxWriter{
+        InfluxConfig: cfg,
+        c:            c,
+        client:       &http.Client{Timeout: cfg.Timeout},
+        failed: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: c.Namespace,
+            Name:      "influxdb_failed_writes_total",
+            Help:      "Nombre d'envois vers InfluxDB en échec.",
+        }),
+    }
+}
+
+// Collectors returns the failure counter of the writer
+func (iw *InfluxWriter) Collectors() []prometheus.Collector {
+    return []prometheus.Collector{iw.failed}
+}
+
+// Run writes every interval until ctx is cancelled
+func (iw *InfluxWriter) Run(ctx context.Context) {
+    t := time.NewTicker(iw.Interval)
+    defer t.Stop()
+    for {
+        lines := influxLines(iw.c.current(), iw.Host, time.Now())
+        if len(lines) > 0 {
+            if err := iw.write(ctx, lines); err != nil {
+                iw.failed.Inc()
+                slog.Warn("influxdb", "err", err)
+            }
+        }
+        select {
+        case <-t.C:
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// write sends every line of a collection in a single request, or in as few datagrams as fit
+func (iw *InfluxWriter) write(ctx context.Context, lines []string) error {
+    if iw.UDPAddr != "" {
+        return iw.writeUDP(ctx, lines)
+    }
+    q := url.Values{"org": {iw.Org}, "bucket": {iw.Bucket}, "precision": {"ns"}}
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(iw.URL, "/")+"/api/v2/write?"+q.Encode(), strings.NewReader(strings.Join(lines, "\n")+"\n"))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+    if iw.Token != "" {
+        req.Header.Set("Authorization", "Token "+iw.Token)
+    }
+    resp, err := iw.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode/100 != 2 {
+        msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+        return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+    }
+    return nil
+}
+
+func (iw *InfluxWriter) writeUDP(ctx context.Context, lines []string) error {
+    d := net.Dialer{Timeout: iw.Timeout}
+    conn, err := d.DialContext(ctx, "udp", iw.UDPAddr)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+    if err := conn.SetWriteDeadline(time.Now().Add(iw.Timeout)); err != nil {
+        return err
+    }
+    var packet []byte
+    for _, l := range lines {
+        if len(packet) > 0 && len(packet)+len(l)+1 > influxMaxDatagram {
+            if _, err := conn.Write(packet); err != nil {
+                return err
+            }
+            packet = packet[:0]
+        }
+        packet = append(packet, l...)
+        packet = append(packet, '\n')
+    }
+    _, err = conn.Write(packet)
+    return err
+}
+
+// influxLines formats the temperatures as `temperature,chip=..,sensor=..,label=..,host=.. value=..`.
+// Empty tags are left out since line protocol does not allow empty tag values.
+func influxLines(readings []reading, host string, now time.Time) []string {
+    ts := strconv.FormatInt(now.UnixNano(), 10)
+    var lines []string
+    for _, r := range readings {
+        if r.kind != kindTemperature {
+            continue
+        }
+        var b strings.Builder
+        b.WriteString("temperature")
+        for _, tag := range [][2]string{{"ccd", r.ccd}, {"chip", r.chip}, {"core", r.core}, {"device", r.device}, {"host", host}, {"label", r.label}, {"package", r.pkg}, {"sensor", r.name}, {"target", r.target}} {
+            if tag[1] == "" {
+                continue
+            }
+            b.WriteString("," + tag[0] + "=" + influxEscape(tag[1]))
+        }
+        b.WriteString(" value=" + strconv.FormatFloat(r.value, 'f', -1, 64) + " " + ts)
+        lines = append(lines, b.String())
+    }
+    return lines
+}
+
+var influxTagEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+
+func influxEscape(s string) string {
+    return influxTagEscaper.Replace(s)
+}
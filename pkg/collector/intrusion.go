@@ -0,0 +1,107 @@
+package collector
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// intrusionFile matches the chassis intrusion attributes of Super I/O chips (nct6775, it87...)
+var intrusionFile = regexp.MustCompile(`^intrusion\d+_alarm$`)
+
+// intrusionAlarm is one intrusionN_alarm attribute. The kernel latches it to 1 when the case
+// is opened and keeps it there until 0 is written back.
+type intrusionAlarm struct {
+    chip   string
+    sensor string // intrusion0, intrusion1...
+    path   string
+    value  float64
+}
+
+// discoverIntrusions reads every intrusionN_alarm of the hwmon chips in fsys (the host when nil).
+// Unreadable attributes are skipped: most boards do not wire the intrusion header at all.
+// Reading stops when ctx ends.
+func discoverIntrusions(ctx context.Context, fsys sources.FS, basePaths []string) []intrusionAlarm {
+    if fsys == nil {
+        fsys = sources.OS
+    }
+    var alarms []intrusionAlarm
+    for _, basePath := range basePaths {
+        entries, err := fsys.ReadDir(basePath)
+        if err != nil {
+            continue
+        }
+        visited := make(map[string]bool)
+        for _, e := range entries {
+            if ctx.Err() != nil {
+                return alarms
+            }
+            chipDir := filepath.Join(basePath, e.Name())
+            if !sources.VisitDir(fsys, chipDir, visited) {
+                continue
+            }
+            files, err := fsys.ReadDir(chipDir)
+            if err != nil {
+                continue
+            }
+            chip := e.Name()
+            if n, err := sources.ReadFirstLine(fsys, filepath.Join(chipDir, "name")); err == nil && n != "" {
+                chip = n
+            }
+            for _, f := range files {
+                if !intrusionFile.MatchString(f.Name()) {
+                    continue
+                }
+                path := filepath.Join(chipDir, f.Name())
+                v, err := sources.ReadValue(fsys, path)
+                if err != nil {
+                    continue
+                }
+                alarms = append(alarms, intrusionAlarm{chip: chip, sensor: strings.TrimSuffix(f.Name(), "_alarm"), path: path, value: v})
+            }
+        }
+    }
+    return alarms
+}
+
+// ClearIntrusionHandler writes 0 to the intrusion alarms on POST /-/clear-intrusion, all of them
+// or only those of ?chip= and ?sensor=. It needs write access to sysfs, hence its own flag.
+func (c *Collector) ClearIntrusionHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            w.Header().Set("Allow", "POST")
+            http.Error(w, "méthode non autorisée", http.StatusMethodNotAllowed)
+            return
+        }
+        chip, sensor := r.URL.Query().Get("chip"), r.URL.Query().Get("sensor")
+        var failed []string
+        cleared := 0
+        for _, a := range discoverIntrusions(r.Context(), c.FS, c.HwmonPaths) {
+            if (chip != "" && a.chip != chip) || (sensor != "" && a.sensor != sensor) {
+                continue
+            }
+            if err := os.WriteFile(a.path, []byte("0"), 0); err != nil {
+                slog.Warn("alarme d'intrusion: effacement impossible", "chip", a.chip, "sensor", a.sensor, "err", err)
+                failed = append(failed, fmt.Sprintf("%s/%s: %v", a.chip, a.sensor, err))
+                continue
+            }
+            slog.Info("alarme d'intrusion effacée", "chip", a.chip, "sensor", a.sensor, "was", a.value)
+            cleared++
+        }
+        switch {
+        case len(failed) > 0:
+            http.Error(w, strings.Join(failed, "\n"), http.StatusInternalServerError)
+        case cleared == 0:
+            http.Error(w, "aucune alarme d'intrusion correspondante", http.StatusNotFound)
+        default:
+            w.WriteHeader(http.StatusNoContent)
+        }
+    })
+}
@@ -0,0 +1,180 @@
+package collector
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+    "text/tabwriter"
+)
+
+// sensorInfo is one entry of the /sensors listing. Every discovered value appears, including
+// those the blocklist, the validity bounds or the filters keep out of /metrics.
+type sensorInfo struct {
+    Source    string         `json:"source"`
+    Chip      string         `json:"chip"`
+    Sensor    string         `json:"sensor"`
+    Label     string         `json:"label"`
+    Kind      string         `json:"kind"`
+    Path      string         `json:"path,omitempty"`   // sysfs file
+    Driver    string         `json:"driver,omitempty"` // kernel driver of the sysfs device
+    Device    string         `json:"device,omitempty"` // PCI address, USB path... of the sysfs device
+    Origin    string         `json:"origin,omitempty"` // command or daemon of the other sources
+    Value     float64        `json:"value"`            // after factor and calibration
+    Factor    float64        `json:"factor,omitempty"`
+    Exported  bool           `json:"exported"`
+    DroppedBy string         `json:"dropped_by,omitempty"` // hwmon-chips, blocklist, below_min, chip-exclude...
+    Rule      string         `json:"rule,omitempty"`       // expression responsible for the drop
+    RenamedTo *renamedSensor `json:"renamed_to,omitempty"`
+}
+
+type renamedSensor struct {
+    Chip   string `json:"chip"`
+    Sensor string `json:"sensor"`
+    Label  string `json:"label"`
+}
+
+// inventory runs a fresh gather with the blocklist disabled, bypassing the hwmon discovery
+// cache, and explains for each value what the pipeline does with it. IPMI and storcli stay
+// served from their cache: they are too slow to run on every request.
+func (c *Collector) inventory() []sensorInfo {
+    c.inflight.Add(1)
+    defer c.inflight.Done()
+    rules := c.rules.Load()
+    unblocked := *rules
+    unblocked.blocklist = &blocklist{suppressed: map[string]string{}}
+    unblocked.chips = nil
+    c.hwmon.Cache.Invalidate()
+    c.mu.Lock()
+    readings, _ := c.gather(c.ctx, &unblocked, nil)
+    c.mu.Unlock()
+
+    list := make([]sensorInfo, 0, len(readings))
+    for _, raw := range readings {
+        r := calibrate([]reading{raw}, rules.calibration)[0]
+        info := sensorInfo{
+            Source: r.source,
+            Chip:   r.chip,
+            Sensor: r.name,
+            Label:  r.label,
+            Kind:   r.kind.String(),
+            Path:   r.path,
+            Driver: r.driver,
+            Device: r.device,
+            Value:  r.value,
+            Factor: r.factor,
+        }
+        if r.path == "" {
+            info.Origin = c.sourceOrigin(r.source)
+        }
+        if flag := rules.chips.excludedBy(r.source, r.chip, r.name, r.label); flag != "" {
+            info.DroppedBy = flag
+        } else if rule := rules.blocklist.match(r.source, r.chip, r.name, r.label); rule != "" {
+            info.DroppedBy, info.Rule = "blocklist", rule
+        } else if isSentinel(raw) {
+            info.DroppedBy = "sentinel"
+        } else if reason := c.invalidReason(r); reason != "" {
+            info.DroppedBy = reason
+        } else if reason, rule := c.filter.why(r); reason != "" {
+            info.DroppedBy, info.Rule = reason, rule
+        } else {
+            info.Exported = true
+            renamed := rename([]reading{r}, rules.renameRules)[0]
+            if renamed.chip != r.chip || renamed.name != r.name || renamed.label != r.label {
+                info.RenamedTo = &renamedSensor{Chip: renamed.chip, Sensor: renamed.name, Label: renamed.label}
+            }
+        }
+        list = append(list, info)
+    }
+    return list
+}
+
+// sourceOrigin names what a command or network based source queries
+func (c *Collector) sourceOrigin(source string) string {
+    switch source {
+    case "sensors-cli":
+        return c.SensorsCliPath
+    case "ipmi":
+        return c.ipmiBin()
+    case "storcli":
+        return c.StorcliPath
+    case "nvidia":
+        return c.NvidiaSmiPath
+    case "vcgencmd":
+        return c.VcgencmdPath
+    case "apcupsd":
+        return c.ApcupsdAddress
+    case "nut":
+        return strings.Join(c.NutUPS, ",")
+    case "liquidctl":
+        return c.LiquidctlPath
+    }
+    return ""
+}
+
+// SensorsHandler serves the inventory as JSON. Deduplication between sources is not applied,
+// so a value read by both hwmon and sensors -j is listed twice.
+func (c *Collector) SensorsHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet && r.Method != http.MethodHead {
+            w.Header().Set("Allow", "GET, HEAD")
+            http.Error(w, "méthode non autorisée", http.StatusMethodNotAllowed)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("Cache-Control", "no-store")
+        enc := json.NewEncoder(w)
+        enc.SetIndent("", "  ")
+        _ = enc.Encode(c.inventory())
+    })
+}
+
+// printInventory writes the inventory for -list-sensors as an aligned table, JSON or CSV.
+func printInventory(w io.Writer, list []sensorInfo, format string) error {
+    switch format {
+    case "json":
+        enc := json.NewEncoder(w)
+        enc.SetIndent("", "  ")
+        return enc.Encode(list)
+    case "csv":
+        cw := csv.NewWriter(w)
+        _ = cw.Write([]string{"source", "chip", "sensor", "label", "kind", "value", "path", "status"})
+        for _, s := range list {
+            _ = cw.Write([]string{s.Source, s.Chip, s.Sensor, s.Label, s.Kind, strconv.FormatFloat(s.Value, 'f', -1, 64), s.location(), s.status()})
+        }
+        cw.Flush()
+        return cw.Error()
+    case "table":
+        tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+        fmt.Fprintln(tw, "SOURCE\tCHIP\tSENSOR\tLABEL\tKIND\tVALUE\tPATH\tSTATUS")
+        for _, s := range list {
+            fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%g\t%s\t%s\n", s.Source, s.Chip, s.Sensor, s.Label, s.Kind, s.Value, s.location(), s.status())
+        }
+        return tw.Flush()
+    }
+    return fmt.Errorf("format %q inconnu (attendu: table, json ou csv)", format)
+}
+
+// location is the sysfs path of a sensor, or the command it comes from
+func (s sensorInfo) location() string {
+    if s.Path != "" {
+        return s.Path
+    }
+    return s.Origin
+}
+
+// status summarizes the fate of a sensor in one word, with the responsible rule when known
+func (s sensorInfo) status() string {
+    switch {
+    case s.Exported && s.RenamedTo != nil:
+        return "exported as " + s.RenamedTo.Chip + "/" + s.RenamedTo.Sensor + "/" + s.RenamedTo.Label
+    case s.Exported:
+        return "exported"
+    case s.Rule != "":
+        return s.DroppedBy + " (" + s.Rule + ")"
+    }
+    return s.DroppedBy
+}
@@ -0,0 +1,166 @@
+package collector
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/csv"
+    "fmt"
+    "log/slog"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+var ipmiWarned bool
+
+// ipmiBin returns the command used by the selected backend
+func (c *Collector) ipmiBin() string {
+    if c.IPMIBackend == "freeipmi" {
+        return c.IPMISensorsPath
+    }
+    return c.IPMIPath
+}
+
+// checkIPMI validates the backend at startup, prepares the SDR cache and warns once when the binary is missing.
+func (c *Collector) checkIPMI() error {
+    switch c.IPMIBackend {
+    case "ipmitool":
+    case "freeipmi":
+        if err := os.MkdirAll(c.IPMISDRCacheDir, 0o700); err != nil {
+            slog.Warn("IPMI: impossible de créer le cache SDR (ipmi-sensors utilisera son cache par défaut)", "dir", c.IPMISDRCacheDir, "err", err)
+        }
+    default:
+        return fmt.Errorf("backend %q inconnu (attendu: ipmitool ou freeipmi)", c.IPMIBackend)
+    }
+    ipmiWarned = missingBinary(c.ipmiBin(), "-enable-ipmi")
+    return nil
+}
+
+// discoverIPMI dispatches to the configured backend; both export the same chip="ipmi" series.
+func (c *Collector) discoverIPMI(ctx context.Context) ([]reading, error) {
+    if c.IPMIBackend == "freeipmi" {
+        return discoverFreeIPMI(ctx, c.IPMISensorsPath, c.IPMISDRCacheDir, c.IPMITimeout)
+    }
+    return discoverIPMItool(ctx, c.IPMIPath, c.IPMITimeout)
+}
+
+// discoverIPMItool runs `ipmitool sensor` and keeps the temperature rows with their critical thresholds.
+func discoverIPMItool(ctx context.Context, bin string, timeout time.Duration) ([]reading, error) {
+    out, err := sources.RunCommand(ctx, timeout, bin, "sensor")
+    if err != nil {
+        return nil, err
+    }
+    return parseIPMItoolSensor(out), nil
+}
+
+// parseIPMItoolSensor parses the pipe separated table printed by `ipmitool sensor`:
+// name | value | unit | status | lnr | lcr | lnc | unc | ucr | unr
+func parseIPMItoolSensor(out []byte) []reading {
+    var res []reading
+    s := bufio.NewScanner(bytes.NewReader(out))
+    for s.Scan() {
+        fields := strings.Split(s.Text(), "|")
+        if len(fields) < 4 {
+            continue
+        }
+        for i := range fields {
+            fields[i] = strings.TrimSpace(fields[i])
+        }
+        name, raw, unit, status := fields[0], fields[1], fields[2], fields[3]
+        toCelsius, ok := ipmiTemperatureUnit(unit)
+        if !ok || ipmiUnavailable(raw) || ipmiUnavailable(status) {
+            continue
+        }
+        v, err := strconv.ParseFloat(raw, 64)
+        if err != nil {
+            continue
+        }
+        res = append(res, reading{chip: "ipmi", name: name, value: toCelsius(v)})
+        // thresholds are optional, "na" simply fails to parse
+        if len(fields) >= 9 {
+            if t, err := strconv.ParseFloat(fields[5], 64); err == nil {
+                res = append(res, reading{chip: "ipmi", name: name, value: toCelsius(t), kind: kindLowCrit})
+            }
+            if t, err := strconv.ParseFloat(fields[8], 64); err == nil {
+                res = append(res, reading{chip: "ipmi", name: name, value: toCelsius(t), kind: kindCrit})
+            }
+        }
+    }
+    return res
+}
+
+// discoverFreeIPMI runs FreeIPMI's ipmi-sensors for temperature sensors, reusing an on-disk SDR cache.
+func discoverFreeIPMI(ctx context.Context, bin, sdrCacheDir string, timeout time.Duration) ([]reading, error) {
+    args := []string{"--comma-separated-output", "--no-header-output", "--output-sensor-thresholds", "-t", "Temperature"}
+    if sdrCacheDir != "" {
+        args = append(args, "--sdr-cache-directory="+sdrCacheDir)
+    }
+    out, err := sources.RunCommand(ctx, timeout, bin, args...)
+    if err != nil {
+        return nil, err
+    }
+    return parseFreeIPMISensors(out)
+}
+
+// parseFreeIPMISensors parses ipmi-sensors CSV output with thresholds:
+// ID,Name,Type,Reading,Units,Lower NR,Lower C,Lower NC,Upper NC,Upper C,Upper NR,Event
+func parseFreeIPMISensors(out []byte) ([]reading, error) {
+    r := csv.NewReader(bytes.NewReader(out))
+    r.FieldsPerRecord = -1
+    r.LazyQuotes = true
+    records, err := r.ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    var res []reading
+    for _, f := range records {
+        if len(f) < 5 {
+            continue
+        }
+        name, raw, unit := strings.TrimSpace(f[1]), strings.TrimSpace(f[3]), strings.TrimSpace(f[4])
+        toCelsius, ok := ipmiTemperatureUnit("degrees " + unit)
+        if !ok || ipmiUnavailable(raw) {
+            continue
+        }
+        v, err := strconv.ParseFloat(raw, 64)
+        if err != nil {
+            continue
+        }
+        res = append(res, reading{chip: "ipmi", name: name, value: toCelsius(v)})
+        if len(f) >= 11 {
+            if t, err := strconv.ParseFloat(strings.TrimSpace(f[6]), 64); err == nil {
+                res = append(res, reading{chip: "ipmi", name: name, value: toCelsius(t), kind: kindLowCrit})
+            }
+            if t, err := strconv.ParseFloat(strings.TrimSpace(f[9]), 64); err == nil {
+                res = append(res, reading{chip: "ipmi", name: name, value: toCelsius(t), kind: kindCrit})
+            }
+        }
+    }
+    return res, nil
+}
+
+// ipmiTemperatureUnit returns a conversion to Celsius for temperature units, ok=false for anything else (RPM, Volts...)
+func ipmiTemperatureUnit(unit string) (func(float64) float64, bool) {
+    switch strings.ToLower(unit) {
+    case "degrees c":
+        return func(v float64) float64 { return v }, true
+    case "degrees f":
+        return func(v float64) float64 { return (v - 32) * 5 / 9 }, true
+    case "degrees k":
+        return func(v float64) float64 { return v - 273.15 }, true
+    }
+    return nil, false
+}
+
+// ipmiUnavailable reports values/statuses ipmitool prints for absent or disabled sensors
+func ipmiUnavailable(s string) bool {
+    switch strings.ToLower(s) {
+    case "", "na", "n/a", "ns", "disabled":
+        return true
+    }
+    return false
+}
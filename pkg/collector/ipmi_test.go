@@ -0,0 +1,79 @@
+package collector
+
+import (
+    "fmt"
+    "math"
+    "os"
+    "path/filepath"
+    "slices"
+    "testing"
+)
+
+// readFixture returns a command output saved under testdata
+func readFixture(t *testing.T, name string) []byte {
+    t.Helper()
+    out, err := os.ReadFile(filepath.Join("testdata", name))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return out
+}
+
+// describe flattens readings to sorted "chip/sensor/label kind=value" strings, the value rounded
+// to the hundredth so unit conversions compare exactly
+func describe(rs []reading) []string {
+    out := make([]string, len(rs))
+    for i, r := range rs {
+        out[i] = fmt.Sprintf("%s/%s/%s %s=%v", r.chip, r.name, r.label, r.kind, math.Round(r.value*100)/100)
+    }
+    slices.Sort(out)
+    return out
+}
+
+func TestParseIPMI(t *testing.T) {
+    ipmitool := func(out []byte) ([]reading, error) { return parseIPMItoolSensor(out), nil }
+    // both fixtures describe the same board: na readings, ns states and the fan and voltage
+    // rows are dropped, Fahrenheit and Kelvin converted
+    board := []string{
+        "ipmi/CPU Temp/ crit=95",
+        "ipmi/CPU Temp/ lcrit=0",
+        "ipmi/CPU Temp/ temperature=45",
+        "ipmi/Exhaust Temp/ temperature=34.85",
+        "ipmi/Inlet Temp/ crit=40",
+        "ipmi/Inlet Temp/ lcrit=0",
+        "ipmi/Inlet Temp/ temperature=25",
+        "ipmi/Peripheral Temp/ temperature=40",
+        "ipmi/System Temp/ crit=85",
+        "ipmi/System Temp/ lcrit=-5",
+        "ipmi/System Temp/ temperature=32",
+    }
+    tests := []struct {
+        name  string
+        parse func([]byte) ([]reading, error)
+        out   []byte
+        want  []string
+    }{
+        {"ipmitool fixture", ipmitool, readFixture(t, "ipmitool-sensor.txt"), board},
+        {"freeipmi fixture", parseFreeIPMISensors, readFixture(t, "ipmi-sensors.csv"), board},
+        {"ipmitool n/a reading", ipmitool, []byte("CPU Temp | N/A | degrees C | ok\n"), nil},
+        {"ipmitool ns state", ipmitool, []byte("CPU Temp | 45.000 | degrees C | ns\n"), nil},
+        {"ipmitool disabled state", ipmitool, []byte("CPU Temp | 45.000 | degrees C | Disabled\n"), nil},
+        {"ipmitool percent unit", ipmitool, []byte("CPU Usage | 12.000 | percent | ok\n"), nil},
+        {"ipmitool without thresholds", ipmitool, []byte("CPU Temp | 45.000 | degrees C | ok\n"), []string{"ipmi/CPU Temp/ temperature=45"}},
+        {"ipmitool short row", ipmitool, []byte("CPU Temp | 45.000\n"), nil},
+        {"freeipmi n/a reading", parseFreeIPMISensors, []byte("4,CPU Temp,Temperature,n/a,C\n"), nil},
+        {"freeipmi volts unit", parseFreeIPMISensors, []byte("30,12V,Voltage,12.19,V,N/A,10.30,N/A,N/A,13.26,N/A,'OK'\n"), nil},
+        {"freeipmi without thresholds", parseFreeIPMISensors, []byte("4,CPU Temp,Temperature,45.00,C\n"), []string{"ipmi/CPU Temp/ temperature=45"}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rs, err := tt.parse(tt.out)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := describe(rs); !slices.Equal(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
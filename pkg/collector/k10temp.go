@@ -0,0 +1,100 @@
+package collector
+
+import (
+    "bufio"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+)
+
+// tccdLabel matches the per-die sensors of k10temp on Zen 2 and later
+var tccdLabel = regexp.MustCompile(`^Tccd(\d+)$`)
+
+// tctlOffsets is the kernel's k10temp table of CPUs whose Tctl reads above the die temperature
+// (drivers/hwmon/k10temp.c), matched against the /proc/cpuinfo model name
+var tctlOffsets = []struct {
+    model  string
+    offset float64
+}{
+    {"AMD Ryzen 5 1600X", 20},
+    {"AMD Ryzen 7 1700X", 20},
+    {"AMD Ryzen 7 1800X", 20},
+    {"AMD Ryzen 7 2700X", 10},
+    {"AMD Ryzen Threadripper 19", 27}, // 1900X, 1920X, 1950X
+    {"AMD Ryzen Threadripper 29", 27}, // 2920X, 2950X, 2970WX, 2990WX
+}
+
+// isK10temp reports whether chip is AMD's k10temp driver, as a hwmon name or an lm-sensors chip
+// (k10temp-pci-00c3)
+func isK10temp(chip string) bool {
+    return chip == "k10temp" || strings.HasPrefix(chip, "k10temp-")
+}
+
+// cpuModel returns the first model name of /proc/cpuinfo, empty when unreadable
+func cpuModel(path string) string {
+    f, err := os.Open(path)
+    if err != nil {
+        return ""
+    }
+    defer f.Close()
+    sc := bufio.NewScanner(f)
+    for sc.Scan() {
+        if k, v, ok := strings.Cut(sc.Text(), ":"); ok && strings.TrimSpace(k) == "model name" {
+            return strings.TrimSpace(v)
+        }
+    }
+    return ""
+}
+
+// tctlOffset returns the Tctl offset of a CPU model, 0 when it has none
+func tctlOffset(model string) float64 {
+    for _, t := range tctlOffsets {
+        if strings.Contains(model, t.model) {
+            return t.offset
+        }
+    }
+    return 0
+}
+
+// annotateK10temp names the unlabeled temp1 of older kernels "Tctl", like newer kernels and
+// lm-sensors do, and fills the ccd of the Tccd sensors. Other chips are left alone.
+func annotateK10temp(readings []reading) {
+    for i := range readings {
+        r := &readings[i]
+        if !isK10temp(r.chip) {
+            continue
+        }
+        if r.label == "" && r.source != "sensors-cli" && strings.HasPrefix(filepath.Base(r.path), "temp1_") {
+            r.label = "Tctl"
+        }
+        if m := tccdLabel.FindStringSubmatch(labelOrName(*r)); m != nil {
+            r.ccd = m[1]
+        }
+    }
+}
+
+// correctTctl lowers the Tctl temperatures of k10temp to the die temperature
+// (-k10temp-correct-tctl). A Tdie reading of the same instance, exposed by kernels that know the
+// offset, is used as is; older kernels get the offset of the CPU model (offset).
+func correctTctl(readings []reading, offset float64) {
+    type instance struct{ source, chip, dir string }
+    inst := func(r reading) instance { return instance{r.source, r.chip, filepath.Dir(r.path)} }
+    tdie := make(map[instance]float64)
+    for _, r := range readings {
+        if isK10temp(r.chip) && r.kind == kindTemperature && labelOrName(r) == "Tdie" {
+            tdie[inst(r)] = r.value
+        }
+    }
+    for i := range readings {
+        r := &readings[i]
+        if !isK10temp(r.chip) || r.kind != kindTemperature || labelOrName(*r) != "Tctl" {
+            continue
+        }
+        if v, ok := tdie[inst(*r)]; ok {
+            r.value = v
+        } else {
+            r.value -= offset
+        }
+    }
+}
@@ -0,0 +1,59 @@
+package collector
+
+import (
+    "context"
+    "encoding/json"
+    "strings"
+    "time"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+var liquidctlWarned bool
+
+// liquidctlDevice is one entry of `liquidctl status --json`
+type liquidctlDevice struct {
+    Description string `json:"description"`
+    Status      []struct {
+        Key   string      `json:"key"`
+        Value interface{} `json:"value"`
+        Unit  string      `json:"unit"`
+    } `json:"status"`
+}
+
+// discoverLiquidctl runs `liquidctl status --json` for AIO coolant temperatures and pump/fan speeds.
+func discoverLiquidctl(ctx context.Context, bin string, timeout time.Duration) ([]reading, error) {
+    out, err := sources.RunCommand(ctx, timeout, bin, "status", "--json")
+    if err != nil {
+        return nil, err
+    }
+    return parseLiquidctl(out)
+}
+
+// parseLiquidctl keeps "°C" entries as temperatures and "rpm" entries as fan speeds;
+// other units (duty %, firmware strings...) and unknown keys are ignored.
+func parseLiquidctl(out []byte) ([]reading, error) {
+    var devices []liquidctlDevice
+    if err := json.Unmarshal(out, &devices); err != nil {
+        return nil, err
+    }
+    var res []reading
+    for _, d := range devices {
+        for _, st := range d.Status {
+            v, ok := st.Value.(float64)
+            if !ok {
+                continue
+            }
+            r := reading{chip: "liquidctl", name: d.Description, label: st.Key, value: v}
+            switch strings.ToLower(st.Unit) {
+            case "°c":
+            case "rpm":
+                r.kind = kindFan
+            default:
+                continue
+            }
+            res = append(res, r)
+        }
+    }
+    return res, nil
+}
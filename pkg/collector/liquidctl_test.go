@@ -0,0 +1,45 @@
+package collector
+
+import (
+    "slices"
+    "testing"
+)
+
+func TestParseLiquidctl(t *testing.T) {
+    tests := []struct {
+        name    string
+        out     []byte
+        want    []string
+        wantErr bool
+    }{
+        {
+            // duty percentages and firmware strings are left out
+            name: "AIO and fan hub",
+            out:  readFixture(t, "liquidctl-status.json"),
+            want: []string{
+                "liquidctl/Corsair Commander Pro/Fan 1 speed fan=820",
+                "liquidctl/Corsair Commander Pro/Temperature 1 temperature=28.5",
+                "liquidctl/NZXT Kraken X (X53, X63 or X73)/Liquid temperature temperature=31.7",
+                "liquidctl/NZXT Kraken X (X53, X63 or X73)/Pump speed fan=2014",
+            },
+        },
+        {
+            name: "unit case",
+            out:  []byte(`[{"description":"Kraken","status":[{"key":"Liquid temperature","value":30,"unit":"°C"},{"key":"Fan speed","value":900,"unit":"RPM"}]}]`),
+            want: []string{"liquidctl/Kraken/Fan speed fan=900", "liquidctl/Kraken/Liquid temperature temperature=30"},
+        },
+        {name: "no device", out: []byte("[]")},
+        {name: "not JSON", out: []byte("ERROR: no devices matches available drivers and selection criteria\n"), wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rs, err := parseLiquidctl(tt.out)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+            }
+            if got := describe(rs); !slices.Equal(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,51 @@
+package collector
+
+import (
+    "strings"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// metricSet accumulates the const metrics of one scrape. Adding the same series twice keeps
+// the last value, as GaugeVec.Set did, instead of emitting a duplicate the registry would reject.
+type metricSet struct {
+    index   map[string]int
+    metrics []constMetric
+}
+
+type constMetric struct {
+    desc   *prometheus.Desc
+    vt     prometheus.ValueType
+    value  float64
+    labels []string
+    at     time.Time // explicit timestamp of the sample, none when zero
+}
+
+func (ms *metricSet) add(desc *prometheus.Desc, vt prometheus.ValueType, value float64, labels ...string) {
+    ms.addAt(time.Time{}, desc, vt, value, labels...)
+}
+
+// addAt is add for a sample measured at a given time, see Options.Timestamps
+func (ms *metricSet) addAt(at time.Time, desc *prometheus.Desc, vt prometheus.ValueType, value float64, labels ...string) {
+    if ms.index == nil {
+        ms.index = map[string]int{}
+    }
+    key := desc.String() + "\xff" + strings.Join(labels, "\xff")
+    if i, ok := ms.index[key]; ok {
+        ms.metrics[i].value, ms.metrics[i].at = value, at
+        return
+    }
+    ms.index[key] = len(ms.metrics)
+    ms.metrics = append(ms.metrics, constMetric{desc: desc, vt: vt, value: value, labels: labels, at: at})
+}
+
+func (ms *metricSet) send(ch chan<- prometheus.Metric) {
+    for _, m := range ms.metrics {
+        metric := prometheus.MustNewConstMetric(m.desc, m.vt, m.value, m.labels...)
+        if !m.at.IsZero() {
+            metric = prometheus.NewMetricWithTimestamp(m.at, metric)
+        }
+        ch <- metric
+    }
+}
@@ -0,0 +1,457 @@
+package collector
+
+import (
+    "bufio"
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "net"
+    "net/url"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// mqttMaxBackoff bounds the wait between two connection attempts to a down broker
+const mqttMaxBackoff = 5 * time.Minute
+
+// MQTT 3.1.1 control packet types, shifted into the high nibble of the fixed header
+const (
+    mqttConnect    = 1 << 4
+    mqttConnack    = 2 << 4
+    mqttPublish    = 3 << 4
+    mqttPuback     = 4 << 4
+    mqttPubrec     = 5 << 4
+    mqttPubrel     = 6<<4 | 0x02
+    mqttPubcomp    = 7 << 4
+    mqttPingreq    = 12 << 4
+    mqttPingresp   = 13 << 4
+    mqttDisconnect = 14 << 4
+)
+
+// MQTTConfig holds the -mqtt-* flags
+type MQTTConfig struct {
+    Broker   string // host:port
+    UseTLS   bool
+    TLS      *tls.Config
+    Prefix   string
+    Host     string
+    ClientID string
+    User     string
+    Password string
+    QoS      byte
+    Interval time.Duration
+    Timeout  time.Duration
+    HAPrefix string // Home Assistant discovery prefix, empty to disable
+}
+
+// ParseMQTTBroker accepts tcp://, mqtt://, ssl://, tls:// and mqtts:// URLs or a bare host:port,
+// and fills in the default port of the scheme.
+func ParseMQTTBroker(s string) (addr string, useTLS bool, err error) {
+    if !strings.Contains(s, "://") {
+        s = "tcp://" + s
+    }
+    u, err := url.Parse(s)
+    if err != nil {
+        return "", false, err
+    }
+    port := "1883"
+    switch u.Scheme {
+    case "tcp", "mqtt":
+    case "ssl", "tls", "mqtts":
+        useTLS, port = true, "8883"
+    default:
+        return "", false, fmt.Errorf("schéma %q inconnu (attendu: tcp, mqtt, ssl, tls ou mqtts)", u.Scheme)
+    }
+    if u.Hostname() == "" {
+        return "", false, fmt.Errorf("%q: hôte manquant", s)
+    }
+    if u.Port() != "" {
+        port = u.Port()
+    }
+    return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+// MQTTTLSConfig verifies the broker against caFile when set, the system roots otherwise
+func MQTTTLSConfig(addr, caFile string, insecure bool) (*tls.Config, error) {
+    host, _, _ := net.SplitHostPort(addr)
+    cfg := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure}
+    if caFile != "" {
+        pem, err := os.ReadFile(caFile)
+        if err != nil {
+            return nil, err
+        }
+        cfg.RootCAs = x509.NewCertPool()
+        if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+            return nil, fmt.Errorf("%s: aucun certificat PEM trouvé", caFile)
+        }
+    }
+    return cfg, nil
+}
+
+// MQTTWriter publishes one retained message per temperature sensor on its own timer. The
+// connection is kept between intervals and re-established with exponential backoff; failures
+// are counted and only connection changes are logged, so a down broker does not flood the journal.
+type MQTTWriter struct {
+    MQTTConfig
+    c        *Collector
+    conn     net.Conn
+    r        *bufio.Reader
+    packetID uint16
+    backoff  time.Duration
+    nextDial time.Time
+    down     bool
+    failed   prometheus.Counter
+
+    // Home Assistant config topics retained on the broker, by unique_id
+    announced map[string]string
+}
+
+// NewMQTTWriter publishes the temperatures of c to the broker of cfg
+func NewMQTTWriter(cfg MQTTConfig, c *Collector) *MQTTWriter {
+    return &MQTTWriter{
+        MQTTConfig: cfg,
+        c:          c,
+        failed: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: c.Namespace,
+            Name:      "mqtt_failed_publishes_total",
+            Help:      "Nombre de publications MQTT en échec (connexion, écriture ou accusé de réception manquant).",
+        }),
+    }
+}
+
+// Collectors returns the failure counter of the writer
+func (mw *MQTTWriter) Collectors() []prometheus.Collector {
+    return []prometheus.Collector{mw.failed}
+}
+
+// Run publishes every interval until ctx is cancelled
+func (mw *MQTTWriter) Run(ctx context.Context) {
+    t := time.NewTicker(mw.Interval)
+    defer t.Stop()
+    defer mw.disconnect()
+    for {
+        msgs := mqttMessages(mw.c.current(), mw.Prefix, mw.Host, time.Now())
+        var announced map[string]string
+        if mw.HAPrefix != "" {
+            var configs []mqttMessage
+            configs, announced = mw.discovery(msgs)
+            msgs = append(configs, msgs...)
+        }
+        if len(msgs) > 0 {
+            if err := mw.publish(ctx, msgs); err != nil {
+                mw.failed.Add(float64(len(msgs)))
+                if !mw.down {
+                    slog.Warn("mqtt", "err", err)
+                    mw.down = true
+                }
+            } else {
+                if announced != nil {
+                    mw.announced = announced
+                }
+                if mw.down {
+                    slog.Info("mqtt: publication rétablie", "broker", mw.Broker)
+                    mw.down = false
+                }
+            }
+        } else if mw.conn != nil {
+            mw.ping()
+        }
+        select {
+        case <-t.C:
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// mqttMessage is one retained publication; chip and label are kept for Home Assistant discovery
+type mqttMessage struct {
+    topic   string
+    payload []byte
+    chip    string
+    label   string
+}
+
+// publish sends the messages over the open connection, connecting first when needed, and waits
+// for their acknowledgements with QoS 1 and 2. Any error drops the connection and delays the
+// next attempt.
+func (mw *MQTTWriter) publish(ctx context.Context, msgs []mqttMessage) error {
+    if mw.conn == nil {
+        if time.Now().Before(mw.nextDial) {
+            return fmt.Errorf("%s injoignable, nouvelle tentative après %s", mw.Broker, mw.nextDial.Format(time.TimeOnly))
+        }
+        if err := mw.connect(ctx); err != nil {
+            return mw.fail(err)
+        }
+    }
+    if err := mw.conn.SetDeadline(time.Now().Add(mw.Timeout)); err != nil {
+        return mw.fail(err)
+    }
+    pending := make(map[uint16]bool)
+    w := bufio.NewWriter(mw.conn)
+    for _, m := range msgs {
+        var id uint16
+        if mw.QoS > 0 {
+            id = mw.nextID()
+            pending[id] = true
+        }
+        if _, err := w.Write(mqttPublishPacket(m, mw.QoS, id)); err != nil {
+            return mw.fail(err)
+        }
+    }
+    if err := w.Flush(); err != nil {
+        return mw.fail(err)
+    }
+    for len(pending) > 0 {
+        typ, body, err := mqttReadPacket(mw.r)
+        if err != nil {
+            return mw.fail(err)
+        }
+        if len(body) < 2 {
+            continue
+        }
+        id := binary.BigEndian.Uint16(body)
+        switch typ & 0xf0 {
+        case mqttPuback, mqttPubcomp:
+            delete(pending, id)
+        case mqttPubrec:
+            if _, err := mw.conn.Write(mqttAck(mqttPubrel, id)); err != nil {
+                return mw.fail(err)
+            }
+        }
+    }
+    mw.backoff = 0
+    return nil
+}
+
+func (mw *MQTTWriter) connect(ctx context.Context) error {
+    d := net.Dialer{Timeout: mw.Timeout}
+    conn, err := d.DialContext(ctx, "tcp", mw.Broker)
+    if err != nil {
+        return err
+    }
+    // the deadline also bounds the TLS handshake and the CONNACK wait
+    if err := conn.SetDeadline(time.Now().Add(mw.Timeout)); err != nil {
+        conn.Close()
+        return err
+    }
+    if mw.UseTLS {
+        tc := tls.Client(conn, mw.TLS)
+        if err := tc.HandshakeContext(ctx); err != nil {
+            conn.Close()
+            return err
+        }
+        conn = tc
+    }
+    mw.conn, mw.r = conn, bufio.NewReader(conn)
+    if _, err := conn.Write(mqttConnectPacket(mw.ClientID, mw.User, mw.Password, mw.keepAlive())); err != nil {
+        return err
+    }
+    typ, body, err := mqttReadPacket(mw.r)
+    if err != nil {
+        return err
+    }
+    if typ != mqttConnack || len(body) < 2 {
+        return fmt.Errorf("réponse inattendue du broker (paquet %d)", typ>>4)
+    }
+    if code := body[1]; code != 0 {
+        return fmt.Errorf("connexion refusée par le broker: %s", mqttConnackReason(code))
+    }
+    return nil
+}
+
+// keepAlive covers two intervals so the broker does not drop a client that publishes on time
+func (mw *MQTTWriter) keepAlive() uint16 {
+    return uint16(min(2*mw.Interval/time.Second+1, 0xffff))
+}
+
+// ping keeps an idle connection alive while no sensor is exported, dropping it when unanswered
+func (mw *MQTTWriter) ping() {
+    if err := mw.conn.SetDeadline(time.Now().Add(mw.Timeout)); err != nil {
+        mw.fail(err)
+        return
+    }
+    if _, err := mw.conn.Write([]byte{mqttPingreq, 0}); err != nil {
+        mw.fail(err)
+        return
+    }
+    if typ, _, err := mqttReadPacket(mw.r); err != nil || typ != mqttPingresp {
+        mw.fail(err)
+    }
+}
+
+func (mw *MQTTWriter) nextID() uint16 {
+    mw.packetID++
+    if mw.packetID == 0 {
+        mw.packetID = 1
+    }
+    return mw.packetID
+}
+
+func (mw *MQTTWriter) fail(err error) error {
+    if mw.conn != nil {
+        mw.conn.Close()
+        mw.conn = nil
+    }
+    // doubles the wait before the next dial, starting at one second
+    switch {
+    case mw.backoff == 0:
+        mw.backoff = time.Second
+    case mw.backoff < mqttMaxBackoff:
+        mw.backoff = min(2*mw.backoff, mqttMaxBackoff)
+    }
+    mw.nextDial = time.Now().Add(mw.backoff)
+    return err
+}
+
+// disconnect says goodbye so the broker does not treat the shutdown as a lost client
+func (mw *MQTTWriter) disconnect() {
+    if mw.conn == nil {
+        return
+    }
+    _ = mw.conn.SetWriteDeadline(time.Now().Add(mw.Timeout))
+    _, _ = mw.conn.Write([]byte{mqttDisconnect, 0})
+    mw.conn.Close()
+}
+
+// mqttMessages builds one message per temperature on prefix/host/chip/label (the sensor name when
+// there is no label), with a {"value","unit","timestamp"} JSON payload.
+func mqttMessages(readings []reading, prefix, host string, now time.Time) []mqttMessage {
+    var msgs []mqttMessage
+    for _, r := range readings {
+        if r.kind != kindTemperature {
+            continue
+        }
+        var topic []string
+        if prefix != "" {
+            topic = append(topic, prefix)
+        }
+        label := r.label
+        if label == "" {
+            label = r.name
+        }
+        for _, p := range []string{host, r.chip, label} {
+            if p = mqttTopicLevel(p); p != "" {
+                topic = append(topic, p)
+            }
+        }
+        payload, _ := json.Marshal(struct {
+            Value     float64 `json:"value"`
+            Unit      string  `json:"unit"`
+            Timestamp int64   `json:"timestamp"`
+        }{r.value, "°C", now.Unix()})
+        msgs = append(msgs, mqttMessage{topic: strings.Join(topic, "/"), payload: payload, chip: r.chip, label: label})
+    }
+    return msgs
+}
+
+// mqttTopicLevel replaces the level separator and wildcards, which are not allowed inside a level
+// of a published topic.
+func mqttTopicLevel(s string) string {
+    return strings.NewReplacer("/", "_", "+", "_", "#", "_", "\x00", "").Replace(s)
+}
+
+func mqttConnectPacket(clientID, user, password string, keepAlive uint16) []byte {
+    flags := byte(0x02) // clean session
+    payload := mqttString(nil, clientID)
+    if user != "" {
+        flags |= 0x80
+        payload = mqttString(payload, user)
+        if password != "" {
+            flags |= 0x40
+            payload = mqttString(payload, password)
+        }
+    }
+    body := mqttString(nil, "MQTT")
+    body = append(body, 4, flags) // protocol level 4 is MQTT 3.1.1
+    body = binary.BigEndian.AppendUint16(body, keepAlive)
+    return mqttPacket(mqttConnect, append(body, payload...))
+}
+
+func mqttPublishPacket(m mqttMessage, qos byte, id uint16) []byte {
+    body := mqttString(nil, m.topic)
+    if qos > 0 {
+        body = binary.BigEndian.AppendUint16(body, id)
+    }
+    // retained: a subscriber (Home Assistant...) gets the last value as soon as it connects
+    return mqttPacket(mqttPublish|qos<<1|0x01, append(body, m.payload...))
+}
+
+func mqttAck(typ byte, id uint16) []byte {
+    return mqttPacket(typ, binary.BigEndian.AppendUint16(nil, id))
+}
+
+// mqttPacket prepends the fixed header, whose remaining length is a base 128 varint
+func mqttPacket(header byte, body []byte) []byte {
+    b := []byte{header}
+    n := len(body)
+    for {
+        d := byte(n % 128)
+        n /= 128
+        if n > 0 {
+            d |= 0x80
+        }
+        b = append(b, d)
+        if n == 0 {
+            break
+        }
+    }
+    return append(b, body...)
+}
+
+func mqttString(b []byte, s string) []byte {
+    b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+    return append(b, s...)
+}
+
+// mqttReadPacket reads one control packet; PINGRESP and other packets without a body come back
+// with an empty one.
+func mqttReadPacket(r *bufio.Reader) (byte, []byte, error) {
+    typ, err := r.ReadByte()
+    if err != nil {
+        return 0, nil, err
+    }
+    n, shift := 0, 0
+    for {
+        d, err := r.ReadByte()
+        if err != nil {
+            return 0, nil, err
+        }
+        n |= int(d&0x7f) << shift
+        if d&0x80 == 0 {
+            break
+        }
+        if shift += 7; shift > 21 {
+            return 0, nil, errors.New("longueur de paquet MQTT invalide")
+        }
+    }
+    body := make([]byte, n)
+    if _, err := io.ReadFull(r, body); err != nil {
+        return 0, nil, err
+    }
+    return typ, body, nil
+}
+
+var mqttConnackReasons = map[byte]string{
+    1: "version de protocole refusée",
+    2: "identifiant client refusé",
+    3: "service indisponible",
+    4: "utilisateur ou mot de passe invalide",
+    5: "non autorisé",
+}
+
+func mqttConnackReason(code byte) string {
+    if s, ok := mqttConnackReasons[code]; ok {
+        return s
+    }
+    return fmt.Sprintf("code %d", code)
+}
@@ -0,0 +1,109 @@
+package collector
+
+import (
+    "bufio"
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "net"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// nutTemperatureVars maps the NUT variables we export to the label used for them
+var nutTemperatureVars = map[string]string{
+    "ups.temperature":     "ups",
+    "battery.temperature": "battery",
+}
+
+// parseNutTarget splits "ups@host[:port]" (host defaults to localhost, port to 3493)
+func parseNutTarget(target string) (ups, addr string) {
+    ups, host, ok := strings.Cut(target, "@")
+    if !ok || host == "" {
+        host = "localhost"
+    }
+    if _, _, err := net.SplitHostPort(host); err != nil {
+        host = net.JoinHostPort(host, "3493")
+    }
+    return ups, host
+}
+
+// discoverNut queries every configured UPS in parallel; a failing UPS is logged and skipped
+// so it never hides the readings of the others. The returned error joins those failures.
+func discoverNut(ctx context.Context, targets []string, timeout time.Duration) ([]reading, error) {
+    var (
+        mu   sync.Mutex
+        wg   sync.WaitGroup
+        res  []reading
+        errs []error
+    )
+    for _, t := range targets {
+        wg.Add(1)
+        go func(target string) {
+            defer wg.Done()
+            readings, err := discoverNutUPS(ctx, target, timeout)
+            if err != nil {
+                slog.Warn("discoverNut error", "ups", target, "err", err)
+                mu.Lock()
+                errs = append(errs, fmt.Errorf("%s: %w", target, err))
+                mu.Unlock()
+                return
+            }
+            mu.Lock()
+            res = append(res, readings...)
+            mu.Unlock()
+        }(t)
+    }
+    wg.Wait()
+    return res, errors.Join(errs...)
+}
+
+// discoverNutUPS speaks the upsd text protocol: LIST VAR <ups> answers with
+// `VAR <ups> <name> "<value>"` lines between BEGIN/END markers.
+func discoverNutUPS(ctx context.Context, target string, timeout time.Duration) ([]reading, error) {
+    ups, addr := parseNutTarget(target)
+    dialer := net.Dialer{Timeout: timeout}
+    conn, err := dialer.DialContext(ctx, "tcp", addr)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Close()
+    if timeout > 0 {
+        if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+            return nil, err
+        }
+    }
+    if _, err := fmt.Fprintf(conn, "LIST VAR %s\n", ups); err != nil {
+        return nil, err
+    }
+    var res []reading
+    s := bufio.NewScanner(conn)
+    for s.Scan() {
+        line := s.Text()
+        if strings.HasPrefix(line, "ERR ") {
+            return nil, fmt.Errorf("upsd: %s", line)
+        }
+        if strings.HasPrefix(line, "END LIST VAR") {
+            _, _ = fmt.Fprint(conn, "LOGOUT\n")
+            return res, nil
+        }
+        fields := strings.SplitN(line, " ", 4)
+        if len(fields) < 4 || fields[0] != "VAR" {
+            continue
+        }
+        label, ok := nutTemperatureVars[fields[2]]
+        if !ok {
+            continue
+        }
+        if v, err := strconv.ParseFloat(strings.Trim(fields[3], `"`), 64); err == nil {
+            res = append(res, reading{chip: "nut", name: ups, label: label, value: v})
+        }
+    }
+    if err := s.Err(); err != nil {
+        return nil, err
+    }
+    return nil, fmt.Errorf("upsd: connection closed before END LIST VAR")
+}
@@ -0,0 +1,79 @@
+package collector
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "net"
+    "slices"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseNutTarget(t *testing.T) {
+    tests := []struct {
+        target, ups, addr string
+    }{
+        {"eaton", "eaton", "localhost:3493"},
+        {"eaton@", "eaton", "localhost:3493"},
+        {"eaton@nas", "eaton", "nas:3493"},
+        {"eaton@nas:3494", "eaton", "nas:3494"},
+        {"eaton@192.0.2.7", "eaton", "192.0.2.7:3493"},
+        {"eaton@[2001:db8::7]:3494", "eaton", "[2001:db8::7]:3494"},
+    }
+    for _, tt := range tests {
+        if ups, addr := parseNutTarget(tt.target); ups != tt.ups || addr != tt.addr {
+            t.Errorf("parseNutTarget(%q) = %q, %q, want %q, %q", tt.target, ups, addr, tt.ups, tt.addr)
+        }
+    }
+}
+
+// upsdServer answers the LIST VAR request with lines
+func upsdServer(t *testing.T, lines []string) string {
+    return serveOnce(t, func(conn net.Conn) {
+        if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+            return
+        }
+        fmt.Fprint(conn, strings.Join(lines, ""))
+    })
+}
+
+func TestDiscoverNutUPS(t *testing.T) {
+    tests := []struct {
+        name    string
+        lines   []string
+        want    []string
+        wantErr bool
+    }{
+        {
+            name: "ups and battery temperatures",
+            lines: []string{
+                "BEGIN LIST VAR eaton\n",
+                "VAR eaton battery.charge \"100\"\n",
+                "VAR eaton battery.temperature \"27.4\"\n",
+                "VAR eaton device.model \"Eaton 5PX 1500\"\n",
+                "VAR eaton ups.temperature \"31.0\"\n",
+                "END LIST VAR eaton\n",
+            },
+            want: []string{"nut/eaton/battery temperature=27.4", "nut/eaton/ups temperature=31"},
+        },
+        {
+            name:  "temperature not numeric",
+            lines: []string{"BEGIN LIST VAR eaton\n", "VAR eaton ups.temperature \"unknown\"\n", "END LIST VAR eaton\n"},
+        },
+        {name: "unknown ups", lines: []string{"ERR UNKNOWN-UPS\n"}, wantErr: true},
+        {name: "closed before the end", lines: []string{"BEGIN LIST VAR eaton\n", "VAR eaton ups.temperature \"31.0\"\n"}, wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rs, err := discoverNutUPS(context.Background(), "eaton@"+upsdServer(t, tt.lines), time.Second)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+            }
+            if got := describe(rs); !slices.Equal(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,53 @@
+package collector
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+var nvidiaWarned bool
+
+// discoverNvidia queries nvidia-smi for the core and memory temperature of every GPU.
+func discoverNvidia(ctx context.Context, bin string, timeout time.Duration) ([]reading, error) {
+    out, err := sources.RunCommand(ctx, timeout, bin, "--query-gpu=index,name,temperature.gpu,temperature.memory", "--format=csv,noheader,nounits")
+    if err != nil {
+        return nil, err
+    }
+    return parseNvidiaSmi(out)
+}
+
+// parseNvidiaSmi parses lines like "0, NVIDIA GeForce RTX 3060, 45, N/A".
+// Each GPU is keyed by its index (sensor="gpuN"), the card name goes to the label.
+func parseNvidiaSmi(out []byte) ([]reading, error) {
+    r := csv.NewReader(bytes.NewReader(out))
+    r.FieldsPerRecord = -1
+    r.TrimLeadingSpace = true
+    records, err := r.ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    var res []reading
+    for _, f := range records {
+        if len(f) < 3 {
+            continue
+        }
+        sensor := "gpu" + strings.TrimSpace(f[0])
+        name := strings.TrimSpace(f[1])
+        if v, err := strconv.ParseFloat(strings.TrimSpace(f[2]), 64); err == nil {
+            res = append(res, reading{chip: "nvidia", name: sensor, label: name, value: v})
+        }
+        // memory temperature is "N/A" on most consumer cards
+        if len(f) >= 4 {
+            if v, err := strconv.ParseFloat(strings.TrimSpace(f[3]), 64); err == nil {
+                res = append(res, reading{chip: "nvidia", name: sensor + "_memory", label: name, value: v})
+            }
+        }
+    }
+    return res, nil
+}
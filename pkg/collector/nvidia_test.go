@@ -0,0 +1,41 @@
+package collector
+
+import (
+    "slices"
+    "testing"
+)
+
+func TestParseNvidiaSmi(t *testing.T) {
+    tests := []struct {
+        name    string
+        out     string
+        want    []string
+        wantErr bool
+    }{
+        {
+            name: "consumer and datacenter cards",
+            out:  "0, NVIDIA GeForce RTX 3060, 45, N/A\n1, NVIDIA A2, 38, 52\n",
+            want: []string{
+                "nvidia/gpu0/NVIDIA GeForce RTX 3060 temperature=45",
+                "nvidia/gpu1/NVIDIA A2 temperature=38",
+                "nvidia/gpu1_memory/NVIDIA A2 temperature=52",
+            },
+        },
+        {name: "card lost by the driver", out: "0, NVIDIA GeForce RTX 3060, [GPU is lost], [GPU is lost]\n"},
+        {name: "no memory column", out: "0, Tesla T4, 41\n", want: []string{"nvidia/gpu0/Tesla T4 temperature=41"}},
+        {name: "short row", out: "0, Tesla T4\n"},
+        {name: "no GPU", out: ""},
+        {name: "broken quoting", out: "0, \"Tesla T4, 41, N/A\n", wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rs, err := parseNvidiaSmi([]byte(tt.out))
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+            }
+            if got := describe(rs); !slices.Equal(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,30 @@
+package collector
+
+import (
+    "io"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/common/expfmt"
+)
+
+// CollectOnce gathers reg a single time and writes it to w in the text exposition format, as a
+// scrape would. ok is false when no enabled source succeeded.
+func (c *Collector) CollectOnce(w io.Writer, reg prometheus.Gatherer) (ok bool, err error) {
+    families, err := reg.Gather()
+    if err != nil {
+        return false, err
+    }
+    for _, mf := range families {
+        if _, err := expfmt.MetricFamilyToText(w, mf); err != nil {
+            return false, err
+        }
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    for _, st := range c.lastStats {
+        if st.success {
+            return true, nil
+        }
+    }
+    return false, nil
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+    "context"
+    "log/slog"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// peakTracker keeps the highest value of each temperature sensor since start or the last
+// reset. It lives in memory only: a restart starts over.
+type peakTracker struct {
+    mu    sync.Mutex
+    peaks map[string]peak
+}
+
+type peak struct {
+    source string
+    labels []string
+    value  float64
+}
+
+func newPeakTracker() *peakTracker {
+    return &peakTracker{peaks: make(map[string]peak)}
+}
+
+// update records the temperatures of a collection; labels returns the metric label values of a reading
+func (pt *peakTracker) update(readings []reading, labels func(reading) []string) {
+    pt.mu.Lock()
+    defer pt.mu.Unlock()
+    for _, r := range readings {
+        if r.kind != kindTemperature {
+            continue
+        }
+        lv := labels(r)
+        key := strings.Join(lv, "\xff")
+        if p, ok := pt.peaks[key]; !ok || r.value > p.value {
+            pt.peaks[key] = peak{source: r.source, labels: lv, value: r.value}
+        }
+    }
+}
+
+// snapshot returns the peaks of the sources selected for a scrape
+func (pt *peakTracker) snapshot(selection map[string]bool) []peak {
+    pt.mu.Lock()
+    defer pt.mu.Unlock()
+    out := make([]peak, 0, len(pt.peaks))
+    for _, p := range pt.peaks {
+        if selected(selection, p.source) {
+            out = append(out, p)
+        }
+    }
+    return out
+}
+
+func (pt *peakTracker) reset() {
+    pt.mu.Lock()
+    defer pt.mu.Unlock()
+    clear(pt.peaks)
+    slog.Info("pics de température réinitialisés")
+}
+
+// samplePeaks collects every interval between scrapes, so a short spike Prometheus would miss
+// still shows up in temperature_peak_celsius
+func (c *Collector) samplePeaks(ctx context.Context, interval time.Duration) {
+    t := time.NewTicker(interval)
+    defer t.Stop()
+    for {
+        select {
+        case <-t.C:
+            c.peaks.update(c.current(), c.labelValues)
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// ResetPeaksHandler clears the peaks on POST /-/reset-peaks
+func (c *Collector) ResetPeaksHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost {
+            w.Header().Set("Allow", "POST")
+            http.Error(w, "méthode non autorisée", http.StatusMethodNotAllowed)
+            return
+        }
+        c.peaks.reset()
+        w.WriteHeader(http.StatusNoContent)
+    })
+}
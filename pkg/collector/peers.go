@@ -0,0 +1,265 @@
+package collector
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    dto "github.com/prometheus/client_model/go"
+    "github.com/prometheus/common/expfmt"
+    "github.com/prometheus/common/model"
+    "google.golang.org/protobuf/proto"
+)
+
+// PeersConfig holds the -peers flags of the /all endpoint
+type PeersConfig struct {
+    Peers        []Peer
+    Node         string        // node label of the local metrics
+    Timeout      time.Duration // per peer, the scrape's own deadline still applies
+    WriteTimeout time.Duration
+}
+
+// Peer is a sibling exporter of /all
+type Peer struct {
+    Node string   // node label of its series
+    URL  *url.URL // its /metrics
+}
+
+// peer is a Peer being scraped. down is set while it fails, so only state changes are logged.
+type peer struct {
+    Peer
+    down atomic.Bool
+}
+
+// ParsePeers checks the -peers entries, [node=]URL. A bare host:port stands for
+// http://host:port/metrics and the node defaults to the host of the URL.
+func ParsePeers(list []string) ([]Peer, error) {
+    var peers []Peer
+    seen := make(map[string]bool)
+    for _, s := range list {
+        var node string
+        // a URL only holds = in its query, after a : or a /
+        if i := strings.IndexByte(s, '='); i > 0 && !strings.ContainsAny(s[:i], ":/") {
+            node, s = s[:i], s[i+1:]
+        }
+        if !strings.Contains(s, "://") {
+            s = "http://" + s
+        }
+        u, err := url.Parse(s)
+        if err != nil {
+            return nil, err
+        }
+        if u.Scheme != "http" && u.Scheme != "https" {
+            return nil, fmt.Errorf("%q: schéma %q inconnu (attendu: http ou https)", s, u.Scheme)
+        }
+        if u.Hostname() == "" {
+            return nil, fmt.Errorf("%q: hôte manquant", s)
+        }
+        if u.Path == "" || u.Path == "/" {
+            u.Path = "/metrics"
+        }
+        if node == "" {
+            node = u.Hostname()
+        }
+        if seen[node] {
+            return nil, fmt.Errorf("%q: nœud %q déjà présent", s, node)
+        }
+        seen[node] = true
+        peers = append(peers, Peer{Node: node, URL: u})
+    }
+    return peers, nil
+}
+
+// AllHandler serves /all: the metrics of this node, as /metrics would serve them with its own
+// metrics included, merged with those of every peer. Peers are fetched in parallel while the local collection runs, each
+// under its own timeout, and their series get node set to the one of their Peer (the local
+// ones to cfg.Node, peer_up none) so identical sensors of two nodes stay apart. A peer that fails is left
+// out and reported by peer_up{peer}; a family whose type differs from the local one is dropped.
+func (c *Collector) AllHandler(own prometheus.Gatherer, labels prometheus.Labels, cfg PeersConfig) http.Handler {
+    peers := make([]*peer, len(cfg.Peers))
+    for i, p := range cfg.Peers {
+        peers[i] = &peer{Peer: p}
+    }
+    client := &http.Client{}
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        selection, err := c.sourceSelection(r)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        timeout := scrapeTimeout(r, cfg.WriteTimeout)
+        ctx, cancel := c.scrapeContext(timeout)
+        defer cancel()
+
+        var wg sync.WaitGroup
+        results := make([]map[string]*dto.MetricFamily, len(peers))
+        for i, p := range peers {
+            wg.Add(1)
+            go func() {
+                defer wg.Done()
+                pctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+                defer cancel()
+                families, err := p.fetch(pctx, client, r.URL.Query()["collect[]"])
+                if err != nil {
+                    if !p.down.Swap(true) {
+                        slog.Warn("pair injoignable", "peer", p.Node, "url", p.URL.Redacted(), "err", err)
+                    }
+                    return
+                }
+                if p.down.Swap(false) {
+                    slog.Info("pair de nouveau joignable", "peer", p.Node)
+                }
+                results[i] = families
+            }()
+        }
+
+        var families []*dto.MetricFamily
+        g, err := c.scrapeGatherer(ctx, selection, labels, own)
+        if err == nil {
+            families, err = g.Gather()
+        }
+        wg.Wait()
+        if err != nil && len(families) == 0 {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+
+        reg := prometheus.NewRegistry()
+        up := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+            Namespace: c.Namespace,
+            Name:      "peer_up",
+            Help:      "1 si les métriques du pair ont pu être lues lors de ce scrape de /all, 0 sinon.",
+        }, []string{"peer"})
+        reg.MustRegister(up)
+        m := newFamilyMerger()
+        m.add(familyMap(families), cfg.Node)
+        for i, p := range peers {
+            if results[i] != nil {
+                m.add(results[i], p.Node)
+                up.WithLabelValues(p.Node).Set(1)
+            } else {
+                up.WithLabelValues(p.Node).Set(0)
+            }
+        }
+        if own, err := reg.Gather(); err == nil {
+            m.add(familyMap(own), "")
+        }
+
+        format := expfmt.Negotiate(r.Header)
+        w.Header().Set("Content-Type", string(format))
+        enc := expfmt.NewEncoder(w, format)
+        for _, mf := range m.families() {
+            if err := enc.Encode(mf); err != nil {
+                return
+            }
+        }
+    })
+}
+
+// fetch scrapes the peer in the text format, passing collect[] along
+func (p *peer) fetch(ctx context.Context, client *http.Client, collect []string) (map[string]*dto.MetricFamily, error) {
+    u := *p.URL
+    if len(collect) > 0 {
+        q := u.Query()
+        q["collect[]"] = collect
+        u.RawQuery = q.Encode()
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeTextPlain)))
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("statut HTTP %s", resp.Status)
+    }
+    var parser expfmt.TextParser
+    return parser.TextToMetricFamilies(resp.Body)
+}
+
+// familyMerger merges metric families by name, dropping series already present
+type familyMerger struct {
+    byName map[string]*dto.MetricFamily
+    seen   map[string]map[uint64]bool // label signatures per family
+}
+
+// familyMap indexes families by name
+func familyMap(families []*dto.MetricFamily) map[string]*dto.MetricFamily {
+    res := make(map[string]*dto.MetricFamily, len(families))
+    for _, mf := range families {
+        res[mf.GetName()] = mf
+    }
+    return res
+}
+
+// newFamilyMerger returns an empty merger
+func newFamilyMerger() *familyMerger {
+    return &familyMerger{byName: make(map[string]*dto.MetricFamily), seen: make(map[string]map[uint64]bool)}
+}
+
+// add merges families into m, setting the node label of their series first when node is not empty
+func (m *familyMerger) add(families map[string]*dto.MetricFamily, node string) {
+    for name, mf := range families {
+        dst, ok := m.byName[name]
+        switch {
+        case !ok:
+            dst = &dto.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type, Unit: mf.Unit}
+            m.byName[name] = dst
+            m.seen[name] = make(map[uint64]bool)
+        case dst.GetType() != mf.GetType():
+            slog.Debug("famille de type différent ignorée", "name", name, "type", mf.GetType(), "node", node)
+            continue
+        }
+        for _, metric := range mf.Metric {
+            if node != "" {
+                setLabel(metric, "node", node)
+            }
+            lv := make(map[string]string, len(metric.Label))
+            for _, l := range metric.Label {
+                lv[l.GetName()] = l.GetValue()
+            }
+            sig := model.LabelsToSignature(lv)
+            if m.seen[name][sig] {
+                continue
+            }
+            m.seen[name][sig] = true
+            dst.Metric = append(dst.Metric, metric)
+        }
+    }
+}
+
+// families returns the merged families sorted by name
+func (m *familyMerger) families() []*dto.MetricFamily {
+    res := make([]*dto.MetricFamily, 0, len(m.byName))
+    for _, mf := range m.byName {
+        if len(mf.Metric) > 0 {
+            res = append(res, mf)
+        }
+    }
+    sort.Slice(res, func(i, j int) bool { return res[i].GetName() < res[j].GetName() })
+    return res
+}
+
+// setLabel sets or replaces a label of metric, keeping the labels sorted by name
+func setLabel(metric *dto.Metric, name, value string) {
+    for _, l := range metric.Label {
+        if l.GetName() == name {
+            l.Value = proto.String(value)
+            return
+        }
+    }
+    metric.Label = append(metric.Label, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+    sort.Slice(metric.Label, func(i, j int) bool { return metric.Label[i].GetName() < metric.Label[j].GetName() })
+}
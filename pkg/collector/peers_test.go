@@ -0,0 +1,209 @@
+package collector
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "reflect"
+    "sort"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    dto "github.com/prometheus/client_model/go"
+    "github.com/prometheus/common/expfmt"
+)
+
+func TestParsePeers(t *testing.T) {
+    tests := []struct {
+        name    string
+        list    []string
+        want    []string // "node URL"
+        wantErr string
+    }{
+        {"node=URL", []string{"pve2=http://10.0.0.2:9102/metrics"}, []string{"pve2 http://10.0.0.2:9102/metrics"}, ""},
+        {"bare host:port", []string{"10.0.0.3:9102"}, []string{"10.0.0.3 http://10.0.0.3:9102/metrics"}, ""},
+        {"node and bare host:port", []string{"pve3=pve3.lan:9102"}, []string{"pve3 http://pve3.lan:9102/metrics"}, ""},
+        {"https with a path", []string{"https://pve4.lan/exporter/metrics"}, []string{"pve4.lan https://pve4.lan/exporter/metrics"}, ""},
+        // the = of a query is not a node
+        {"query", []string{"http://pve5.lan:9102/metrics?collect[]=hwmon"}, []string{"pve5.lan http://pve5.lan:9102/metrics?collect[]=hwmon"}, ""},
+        {"duplicate node", []string{"pve2=10.0.0.2:9102", "pve2=10.0.0.9:9102"}, nil, "déjà présent"},
+        {"duplicate host", []string{"10.0.0.2:9102", "http://10.0.0.2:9200/metrics"}, nil, "déjà présent"},
+        {"unknown scheme", []string{"ftp://pve2.lan/metrics"}, nil, "schéma"},
+        {"no host", []string{"pve2=http:///metrics"}, nil, "hôte manquant"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            peers, err := ParsePeers(tt.list)
+            if tt.wantErr != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                    t.Errorf("ParsePeers(%q) error = %v, want one containing %q", tt.list, err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            var got []string
+            for _, p := range peers {
+                got = append(got, p.Node+" "+p.URL.String())
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("ParsePeers(%q) = %q, want %q", tt.list, got, tt.want)
+            }
+        })
+    }
+}
+
+// parseFamilies parses the text exposition format
+func parseFamilies(t *testing.T, text string) map[string]*dto.MetricFamily {
+    t.Helper()
+    var parser expfmt.TextParser
+    families, err := parser.TextToMetricFamilies(strings.NewReader(text))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return families
+}
+
+// seriesOf formats the series of family name as "label=value,... value", sorted
+func seriesOf(families []*dto.MetricFamily, name string) []string {
+    var got []string
+    for _, mf := range families {
+        if mf.GetName() != name {
+            continue
+        }
+        for _, m := range mf.GetMetric() {
+            var labels []string
+            for _, l := range m.GetLabel() {
+                labels = append(labels, l.GetName()+"="+l.GetValue())
+            }
+            value := m.GetGauge().GetValue() + m.GetCounter().GetValue() + m.GetUntyped().GetValue()
+            got = append(got, fmt.Sprintf("%s %g", strings.Join(labels, ","), value))
+        }
+    }
+    sort.Strings(got)
+    return got
+}
+
+func TestFamilyMerger(t *testing.T) {
+    local := parseFamilies(t, `# TYPE temperature_celsius gauge
+temperature_celsius{chip="k10temp",sensor="k10temp"} 45
+# TYPE sensors_discovered gauge
+sensors_discovered{source="hwmon"} 5
+`)
+    peer := parseFamilies(t, `# TYPE temperature_celsius gauge
+temperature_celsius{chip="k10temp",node="spoofed",sensor="k10temp"} 50
+# TYPE sensors_discovered counter
+sensors_discovered{source="hwmon"} 7
+# TYPE fan_rpm gauge
+fan_rpm{chip="nct6798",sensor="fan2"} 1139
+`)
+    own := parseFamilies(t, `# TYPE peer_up gauge
+peer_up{peer="pve2"} 1
+`)
+    m := newFamilyMerger()
+    m.add(local, "pve1")
+    m.add(peer, "pve2")
+    // a peer listed twice, or answering twice, adds no series
+    m.add(parseFamilies(t, `# TYPE temperature_celsius gauge
+temperature_celsius{chip="k10temp",sensor="k10temp"} 51
+`), "pve2")
+    m.add(own, "")
+    families := m.families()
+
+    tests := []struct {
+        name string
+        want []string
+    }{
+        // the node of the peer wins over the one its series carried
+        {"temperature_celsius", []string{"chip=k10temp,node=pve1,sensor=k10temp 45", "chip=k10temp,node=pve2,sensor=k10temp 50"}},
+        // the peer's counter does not mix into the local gauge
+        {"sensors_discovered", []string{"node=pve1,source=hwmon 5"}},
+        {"fan_rpm", []string{"chip=nct6798,node=pve2,sensor=fan2 1139"}},
+        // the /all metrics keep their labels
+        {"peer_up", []string{"peer=pve2 1"}},
+    }
+    for _, tt := range tests {
+        if got := seriesOf(families, tt.name); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+    var names []string
+    for _, mf := range families {
+        names = append(names, mf.GetName())
+    }
+    if want := []string{"fan_rpm", "peer_up", "sensors_discovered", "temperature_celsius"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("families() names = %q, want %q sorted", names, want)
+    }
+}
+
+// peerURL returns the Peer of node served by srv
+func peerURL(t *testing.T, node string, srv *httptest.Server) Peer {
+    t.Helper()
+    u, err := url.Parse(srv.URL + "/metrics")
+    if err != nil {
+        t.Fatal(err)
+    }
+    return Peer{Node: node, URL: u}
+}
+
+// TestAllHandler merges the local metrics with a peer that answers, one that is down and one
+// too slow: the response comes within -peers-timeout and peer_up tells which ones are missing
+func TestAllHandler(t *testing.T) {
+    good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        fmt.Fprint(w, "# TYPE temperature_celsius gauge\ntemperature_celsius{chip=\"k10temp\",sensor=\"k10temp\",label=\"Tctl\"} 50\n")
+    }))
+    defer good.Close()
+    down := httptest.NewServer(http.NotFoundHandler())
+    down.Close()
+    release := make(chan struct{})
+    slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        select {
+        case <-r.Context().Done():
+        case <-release:
+        }
+    }))
+    defer slow.Close()
+    defer close(release)
+
+    c, err := New(Options{EnableHwmon: true, HwmonPaths: []string{writeTree(t, fakeHwmonFiles)}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    const peerTimeout = 200 * time.Millisecond
+    h := c.AllHandler(prometheus.NewRegistry(), nil, PeersConfig{
+        Peers:   []Peer{peerURL(t, "pve2", good), peerURL(t, "pve3", down), peerURL(t, "pve4", slow)},
+        Node:    "pve1",
+        Timeout: peerTimeout,
+    })
+    start := time.Now()
+    rec := httptest.NewRecorder()
+    h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/all", nil))
+    if elapsed := time.Since(start); elapsed > peerTimeout+time.Second {
+        t.Errorf("/all took %v with a peer that never answers, want about -peers-timeout (%v)", elapsed, peerTimeout)
+    }
+    if rec.Code != http.StatusOK {
+        t.Fatalf("/all status = %d: %s", rec.Code, rec.Body)
+    }
+    var families []*dto.MetricFamily
+    for _, mf := range parseFamilies(t, rec.Body.String()) {
+        families = append(families, mf)
+    }
+    if got, want := seriesOf(families, "peer_up"), []string{"peer=pve2 1", "peer=pve3 0", "peer=pve4 0"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("peer_up = %q, want %q", got, want)
+    }
+    nodes := map[string]int{}
+    for _, s := range seriesOf(families, "temperature_celsius") {
+        for _, l := range strings.Split(strings.Fields(s)[0], ",") {
+            if node, ok := strings.CutPrefix(l, "node="); ok {
+                nodes[node]++
+            }
+        }
+    }
+    if want := map[string]int{"pve1": 5, "pve2": 1}; !reflect.DeepEqual(nodes, want) {
+        t.Errorf("temperature_celsius series by node = %v, want %v", nodes, want)
+    }
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+    "context"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// raplDomain is one intel-rapl powercap zone (package, core, uncore, dram)
+type raplDomain struct {
+    pkg      string // socket index from intel-rapl:N
+    domain   string // content of the name file, package-N normalized to "package"
+    energy   uint64 // energy_uj
+    maxRange uint64 // max_energy_range_uj, 0 when unknown
+}
+
+var raplZoneRe = regexp.MustCompile(`^intel-rapl:(\d+)(?::\d+)?$`)
+
+// discoverRAPL reads every intel-rapl zone under powercapBase (default /sys/class/powercap) in
+// fsys (the host when nil), returning the zones read so far once ctx ends.
+func discoverRAPL(ctx context.Context, fsys sources.FS, powercapBase string) ([]raplDomain, error) {
+    if fsys == nil {
+        fsys = sources.OS
+    }
+    var domains []raplDomain
+    entries, err := fsys.ReadDir(powercapBase)
+    if err != nil {
+        return domains, err
+    }
+    for _, e := range entries {
+        if ctx.Err() != nil {
+            return domains, nil
+        }
+        match := raplZoneRe.FindStringSubmatch(e.Name())
+        if match == nil {
+            continue
+        }
+        zoneDir := filepath.Join(powercapBase, e.Name())
+        raw, err := sources.ReadFirstLine(fsys, filepath.Join(zoneDir, "energy_uj"))
+        if err != nil {
+            // energy_uj is root-only on most kernels since CVE-2020-8694
+            continue
+        }
+        energy, err := strconv.ParseUint(raw, 10, 64)
+        if err != nil {
+            continue
+        }
+        name, _ := sources.ReadFirstLine(fsys, filepath.Join(zoneDir, "name"))
+        if strings.HasPrefix(name, "package-") {
+            name = "package"
+        }
+        d := raplDomain{pkg: match[1], domain: name, energy: energy}
+        if m, err := sources.ReadFirstLine(fsys, filepath.Join(zoneDir, "max_energy_range_uj")); err == nil {
+            d.maxRange, _ = strconv.ParseUint(m, 10, 64)
+        }
+        domains = append(domains, d)
+    }
+    return domains, nil
+}
+
+// raplCounters turns the wrapping energy_uj registers into monotonically increasing joule counters.
+type raplCounters struct {
+    mu    sync.Mutex
+    last  map[string]uint64
+    total map[string]float64
+}
+
+func newRaplCounters() *raplCounters {
+    return &raplCounters{last: map[string]uint64{}, total: map[string]float64{}}
+}
+
+// update records a new raw reading and returns the accumulated energy in joules.
+// When the register wrapped at max_energy_range_uj the missing range is added back so rate() stays correct.
+func (rc *raplCounters) update(d raplDomain) float64 {
+    rc.mu.Lock()
+    defer rc.mu.Unlock()
+    key := d.pkg + "/" + d.domain
+    last, seen := rc.last[key]
+    switch {
+    case !seen:
+        rc.total[key] = float64(d.energy) / 1e6
+    case d.energy >= last:
+        rc.total[key] += float64(d.energy-last) / 1e6
+    case d.maxRange > last:
+        rc.total[key] += float64(d.maxRange-last+d.energy) / 1e6
+    default:
+        // wrapped without a known range: count from zero rather than going backwards
+        rc.total[key] += float64(d.energy) / 1e6
+    }
+    rc.last[key] = d.energy
+    return rc.total[key]
+}
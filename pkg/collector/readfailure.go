@@ -0,0 +1,52 @@
+package collector
+
+import (
+    "errors"
+    "io/fs"
+    "log/slog"
+    "strconv"
+    "sync"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// failureLogged remembers the sensors whose failure was already logged, once per reason
+var failureLogged sync.Map
+
+// failureReason classifies a read error for the reason label
+func failureReason(err error) string {
+    var numErr *strconv.NumError
+    switch {
+    case errors.Is(err, sources.ErrReadAbandoned):
+        return "timeout"
+    case errors.Is(err, fs.ErrPermission):
+        return "permission"
+    case errors.Is(err, fs.ErrNotExist):
+        return "not_found"
+    case errors.Is(err, sources.ErrEmptyFile), errors.Is(err, sources.ErrNotNumber), errors.As(err, &numErr):
+        return "parse"
+    }
+    return "io"
+}
+
+// recordFailures counts the failed reads of a collection per sensor, and in the per-source
+// counters of the sysfs files. Only the first failure of a sensor (for each reason) is logged,
+// so one failing for weeks does not flood the logs.
+func (c *Collector) recordFailures(source string, failures sources.ReadErrors) {
+    for _, f := range failures {
+        chip, name, label := sanitizeLabel(f.Chip), sanitizeLabel(f.Name), sanitizeLabel(f.Label)
+        reason := failureReason(f.Err)
+        c.failures.WithLabelValues(chip, name, label, reason).Inc()
+        if f.Path != "" {
+            if reason == "timeout" {
+                c.abandoned.WithLabelValues(source).Inc()
+            } else {
+                c.readErrors.WithLabelValues(source).Inc()
+            }
+        }
+        key := source + "\xff" + chip + "\xff" + name + "\xff" + label + "\xff" + f.Path + "\xff" + reason
+        if _, seen := failureLogged.LoadOrStore(key, struct{}{}); !seen {
+            slog.Debug("échec de lecture d'un capteur", "source", source, "chip", chip, "sensor", name, "label", label, "path", f.Path, "reason", reason)
+        }
+    }
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+    "fmt"
+    "log/slog"
+)
+
+// ruleSet groups everything loaded from rule files. The collector swaps it as a whole on
+// SIGHUP so a scrape never sees half of an old and half of a new configuration.
+type ruleSet struct {
+    chips       sourceChips // from flags, rebuilt with the files so /sensors can bypass it
+    blocklist   *blocklist
+    calibration []calibrationEntry
+    renameRules []renameRule
+    alertRules  []alertRule
+}
+
+// loadRules reads the blocklist, calibration and rename files named in the config.
+func (cfg *Options) loadRules() (*ruleSet, error) {
+    var rs ruleSet
+    var err error
+    if rs.chips, err = newSourceChips(cfg.HwmonChips, cfg.ThermalZones, cfg.SensorsCliChips); err != nil {
+        return nil, err
+    }
+    if rs.blocklist, err = newBlocklist(cfg.DefaultBlocklist, cfg.BlocklistFile); err != nil {
+        return nil, fmt.Errorf("-blocklist-file: %v", err)
+    }
+    rs.blocklist.log = cfg.LogFiltered
+    if cfg.CalibrationFile != "" {
+        if rs.calibration, err = loadCalibration(cfg.CalibrationFile); err != nil {
+            return nil, fmt.Errorf("-calibration-file: %v", err)
+        }
+    }
+    if cfg.RenameFile != "" {
+        if rs.renameRules, err = loadRenameRules(cfg.RenameFile); err != nil {
+            return nil, fmt.Errorf("-rename-file: %v", err)
+        }
+    }
+    if cfg.AlertRulesFile != "" {
+        if rs.alertRules, err = loadAlertRules(cfg.AlertRulesFile); err != nil {
+            return nil, fmt.Errorf("-alert-rules-file: %v", err)
+        }
+    }
+    return &rs, nil
+}
+
+// Reload re-reads the rule files and swaps them in. On error the running rules stay active.
+func (c *Collector) Reload() {
+    next, err := c.loadRules()
+    if err != nil {
+        c.reloadOK.Set(0)
+        slog.Error("reload failed, keeping the previous configuration", "err", err)
+        return
+    }
+    prev := c.rules.Swap(next)
+    c.reloadOK.Set(1)
+    slog.Info("configuration reloaded",
+        "blocklist", fmt.Sprintf("%d -> %d rules", len(prev.blocklist.rules), len(next.blocklist.rules)),
+        "calibration", fmt.Sprintf("%d -> %d entries", len(prev.calibration), len(next.calibration)),
+        "rename", fmt.Sprintf("%d -> %d rules", len(prev.renameRules), len(next.renameRules)),
+        "alerts", fmt.Sprintf("%d -> %d rules", len(prev.alertRules), len(next.alertRules)))
+    if len(next.calibration) > 0 {
+        c.inflight.Add(1)
+        defer c.inflight.Done()
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        readings, _ := c.gather(c.ctx, next, nil)
+        reportUnmatchedCalibration(readings, next.calibration)
+    }
+}
@@ -0,0 +1,301 @@
+package collector
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "log/slog"
+    "math"
+    "net/http"
+    "sort"
+    "strconv"
+    "time"
+
+    "github.com/klauspost/compress/snappy"
+    "github.com/prometheus/client_golang/prometheus"
+    dto "github.com/prometheus/client_model/go"
+    "google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWriteConfig holds the -remote-write-* flags
+type RemoteWriteConfig struct {
+    URL        string
+    Interval   time.Duration
+    Timeout    time.Duration
+    User       string
+    Password   string
+    Token      string
+    Job        string
+    Instance   string
+    QueueSize  int
+    MaxRetries int
+}
+
+// RemoteWriter pushes the registry to a remote_write endpoint. Batches wait in a bounded
+// queue: when the endpoint is slower than the interval the oldest batch is dropped rather
+// than letting memory grow.
+type RemoteWriter struct {
+    RemoteWriteConfig
+    gatherer prometheus.Gatherer
+    client   *http.Client
+    queue    chan []byte
+    sent     prometheus.Counter
+    failed   prometheus.Counter
+    dropped  prometheus.Counter
+}
+
+// NewRemoteWriter pushes the series of g, namespace being the prefix of its own metrics
+func NewRemoteWriter(cfg RemoteWriteConfig, namespace string, g prometheus.Gatherer) *RemoteWriter {
+    return &RemoteWriter{
+        RemoteWriteConfig: cfg,
+        gatherer:          g,
+        client:            &http.Client{Timeout: cfg.Timeout},
+        queue:             make(chan []byte, cfg.QueueSize),
+        sent: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: namespace,
+            Name:      "remote_write_samples_sent_total",
+            Help:      "Nombre d'échantillons acceptés par le point de terminaison remote_write.",
+        }),
+        failed: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: namespace,
+            Name:      "remote_write_failed_batches_total",
+            Help:      "Nombre de lots remote_write abandonnés après erreur ou épuisement des tentatives.",
+        }),
+        dropped: prometheus.NewCounter(prometheus.CounterOpts{
+            Namespace: namespace,
+            Name:      "remote_write_dropped_batches_total",
+            Help:      "Nombre de lots remote_write écartés car la file d'attente était pleine.",
+        }),
+    }
+}
+
+// Collectors returns the failure and drop counters of the writer
+func (rw *RemoteWriter) Collectors() []prometheus.Collector {
+    return []prometheus.Collector{rw.sent, rw.failed, rw.dropped}
+}
+
+// Run gathers every interval and sends the queued batches until ctx is cancelled
+func (rw *RemoteWriter) Run(ctx context.Context) {
+    go rw.send(ctx)
+    t := time.NewTicker(rw.Interval)
+    defer t.Stop()
+    for {
+        rw.enqueue()
+        select {
+        case <-t.C:
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// enqueue gathers the registry into a batch, dropping the oldest queued one when full
+func (rw *RemoteWriter) enqueue() {
+    families, err := rw.gatherer.Gather()
+    if err != nil {
+        slog.Warn("remote_write", "err", err)
+    }
+    batch := encodeWriteRequest(families, map[string]string{"job": rw.Job, "instance": rw.Instance}, time.Now())
+    for {
+        select {
+        case rw.queue <- batch:
+            return
+        default:
+        }
+        select {
+        case <-rw.queue:
+            rw.dropped.Inc()
+        default:
+        }
+    }
+}
+
+// send posts queued batches, retrying 429 and 5xx answers with exponential backoff
+func (rw *RemoteWriter) send(ctx context.Context) {
+    for {
+        var batch []byte
+        select {
+        case batch = <-rw.queue:
+        case <-ctx.Done():
+            return
+        }
+        backoff := time.Second
+        for attempt := 0; ; attempt++ {
+            retry, err := rw.post(ctx, batch)
+            if err == nil {
+                break
+            }
+            if !retry || attempt >= rw.MaxRetries || ctx.Err() != nil {
+                rw.failed.Inc()
+                slog.Warn("remote_write: lot abandonné", "err", err)
+                break
+            }
+            select {
+            case <-time.After(backoff):
+            case <-ctx.Done():
+            }
+            backoff *= 2
+        }
+    }
+}
+
+// post sends one snappy compressed WriteRequest; retry tells whether the error is transient
+func (rw *RemoteWriter) post(ctx context.Context, batch []byte) (retry bool, err error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, rw.URL, bytes.NewReader(snappy.Encode(nil, batch)))
+    if err != nil {
+        return false, err
+    }
+    req.Header.Set("Content-Type", "application/x-protobuf")
+    req.Header.Set("Content-Encoding", "snappy")
+    req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+    req.Header.Set("User-Agent", "temperature-exporter/"+Version)
+    if rw.User != "" {
+        req.SetBasicAuth(rw.User, rw.Password)
+    } else if rw.Token != "" {
+        req.Header.Set("Authorization", "Bearer "+rw.Token)
+    }
+    resp, err := rw.client.Do(req)
+    if err != nil {
+        return true, err
+    }
+    defer resp.Body.Close()
+    msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+    switch {
+    case resp.StatusCode/100 == 2:
+        rw.sent.Add(float64(countSamples(batch)))
+        return false, nil
+    case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5:
+        return true, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+    }
+    return false, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+// encodeWriteRequest serializes families as a prometheus.WriteRequest protobuf:
+//
+//    WriteRequest { repeated TimeSeries timeseries = 1; }
+//    TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//    Label        { string name = 1; string value = 2; }
+//    Sample       { double value = 1; int64 timestamp = 2; }
+//
+// Histograms and summaries are flattened into their _bucket, _sum and _count series.
+func encodeWriteRequest(families []*dto.MetricFamily, extra map[string]string, now time.Time) []byte {
+    ts := now.UnixMilli()
+    var buf []byte
+    for _, mf := range families {
+        for _, m := range mf.GetMetric() {
+            at := ts
+            if m.TimestampMs != nil {
+                at = m.GetTimestampMs()
+            }
+            for _, s := range flattenMetric(mf, m) {
+                labels := map[string]string{"__name__": s.name}
+                for k, v := range extra {
+                    if v != "" {
+                        labels[k] = v
+                    }
+                }
+                // an empty value is an absent label and remote write forbids sending it
+                for _, lp := range m.GetLabel() {
+                    if lp.GetValue() != "" {
+                        labels[lp.GetName()] = lp.GetValue()
+                    }
+                }
+                for k, v := range s.labels {
+                    labels[k] = v
+                }
+                buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+                buf = protowire.AppendBytes(buf, encodeTimeSeries(labels, s.value, at))
+            }
+        }
+    }
+    return buf
+}
+
+func encodeTimeSeries(labels map[string]string, value float64, ts int64) []byte {
+    names := make([]string, 0, len(labels))
+    for k := range labels {
+        names = append(names, k)
+    }
+    // remote_write receivers expect labels sorted by name
+    sort.Strings(names)
+    var b []byte
+    for _, k := range names {
+        var l []byte
+        l = protowire.AppendTag(l, 1, protowire.BytesType)
+        l = protowire.AppendString(l, k)
+        l = protowire.AppendTag(l, 2, protowire.BytesType)
+        l = protowire.AppendString(l, labels[k])
+        b = protowire.AppendTag(b, 1, protowire.BytesType)
+        b = protowire.AppendBytes(b, l)
+    }
+    var s []byte
+    s = protowire.AppendTag(s, 1, protowire.Fixed64Type)
+    s = protowire.AppendFixed64(s, math.Float64bits(value))
+    s = protowire.AppendTag(s, 2, protowire.VarintType)
+    s = protowire.AppendVarint(s, uint64(ts))
+    b = protowire.AppendTag(b, 2, protowire.BytesType)
+    return protowire.AppendBytes(b, s)
+}
+
+// countSamples counts the time series of an encoded WriteRequest, one sample each
+func countSamples(batch []byte) int {
+    n := 0
+    for len(batch) > 0 {
+        _, typ, l := protowire.ConsumeTag(batch)
+        if l < 0 {
+            break
+        }
+        batch = batch[l:]
+        l = protowire.ConsumeFieldValue(1, typ, batch)
+        if l < 0 {
+            break
+        }
+        batch = batch[l:]
+        n++
+    }
+    return n
+}
+
+// flatSample is one series of a metric, with the labels a histogram or summary adds
+type flatSample struct {
+    name   string
+    labels map[string]string
+    value  float64
+}
+
+func flattenMetric(mf *dto.MetricFamily, m *dto.Metric) []flatSample {
+    name := mf.GetName()
+    switch mf.GetType() {
+    case dto.MetricType_COUNTER:
+        return []flatSample{{name: name, value: m.GetCounter().GetValue()}}
+    case dto.MetricType_GAUGE:
+        return []flatSample{{name: name, value: m.GetGauge().GetValue()}}
+    case dto.MetricType_UNTYPED:
+        return []flatSample{{name: name, value: m.GetUntyped().GetValue()}}
+    case dto.MetricType_HISTOGRAM:
+        h := m.GetHistogram()
+        var out []flatSample
+        for _, b := range h.GetBucket() {
+            out = append(out, flatSample{name: name + "_bucket", labels: map[string]string{"le": formatFloat(b.GetUpperBound())}, value: float64(b.GetCumulativeCount())})
+        }
+        return append(out,
+            flatSample{name: name + "_bucket", labels: map[string]string{"le": "+Inf"}, value: float64(h.GetSampleCount())},
+            flatSample{name: name + "_sum", value: h.GetSampleSum()},
+            flatSample{name: name + "_count", value: float64(h.GetSampleCount())})
+    case dto.MetricType_SUMMARY:
+        sm := m.GetSummary()
+        var out []flatSample
+        for _, q := range sm.GetQuantile() {
+            out = append(out, flatSample{name: name, labels: map[string]string{"quantile": formatFloat(q.GetQuantile())}, value: q.GetValue()})
+        }
+        return append(out,
+            flatSample{name: name + "_sum", value: sm.GetSampleSum()},
+            flatSample{name: name + "_count", value: float64(sm.GetSampleCount())})
+    }
+    return nil
+}
+
+func formatFloat(f float64) string {
+    return strconv.FormatFloat(f, 'g', -1, 64)
+}
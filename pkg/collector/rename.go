@@ -0,0 +1,113 @@
+package collector
+
+import (
+    "encoding/csv"
+    "fmt"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// renameRule rewrites the chip, sensor and label of readings matching all three expressions.
+// An empty replacement keeps the original value; replacements may use $1 or ${name} from the
+// expression of the same column.
+type renameRule struct {
+    match   [3]*regexp.Regexp // chip, sensor, label
+    replace [3]string
+}
+
+var renameColumns = [3]string{"chip", "sensor", "label"}
+
+// templateRefRe finds capture references the way regexp.Expand reads them ($$ is a literal $)
+var templateRefRe = regexp.MustCompile(`\$(\$|\{(\w+)\}|(\w+))`)
+
+// loadRenameRules reads a "chip_regex,sensor_regex,label_regex,chip,sensor,label" CSV.
+// Regexes are anchored, an empty one matches anything, and lines starting with # are comments.
+func loadRenameRules(path string) ([]renameRule, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    r := csv.NewReader(f)
+    r.Comment = '#'
+    r.FieldsPerRecord = 6
+    records, err := r.ReadAll()
+    if err != nil {
+        return nil, err
+    }
+    var rules []renameRule
+    for i, rec := range records {
+        var rule renameRule
+        for col := range renameColumns {
+            expr := strings.TrimSpace(rec[col])
+            if expr == "" {
+                expr = ".*"
+            }
+            re, err := regexp.Compile("^(?:" + expr + ")$")
+            if err != nil {
+                return nil, fmt.Errorf("%s: ligne %d: %s: %v", path, i+1, renameColumns[col], err)
+            }
+            repl := strings.TrimSpace(rec[col+3])
+            if err := checkTemplateRefs(re, repl); err != nil {
+                return nil, fmt.Errorf("%s: ligne %d: %s: %v", path, i+1, renameColumns[col], err)
+            }
+            rule.match[col], rule.replace[col] = re, repl
+        }
+        rules = append(rules, rule)
+    }
+    return rules, nil
+}
+
+// checkTemplateRefs rejects $N or ${name} references to groups the expression does not have,
+// which regexp.Expand would otherwise silently replace with an empty string.
+func checkTemplateRefs(re *regexp.Regexp, tmpl string) error {
+    for _, m := range templateRefRe.FindAllStringSubmatch(tmpl, -1) {
+        ref := m[2] + m[3]
+        if ref == "" {
+            continue // $$
+        }
+        if n, err := strconv.Atoi(ref); err == nil {
+            if n > re.NumSubexp() {
+                return fmt.Errorf("référence $%s: seulement %d groupe(s) de capture", ref, re.NumSubexp())
+            }
+            continue
+        }
+        if re.SubexpIndex(ref) < 0 {
+            return fmt.Errorf("référence ${%s}: groupe nommé inconnu", ref)
+        }
+    }
+    return nil
+}
+
+// rename applies the first matching rule to every reading.
+func rename(readings []reading, rules []renameRule) []reading {
+    if len(rules) == 0 {
+        return readings
+    }
+    for i, r := range readings {
+        values := [3]string{r.chip, r.name, r.label}
+        for _, rule := range rules {
+            var matches [3][]int
+            ok := true
+            for col, re := range rule.match {
+                if matches[col] = re.FindStringSubmatchIndex(values[col]); matches[col] == nil {
+                    ok = false
+                    break
+                }
+            }
+            if !ok {
+                continue
+            }
+            for col, repl := range rule.replace {
+                if repl != "" {
+                    values[col] = string(rule.match[col].ExpandString(nil, repl, values[col], matches[col]))
+                }
+            }
+            break
+        }
+        readings[i].chip, readings[i].name, readings[i].label = values[0], values[1], values[2]
+    }
+    return readings
+}
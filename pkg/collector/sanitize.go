@@ -0,0 +1,79 @@
+package collector
+
+import (
+    "log/slog"
+    "strings"
+    "sync"
+    "unicode"
+    "unicode/utf8"
+)
+
+// maxLabelLen caps label values taken from sensor files and command output, in runes
+const maxLabelLen = 128
+
+// sanitizedLogged remembers the raw values already reported so each mapping is logged once
+var sanitizedLogged sync.Map
+
+// sanitizeReadings cleans the chip, sensor, label and adapter of every reading in place. Files
+// and drivers sometimes return trailing tabs, embedded newlines or invalid UTF-8, which would
+// otherwise give odd or duplicate-looking series.
+func sanitizeReadings(readings []reading) {
+    for i := range readings {
+        r := &readings[i]
+        for _, f := range []*string{&r.chip, &r.name, &r.label, &r.adapter} {
+            if clean := sanitizeLabel(*f); clean != *f {
+                if _, seen := sanitizedLogged.LoadOrStore(*f, struct{}{}); !seen {
+                    slog.Debug("valeur de label nettoyée", "source", r.source, "raw", *f, "clean", clean)
+                }
+                *f = clean
+            }
+        }
+    }
+}
+
+// sanitizeLabel trims whitespace, collapses runs of whitespace and control characters into one
+// space, replaces invalid UTF-8 with U+FFFD and truncates to maxLabelLen runes
+func sanitizeLabel(s string) string {
+    if labelClean(s) {
+        return s
+    }
+    s = strings.ToValidUTF8(s, "\uFFFD")
+    var b strings.Builder
+    pending, n := false, 0
+    for _, r := range s {
+        if unicode.IsSpace(r) || unicode.IsControl(r) {
+            pending = b.Len() > 0
+            continue
+        }
+        if n >= maxLabelLen {
+            break
+        }
+        if pending {
+            if n+1 >= maxLabelLen {
+                break
+            }
+            b.WriteByte(' ')
+            n++
+            pending = false
+        }
+        b.WriteRune(r)
+        n++
+    }
+    return b.String()
+}
+
+// labelClean reports whether s needs no change, the common case, without allocating
+func labelClean(s string) bool {
+    if !utf8.ValidString(s) || utf8.RuneCountInString(s) > maxLabelLen {
+        return false
+    }
+    prevSpace := true // a leading space is not clean either
+    for _, r := range s {
+        space := unicode.IsSpace(r)
+        if (space && (r != ' ' || prevSpace)) || unicode.IsControl(r) {
+            return false
+        }
+        prevSpace = space
+    }
+    return !prevSpace || s == ""
+}
@@ -0,0 +1,95 @@
+package collector
+
+import (
+    "reflect"
+    "sort"
+    "strings"
+    "testing"
+)
+
+func TestSanitizeLabel(t *testing.T) {
+    tests := []struct {
+        name, in, want string
+    }{
+        {"clean", "Package id 0", "Package id 0"},
+        {"empty", "", ""},
+        {"trailing tab", "Tctl\t", "Tctl"},
+        {"surrounding spaces", "  Tccd1 ", "Tccd1"},
+        {"embedded newline", "CPU\nTIN", "CPU TIN"},
+        {"whitespace run", "SYS \t\r\n TIN", "SYS TIN"},
+        {"control characters", "Sys\x00\x01tem", "Sys tem"},
+        {"only control characters", "\x00\t\n", ""},
+        {"invalid utf-8", "\xffSYSTIN\xfe", "�SYSTIN�"},
+        {"utf-8 kept", "Température °C", "Température °C"},
+        {"too long", strings.Repeat("x", 200), strings.Repeat("x", maxLabelLen)},
+        {"too long multibyte", strings.Repeat("é", 200), strings.Repeat("é", maxLabelLen)},
+        // no trailing space left when the cap falls on a collapsed run
+        {"cap on a space", strings.Repeat("x", maxLabelLen-1) + "\t\ty", strings.Repeat("x", maxLabelLen-1)},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := sanitizeLabel(tt.in)
+            if got != tt.want {
+                t.Errorf("sanitizeLabel(%q) = %q, want %q", tt.in, got, tt.want)
+            }
+            // cleaning is idempotent, so the series stays the same from one scrape to the next
+            if again := sanitizeLabel(got); again != got {
+                t.Errorf("sanitizeLabel(%q) = %q, not stable", got, again)
+            }
+            if !labelClean(got) {
+                t.Errorf("labelClean(%q) = false for a sanitized value", got)
+            }
+        })
+    }
+}
+
+// series returns "chip|sensor|label" of the temperature_celsius series c exports, sorted
+func series(t *testing.T, c *Collector) []string {
+    t.Helper()
+    var got []string
+    for _, m := range gather(t, c, "temperature_celsius") {
+        labels := map[string]string{}
+        for _, l := range m.GetLabel() {
+            labels[l.GetName()] = l.GetValue()
+        }
+        got = append(got, labels["chip"]+"|"+labels["sensor"]+"|"+labels["label"])
+    }
+    sort.Strings(got)
+    return got
+}
+
+// TestHostileHwmonLabels discovers chips whose name and label files hold what broken drivers
+// write; the pedantic registry used by gather rejects invalid UTF-8, so a scrape succeeding at
+// all already proves part of the cleaning
+func TestHostileHwmonLabels(t *testing.T) {
+    long := strings.Repeat("n", 200)
+    hwmon := writeTree(t, map[string]string{
+        "hwmon0/name":        "k10temp\t\n",
+        "hwmon0/temp1_input": "45000\n",
+        "hwmon0/temp1_label": "Tctl\t\n",
+        "hwmon1/name":        "nct6798\n",
+        "hwmon1/temp1_input": "31000\n",
+        "hwmon1/temp1_label": "\xffSYS\x01\x02TIN\n",
+        "hwmon1/temp2_input": "40000\n",
+        "hwmon1/temp2_label": "CPU \t TIN\n",
+        "hwmon2/name":        long + "\n",
+        "hwmon2/temp1_input": "38000\n",
+    })
+    c, err := New(Options{EnableHwmon: true, HwmonPaths: []string{hwmon}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    clipped := long[:maxLabelLen]
+    want := []string{
+        "k10temp|k10temp|Tctl",
+        clipped + "|" + clipped + "|",
+        "nct6798|nct6798|CPU TIN",
+        "nct6798|nct6798|�SYS TIN",
+    }
+    sort.Strings(want)
+    for scrape := 1; scrape <= 2; scrape++ {
+        if got := series(t, c); !reflect.DeepEqual(got, want) {
+            t.Errorf("scrape %d series =\n  %q\nwant\n  %q", scrape, got, want)
+        }
+    }
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package collector
+
+import (
+    "reflect"
+    "testing"
+    "time"
+)
+
+// TestHostileSensorsCliLabels feeds sensors -j sections and labels with the newlines, tabs and
+// control characters a chip config or driver can produce
+func TestHostileSensorsCliLabels(t *testing.T) {
+    sensors := fakeSensorsOutput(t, []byte(`{
+   "nct6798-isa-0290":{
+      "Adapter": "ISA\tadapter",
+      "CPU\nTIN":{
+         "temp2_input": 40.500
+      },
+      " SYSTIN\t":{
+         "temp1_input": 31.000,
+         "temp1_label": "Sys\u0000tem\r\n"
+      }
+   }
+}`))
+    c, err := New(Options{EnableSensorsCli: true, SensorsCliPath: sensors, SensorsCliFormat: "json", SensorsTimeout: 5 * time.Second})
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []string{"nct6798-isa-0290|CPU TIN|", "nct6798-isa-0290|SYSTIN|Sys tem"}
+    for scrape := 1; scrape <= 2; scrape++ {
+        if got := series(t, c); !reflect.DeepEqual(got, want) {
+            t.Errorf("scrape %d series = %q, want %q", scrape, got, want)
+        }
+    }
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrapeTimeoutOffset is taken off a scrape's timeout so the response, a 503 at worst, is
+// written before the scraper or -write-timeout closes the connection
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
+// errScrapeTimeout is the cause of a scrape context whose deadline expired
+var errScrapeTimeout = errors.New("délai du scrape dépassé")
+
+// ScrapeHandler serves /metrics. Every scrape gets a registry holding a view of the collector
+// bound to its own deadline, restricted to the collect[] sources when given, next to the
+// exporter's own metrics (own). promhttp's MaxRequestsInFlight only counts the requests of one
+// handler, so the maxRequests limit is shared here instead; 0 disables it.
+func (c *Collector) ScrapeHandler(own prometheus.Gatherer, labels prometheus.Labels, maxRequests int, writeTimeout time.Duration, rejected *prometheus.CounterVec) http.Handler {
+    var slots chan struct{}
+    if maxRequests > 0 {
+        slots = make(chan struct{}, maxRequests)
+    }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        selection, err := c.sourceSelection(r)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if slots != nil {
+            select {
+            case slots <- struct{}{}:
+                defer func() { <-slots }()
+            default:
+                rejected.WithLabelValues("limit").Inc()
+                http.Error(w, fmt.Sprintf("trop de scrapes simultanés (-max-requests %d)", maxRequests), http.StatusServiceUnavailable)
+                return
+            }
+        }
+
+        timeout := scrapeTimeout(r, writeTimeout)
+        ctx, cancel := c.scrapeContext(timeout)
+        defer cancel()
+        g, err := c.scrapeGatherer(ctx, selection, labels, own)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        promhttp.HandlerFor(g, promhttp.HandlerOpts{Timeout: timeout}).ServeHTTP(w, r)
+        // ctx.Err() may lag behind the 503 of the timeout handler, the deadline itself does not
+        if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+            rejected.WithLabelValues("timeout").Inc()
+        }
+    })
+}
+
+// scrapeContext returns the context of one scrape, ended by Stop and, when timeout is not 0,
+// by errScrapeTimeout once it elapsed
+func (c *Collector) scrapeContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+    if timeout > 0 {
+        return context.WithTimeoutCause(c.ctx, timeout, errScrapeTimeout)
+    }
+    return context.WithCancel(c.ctx)
+}
+
+// scrapeGatherer gathers a view of the collector bound to ctx, with labels added, next to own
+func (c *Collector) scrapeGatherer(ctx context.Context, selection map[string]bool, labels prometheus.Labels, own prometheus.Gatherer) (prometheus.Gatherer, error) {
+    reg := prometheus.NewRegistry()
+    if err := prometheus.WrapRegistererWith(labels, reg).Register(scrapeView{c, ctx, selection}); err != nil {
+        return nil, err
+    }
+    return prometheus.Gatherers{reg, own}, nil
+}
+
+// scrapeTimeout returns how long a scrape may run: the X-Prometheus-Scrape-Timeout-Seconds header
+// Prometheus sends, capped by -write-timeout, minus scrapeTimeoutOffset; 0 means no deadline
+func scrapeTimeout(r *http.Request, writeTimeout time.Duration) time.Duration {
+    timeout := writeTimeout
+    if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+        if s, err := strconv.ParseFloat(v, 64); err == nil && s > 0 {
+            if d := time.Duration(s * float64(time.Second)); timeout <= 0 || d < timeout {
+                timeout = d
+            }
+        }
+    }
+    if timeout <= 0 {
+        return 0
+    }
+    return timeout - min(scrapeTimeoutOffset, timeout/2)
+}
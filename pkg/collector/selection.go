@@ -0,0 +1,26 @@
+package collector
+
+import (
+    "fmt"
+    "net/http"
+    "slices"
+    "strings"
+)
+
+// sourceSelection reads the collect[] parameters of a scrape, node_exporter style: nil when there
+// are none, otherwise the set of named sources, which must all be enabled
+func (c *Collector) sourceSelection(r *http.Request) (map[string]bool, error) {
+    names := r.URL.Query()["collect[]"]
+    if len(names) == 0 {
+        return nil, nil
+    }
+    valid := c.sourceNames()
+    selection := make(map[string]bool, len(names))
+    for _, n := range names {
+        if !slices.Contains(valid, n) {
+            return nil, fmt.Errorf("collect[]: source %q inconnue ou désactivée (valides: %s)", n, strings.Join(valid, ", "))
+        }
+        selection[n] = true
+    }
+    return selection, nil
+}
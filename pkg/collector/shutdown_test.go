@@ -0,0 +1,149 @@
+//go:build unix
+
+package collector
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "syscall"
+    "testing"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+)
+
+// TestShutdownKillsSlowSource scrapes a sensors command that hangs, behind a wrapper script
+// like the ones sudo or ssh setups use, then shuts down: the collection must return well
+// before the command's own timeout and leave no process behind.
+func TestShutdownKillsSlowSource(t *testing.T) {
+    dir := t.TempDir()
+    pidFile := filepath.Join(dir, "sleep.pid")
+    script := filepath.Join(dir, "sensors")
+    body := "#!/bin/sh\nsleep 60 &\necho $! > " + pidFile + "\nwait\n"
+    if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+        t.Fatal(err)
+    }
+    c, err := New(Options{EnableSensorsCli: true, SensorsCliPath: script, SensorsCliFormat: "json", SensorsTimeout: time.Minute})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        ch := make(chan prometheus.Metric)
+        go func() {
+            for range ch {
+            }
+        }()
+        c.Collect(ch)
+        close(ch)
+    }()
+    pid := waitForPID(t, pidFile)
+
+    c.Stop()
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    if err := c.Wait(ctx); err != nil {
+        t.Fatalf("Wait() = %v, the collection outlived the shutdown deadline", err)
+    }
+    select {
+    case <-done:
+    case <-ctx.Done():
+        t.Fatal("Collect did not return after shutdown")
+    }
+    for !processGone(pid) {
+        if ctx.Err() != nil {
+            t.Fatalf("process %d started by the sensors wrapper survived the shutdown", pid)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
+
+// waitForPID returns the pid the fake command wrote to path
+func waitForPID(t *testing.T, path string) int {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for time.Now().Before(deadline) {
+        if b, err := os.ReadFile(path); err == nil && strings.HasSuffix(string(b), "\n") {
+            pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+            if err != nil {
+                t.Fatal(err)
+            }
+            return pid
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    t.Fatal("the fake sensors command never started")
+    return 0
+}
+
+// processGone reports whether pid no longer runs. Without an init reaping orphans (containers)
+// a killed process may linger as a zombie, which counts as gone.
+func processGone(pid int) bool {
+    if err := syscall.Kill(pid, 0); err != nil {
+        return true
+    }
+    stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+    if err != nil {
+        return false
+    }
+    // pid (comm) state ...
+    i := strings.LastIndexByte(string(stat), ')')
+    return i >= 0 && i+2 < len(stat) && stat[i+2] == 'Z'
+}
+
+// TestRunningForStuckGather queues scrapes behind a gather that hangs: RunningFor must keep
+// counting from the stuck gather's start so the watchdog fires, not from the last scrape
+func TestRunningForStuckGather(t *testing.T) {
+    dir := t.TempDir()
+    pidFile := filepath.Join(dir, "sleep.pid")
+    script := filepath.Join(dir, "sensors")
+    if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 60 &\necho $! > "+pidFile+"\nwait\n"), 0o755); err != nil {
+        t.Fatal(err)
+    }
+    c, err := New(Options{EnableSensorsCli: true, SensorsCliPath: script, SensorsCliFormat: "json", SensorsTimeout: time.Minute})
+    if err != nil {
+        t.Fatal(err)
+    }
+    scrape := func() {
+        ch := make(chan prometheus.Metric)
+        go func() {
+            for range ch {
+            }
+        }()
+        c.Collect(ch)
+        close(ch)
+    }
+    go scrape()
+    waitForPID(t, pidFile)
+    stuckSince := time.Now()
+
+    // scrapes arriving and giving up while the gather hangs
+    for i := 0; i < 3; i++ {
+        time.Sleep(100 * time.Millisecond)
+        v, cancel := c.scrapeContext(10 * time.Millisecond)
+        go func() {
+            defer cancel()
+            ch := make(chan prometheus.Metric, 1000)
+            scrapeView{c, v, nil}.Collect(ch)
+        }()
+    }
+    time.Sleep(50 * time.Millisecond)
+    if running, stuck := c.RunningFor(), time.Since(stuckSince); running < stuck {
+        t.Errorf("RunningFor() = %v with a gather stuck for %v", running, stuck)
+    }
+
+    c.Stop()
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    if err := c.Wait(ctx); err != nil {
+        t.Fatal(err)
+    }
+    if running := c.RunningFor(); running != 0 {
+        t.Errorf("RunningFor() = %v once every collection returned, want 0", running)
+    }
+}
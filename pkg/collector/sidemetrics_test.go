@@ -0,0 +1,74 @@
+package collector
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// onlyValue returns the value of the only series of family name
+func onlyValue(t *testing.T, c *Collector, name string) float64 {
+    t.Helper()
+    metrics := gather(t, c, name)
+    if len(metrics) != 1 {
+        t.Fatalf("%s has %d series, want 1", name, len(metrics))
+    }
+    return metrics[0].GetGauge().GetValue() + metrics[0].GetCounter().GetValue()
+}
+
+// TestSideMetricsServedFromSnapshot changes the RAPL and intrusion files between scrapes: with
+// -collect-interval, scrapes serve what the last background collection read, like the readings
+func TestSideMetricsServedFromSnapshot(t *testing.T) {
+    hwmon := writeTree(t, map[string]string{
+        "hwmon0/name":             "nct6798\n",
+        "hwmon0/temp1_input":      "31000\n",
+        "hwmon0/intrusion0_alarm": "0\n",
+    })
+    powercap := writeTree(t, map[string]string{
+        "intel-rapl:0/name":                "package-0\n",
+        "intel-rapl:0/energy_uj":           "1000000\n",
+        "intel-rapl:0/max_energy_range_uj": "262143328850\n",
+    })
+    c, err := New(Options{
+        EnableHwmon:     true,
+        HwmonPaths:      []string{hwmon},
+        EnableRapl:      true,
+        RaplPath:        powercap,
+        CollectInterval: time.Hour,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(c.Stop)
+    c.Start()
+    write := func(dir, path, content string) {
+        t.Helper()
+        if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0o644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    write(powercap, "intel-rapl:0/energy_uj", "3000000\n")
+    write(hwmon, "hwmon0/intrusion0_alarm", "1\n")
+    for scrape := 1; scrape <= 2; scrape++ {
+        if v := onlyValue(t, c, "rapl_energy_joules_total"); v != 1 {
+            t.Errorf("scrape %d: rapl_energy_joules_total = %v, want 1 from the snapshot", scrape, v)
+        }
+        if v := onlyValue(t, c, "intrusion_alarm"); v != 0 {
+            t.Errorf("scrape %d: intrusion_alarm = %v, want 0 from the snapshot", scrape, v)
+        }
+    }
+    c.refresh()
+    if v := onlyValue(t, c, "rapl_energy_joules_total"); v != 3 {
+        t.Errorf("rapl_energy_joules_total after a collection = %v, want 3", v)
+    }
+    if v := onlyValue(t, c, "intrusion_alarm"); v != 1 {
+        t.Errorf("intrusion_alarm after a collection = %v, want 1", v)
+    }
+    // rapl is a source of its own in the collection stats
+    for _, m := range gather(t, c, "collector_success") {
+        if m.GetLabel()[0].GetValue() == "rapl" && m.GetGauge().GetValue() != 1 {
+            t.Errorf("collector_success{source=\"rapl\"} = %v, want 1", m.GetGauge().GetValue())
+        }
+    }
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+    "context"
+    "time"
+)
+
+// snapshot is the result of one background collection. It is never modified once stored, so
+// scrapes reading it concurrently always see a complete set.
+type snapshot struct {
+    readings []reading
+    stats    []sourceStats
+    at       time.Time
+}
+
+// refresh runs the gather pipeline and publishes its result as the current snapshot
+func (c *Collector) refresh() {
+    c.inflight.Add(1)
+    defer c.inflight.Done()
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    readings, stats := c.pipeline(c.ctx, nil)
+    s := &snapshot{readings: readings, stats: stats, at: time.Now()}
+    c.snapshot.Store(s)
+    c.record(s)
+}
+
+// collectLoop refreshes the snapshot every interval until ctx is cancelled. The first
+// collection is done by the caller so /metrics never serves an empty snapshot.
+func (c *Collector) collectLoop(ctx context.Context, interval time.Duration) {
+    t := time.NewTicker(interval)
+    defer t.Stop()
+    for {
+        select {
+        case <-t.C:
+            c.refresh()
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// latest returns the readings scrapes and push targets work on: the snapshot with
+// -collect-interval, a gather of their own otherwise (shared with concurrent scrapes),
+// without the readings older than -stale-after.
+func (c *Collector) latest(ctx context.Context, since time.Time) ([]reading, []sourceStats) {
+    if s := c.snapshot.Load(); s != nil {
+        return c.dropStale(s.readings, time.Now()), s.stats
+    }
+    readings, stats := c.readings(ctx, since)
+    return c.dropStale(readings, time.Now()), stats
+}
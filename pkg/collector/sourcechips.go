@@ -0,0 +1,114 @@
+package collector
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// sourceChips holds the allow-lists of -hwmon-chips, -thermal-zones and -sensors-cli-chips by
+// source. A source without a list keeps all its chips; a nil sourceChips keeps everything.
+type sourceChips map[string]*chipAllowList
+
+// chipAllowList keeps the chips of one source that match one of its patterns
+type chipAllowList struct {
+    flag     string
+    patterns []*regexp.Regexp
+}
+
+// newSourceChips compiles the patterns of the three flags, see compileChipPattern
+func newSourceChips(hwmon, thermal, sensorsCli []string) (sourceChips, error) {
+    sc := sourceChips{}
+    for _, l := range []struct {
+        source, flag string
+        patterns     []string
+    }{
+        {"hwmon", "hwmon-chips", hwmon},
+        {"thermal", "thermal-zones", thermal},
+        {"sensors-cli", "sensors-cli-chips", sensorsCli},
+    } {
+        if len(l.patterns) == 0 {
+            continue
+        }
+        list := &chipAllowList{flag: l.flag}
+        for _, p := range l.patterns {
+            re, err := compileChipPattern(p)
+            if err != nil {
+                return nil, fmt.Errorf("-%s: %v", l.flag, err)
+            }
+            list.patterns = append(list.patterns, re)
+        }
+        sc[l.source] = list
+    }
+    return sc, nil
+}
+
+// compileChipPattern turns a glob (nvme*, coretemp-isa-000?) into an anchored expression. A
+// pattern between slashes (/^nvme-pci-0[12]/) is taken as an RE2 expression instead.
+func compileChipPattern(p string) (*regexp.Regexp, error) {
+    if len(p) >= 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+        return regexp.Compile(p[1 : len(p)-1])
+    }
+    var b strings.Builder
+    b.WriteString("^")
+    for _, r := range p {
+        switch r {
+        case '*':
+            b.WriteString(".*")
+        case '?':
+            b.WriteString(".")
+        default:
+            b.WriteString(regexp.QuoteMeta(string(r)))
+        }
+    }
+    b.WriteString("$")
+    return regexp.Compile(b.String())
+}
+
+// excludedBy returns the flag whose allow-list leaves the sensor out, "" when it is kept.
+// Thermal zones are matched on their type (x86_pkg_temp) or name (thermal_zone0), the other
+// sources on the chip.
+func (sc sourceChips) excludedBy(source, chip, name, label string) string {
+    list := sc[source]
+    if list == nil {
+        return ""
+    }
+    values := []string{chip}
+    if source == "thermal" {
+        values = []string{name, label}
+    }
+    if matchAny(list.patterns, values...) {
+        return ""
+    }
+    return list.flag
+}
+
+// filterSensors drops the discovered sensors of the chips left out, so they are never read
+func (sc sourceChips) filterSensors(source string, sensors []sources.Sensor) []sources.Sensor {
+    if sc[source] == nil {
+        return sensors
+    }
+    var kept []sources.Sensor
+    for _, s := range sensors {
+        if sc.excludedBy(source, s.Chip, s.Name, s.Label) == "" {
+            kept = append(kept, s)
+        }
+    }
+    return kept
+}
+
+// filterReadings is the equivalent for command based sources, which return values directly
+func (sc sourceChips) filterReadings(source string, readings []reading) []reading {
+    if sc[source] == nil {
+        return readings
+    }
+    var kept []reading
+    for _, r := range readings {
+        if sc.excludedBy(source, r.chip, r.name, r.label) == "" {
+            kept = append(kept, r)
+        }
+    }
+    return kept
+}
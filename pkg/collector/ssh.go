@@ -0,0 +1,193 @@
+package collector
+
+import (
+    "cmp"
+    "context"
+    "fmt"
+    "log/slog"
+    "net"
+    "os"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "golang.org/x/crypto/ssh"
+    "golang.org/x/crypto/ssh/knownhosts"
+    "gopkg.in/yaml.v2"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// defaultSSHTimeout bounds the collection of a target that does not set its own timeout
+const defaultSSHTimeout = 5 * time.Second
+
+// sshTargetsFile is the YAML of -ssh-targets-file. The top-level user, key_file, known_hosts
+// and timeout apply to the targets that leave them out.
+type sshTargetsFile struct {
+    sshTargetConfig `yaml:",inline"`
+    Targets         []sshTargetConfig `yaml:"targets"`
+}
+
+type sshTargetConfig struct {
+    Name                  string        `yaml:"name"`    // target label, the host of address by default
+    Address               string        `yaml:"address"` // host or host:port, port 22 by default
+    User                  string        `yaml:"user"`
+    KeyFile               string        `yaml:"key_file"`
+    KnownHosts            string        `yaml:"known_hosts"`
+    InsecureIgnoreHostKey bool          `yaml:"insecure_ignore_host_key"`
+    Mode                  string        `yaml:"mode"` // hwmon or sensors
+    HwmonPath             string        `yaml:"hwmon_path"`
+    SensorsPath           string        `yaml:"sensors_path"`
+    Timeout               time.Duration `yaml:"timeout"`
+}
+
+// sshTarget is one remote host with its own deadline
+type sshTarget struct {
+    *sources.SSH
+    timeout time.Duration
+    // down is set while the target fails, so only state changes are logged
+    down atomic.Bool
+}
+
+// targetStats records how one remote target fared during a gather
+type targetStats struct {
+    target   string
+    success  bool
+    duration time.Duration
+}
+
+// loadSSHTargets reads the targets of -ssh-targets-file. Keys and known_hosts files are read
+// here so a typo fails at startup rather than on every collection.
+func loadSSHTargets(path string) ([]*sshTarget, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var file sshTargetsFile
+    if err := yaml.UnmarshalStrict(data, &file); err != nil {
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+    if len(file.Targets) == 0 {
+        return nil, fmt.Errorf("%s: aucune cible (targets)", path)
+    }
+    def := file.sshTargetConfig
+    signers := make(map[string]ssh.Signer)
+    hostKeys := make(map[string]ssh.HostKeyCallback)
+    seen := make(map[string]bool)
+    var targets []*sshTarget
+    for i, t := range file.Targets {
+        where := fmt.Sprintf("%s: cible %d", path, i+1)
+        if t.Address == "" {
+            return nil, fmt.Errorf("%s: address manquant", where)
+        }
+        if _, _, err := net.SplitHostPort(t.Address); err != nil {
+            t.Address = net.JoinHostPort(t.Address, "22")
+        }
+        if t.Name == "" {
+            t.Name, _, _ = net.SplitHostPort(t.Address)
+        }
+        if seen[t.Name] {
+            return nil, fmt.Errorf("%s: nom %q déjà utilisé", where, t.Name)
+        }
+        seen[t.Name] = true
+        t.User = cmp.Or(t.User, def.User)
+        t.KeyFile = cmp.Or(t.KeyFile, def.KeyFile)
+        t.KnownHosts = cmp.Or(t.KnownHosts, def.KnownHosts)
+        t.InsecureIgnoreHostKey = t.InsecureIgnoreHostKey || def.InsecureIgnoreHostKey
+        t.Timeout = cmp.Or(t.Timeout, def.Timeout, defaultSSHTimeout)
+        switch t.Mode = cmp.Or(t.Mode, def.Mode, "hwmon"); t.Mode {
+        case "hwmon", "sensors":
+        default:
+            return nil, fmt.Errorf("%s: mode %q inconnu (attendu: hwmon ou sensors)", where, t.Mode)
+        }
+        if t.User == "" || t.KeyFile == "" {
+            return nil, fmt.Errorf("%s: user et key_file sont requis", where)
+        }
+        signer, ok := signers[t.KeyFile]
+        if !ok {
+            key, err := os.ReadFile(t.KeyFile)
+            if err != nil {
+                return nil, fmt.Errorf("%s: %v", where, err)
+            }
+            if signer, err = ssh.ParsePrivateKey(key); err != nil {
+                return nil, fmt.Errorf("%s: %s: %v", where, t.KeyFile, err)
+            }
+            signers[t.KeyFile] = signer
+        }
+        var hostKey ssh.HostKeyCallback
+        switch {
+        case t.KnownHosts != "":
+            if hostKey, ok = hostKeys[t.KnownHosts]; !ok {
+                if hostKey, err = knownhosts.New(t.KnownHosts); err != nil {
+                    return nil, fmt.Errorf("%s: %v", where, err)
+                }
+                hostKeys[t.KnownHosts] = hostKey
+            }
+        case t.InsecureIgnoreHostKey:
+            slog.Warn("ssh: clé d'hôte non vérifiée", "target", t.Name)
+            hostKey = ssh.InsecureIgnoreHostKey()
+        default:
+            return nil, fmt.Errorf("%s: known_hosts requis (ou insecure_ignore_host_key: true)", where)
+        }
+        targets = append(targets, &sshTarget{
+            SSH: &sources.SSH{
+                Target:      t.Name,
+                Address:     t.Address,
+                User:        t.User,
+                Signer:      signer,
+                HostKey:     hostKey,
+                Mode:        t.Mode,
+                HwmonPath:   t.HwmonPath,
+                SensorsPath: t.SensorsPath,
+            },
+            timeout: t.Timeout,
+        })
+    }
+    return targets, nil
+}
+
+// gatherSSH collects every target in parallel, each under its own timeout, so a dead host
+// costs its timeout once instead of delaying the others. Read failures of a target that
+// answered are recorded per sensor like the local sources.
+func (c *Collector) gatherSSH(ctx context.Context, rules *ruleSet) ([]reading, []targetStats, error) {
+    var wg sync.WaitGroup
+    results := make([][]reading, len(c.ssh))
+    stats := make([]targetStats, len(c.ssh))
+    for i, t := range c.ssh {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            start := time.Now()
+            tctx, cancel := sourceContext(ctx, t.timeout)
+            defer cancel()
+            srs, err := t.Readings(tctx)
+            failures, err := sources.SplitErrors(err)
+            stats[i] = targetStats{target: t.Target, success: err == nil, duration: time.Since(start)}
+            c.recordFailures("ssh", failures)
+            if err != nil {
+                c.sshErrors.WithLabelValues(t.Target).Inc()
+                if !t.down.Swap(true) {
+                    slog.Warn("ssh: collecte impossible", "target", t.Target, "address", t.Address, "err", err)
+                }
+                return
+            }
+            if t.down.Swap(false) {
+                slog.Info("ssh: cible de nouveau joignable", "target", t.Target)
+            }
+            results[i] = rules.blocklist.filterReadings("ssh", fromSources(srs))
+        }()
+    }
+    wg.Wait()
+    var readings []reading
+    failed := 0
+    for i, st := range stats {
+        readings = append(readings, results[i]...)
+        if !st.success {
+            failed++
+        }
+    }
+    if failed > 0 {
+        return readings, stats, fmt.Errorf("%d cible(s) SSH sur %d en échec", failed, len(c.ssh))
+    }
+    return readings, stats, nil
+}
@@ -0,0 +1,50 @@
+package collector
+
+import (
+    "time"
+)
+
+// autoStaleAfter is the -stale-after default: three background collections, and never less than
+// the IPMI or storcli cache plus one collection, whose readings are legitimately that old.
+// Without -collect-interval every scrape reads afresh and nothing expires.
+func autoStaleAfter(cfg Options) time.Duration {
+    if cfg.CollectInterval <= 0 {
+        return 0
+    }
+    d := 3 * cfg.CollectInterval
+    if cfg.EnableIPMI {
+        d = max(d, cfg.IPMICache+cfg.CollectInterval)
+    }
+    if cfg.EnableStorcli {
+        d = max(d, cfg.StorcliCache+cfg.CollectInterval)
+    }
+    return d
+}
+
+// dropStale removes the readings older than -stale-after, so a snapshot the background loop
+// stopped refreshing, or a cache kept past its time, shows a gap rather than frozen values.
+// readings is shared with other scrapes and left untouched.
+func (c *Collector) dropStale(readings []reading, now time.Time) []reading {
+    if c.StaleAfter <= 0 {
+        return readings
+    }
+    var out []reading
+    dropped := false
+    for i, r := range readings {
+        if now.Sub(r.at) <= c.StaleAfter {
+            if dropped {
+                out = append(out, r)
+            }
+            continue
+        }
+        if !dropped {
+            out = append(make([]reading, 0, len(readings)), readings[:i]...)
+            dropped = true
+        }
+        c.stale.WithLabelValues(r.chip).Inc()
+    }
+    if !dropped {
+        return readings
+    }
+    return out
+}
@@ -0,0 +1,95 @@
+package collector
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "testing"
+    "time"
+)
+
+// chips returns the chip label of the temperature_celsius series c exports, sorted
+func chips(t *testing.T, c *Collector) []string {
+    t.Helper()
+    var got []string
+    for _, m := range gather(t, c, "temperature_celsius") {
+        for _, l := range m.GetLabel() {
+            if l.GetName() == "chip" {
+                got = append(got, l.GetValue())
+            }
+        }
+    }
+    sort.Strings(got)
+    return got
+}
+
+// TestRemovedSensorDisappears removes the nvme input between two collections: whether scrapes
+// gather themselves, reuse the discovery cache or serve the background snapshot, its series
+// must go rather than keep the last value
+func TestRemovedSensorDisappears(t *testing.T) {
+    tests := []struct {
+        name string
+        opts Options
+    }{
+        {"scrape", Options{}},
+        {"discovery cache", Options{HwmonCache: time.Hour}},
+        {"background collection", Options{CollectInterval: time.Hour}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            hwmon := writeTree(t, fakeHwmonFiles)
+            opts := tt.opts
+            opts.EnableHwmon, opts.HwmonPaths = true, []string{hwmon}
+            c, err := New(opts)
+            if err != nil {
+                t.Fatal(err)
+            }
+            t.Cleanup(c.Stop)
+            c.Start()
+            if got, want := chips(t, c), []string{"acpitz", "amdgpu", "k10temp", "k10temp", "nvme"}; !reflect.DeepEqual(got, want) {
+                t.Fatalf("chips before removal = %q, want %q", got, want)
+            }
+            if err := os.Remove(filepath.Join(hwmon, "hwmon1/temp1_input")); err != nil {
+                t.Fatal(err)
+            }
+            if opts.CollectInterval > 0 {
+                c.refresh()
+            }
+            if got, want := chips(t, c), []string{"acpitz", "amdgpu", "k10temp", "k10temp"}; !reflect.DeepEqual(got, want) {
+                t.Errorf("chips after removal = %q, want %q", got, want)
+            }
+        })
+    }
+}
+
+// TestStaleSnapshotExpires stops refreshing the background snapshot: past -stale-after its
+// readings are no longer exported and counted by chip
+func TestStaleSnapshotExpires(t *testing.T) {
+    c, err := New(Options{
+        EnableHwmon:     true,
+        HwmonPaths:      []string{writeTree(t, fakeHwmonFiles)},
+        CollectInterval: time.Hour,
+        StaleAfter:      50 * time.Millisecond,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(c.Stop)
+    c.Start()
+    if got := chips(t, c); len(got) != 5 {
+        t.Fatalf("chips of a fresh snapshot = %q, want 5 series", got)
+    }
+    time.Sleep(100 * time.Millisecond)
+    if got := chips(t, c); len(got) != 0 {
+        t.Errorf("chips of a stale snapshot = %q, want none", got)
+    }
+    stale := map[string]float64{}
+    for _, m := range gather(t, c, "stale_readings_total") {
+        stale[m.GetLabel()[0].GetValue()] = m.GetCounter().GetValue()
+    }
+    // every reading of the snapshot (inputs and thresholds) is counted by each of the two scrapes
+    if want := map[string]float64{"k10temp": 4, "nvme": 6, "amdgpu": 4, "acpitz": 4}; !reflect.DeepEqual(stale, want) {
+        t.Errorf("stale_readings_total = %v, want %v", stale, want)
+    }
+}
@@ -0,0 +1,181 @@
+package collector
+
+import (
+    "html/template"
+    "log/slog"
+    "math"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+// maxStatusRefresh caps ?refresh=, in seconds
+const maxStatusRefresh = 3600
+
+// statusSource is one line of the source table of the status page
+type statusSource struct {
+    Name     string
+    OK       bool
+    Sensors  int
+    Duration time.Duration
+}
+
+// statusRow is one temperature of the status page
+type statusRow struct {
+    Source, Target, Chip, Sensor, Label string
+    Celsius                             float64
+    Max, Crit                           float64 // NaN when the sensor announces none
+    Level                               string  // ok, warn (at or above max), crit (at or above crit), empty without thresholds
+}
+
+// statusPage is what statusTemplate renders
+type statusPage struct {
+    Version  string
+    At       time.Time
+    Duration time.Duration // of the collection, or of the snapshot lookup with -collect-interval
+    Refresh  int
+    Targets  bool // adds the target column of the ssh source
+    Sources  []statusSource
+    Rows     []statusRow
+}
+
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+    "celsius": func(v float64) string {
+        if math.IsNaN(v) {
+            return ""
+        }
+        return strconv.FormatFloat(v, 'f', 1, 64)
+    },
+    "ms": func(d time.Duration) string {
+        return strconv.FormatFloat(d.Seconds()*1000, 'f', 1, 64) + " ms"
+    },
+}).Parse(`<!DOCTYPE html>
+<html lang="fr">
+<head>
+<meta charset="utf-8">
+{{- if .Refresh}}
+<meta http-equiv="refresh" content="{{.Refresh}}">
+{{- end}}
+<title>Temperature Exporter</title>
+<style>
+body { font-family: sans-serif; margin: 1.5em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.6em; text-align: left; }
+td.num { text-align: right; }
+tr.ok td.temp { background: #c9efc9; }
+tr.warn td.temp { background: #ffe08a; }
+tr.crit td.temp { background: #f5a3a3; }
+tr.failed td { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Temperature Exporter</h1>
+<p>Version {{.Version}}. Collecte du {{.At.Format "2006-01-02 15:04:05"}}, obtenue en {{ms .Duration}}.</p>
+<table>
+<tr><th>Source</th><th>État</th><th>Capteurs</th><th>Durée</th></tr>
+{{- range .Sources}}
+<tr{{if not .OK}} class="failed"{{end}}><td>{{.Name}}</td><td>{{if .OK}}ok{{else}}échec{{end}}</td><td class="num">{{.Sensors}}</td><td class="num">{{ms .Duration}}</td></tr>
+{{- end}}
+</table>
+<table>
+<tr><th>Source</th>{{if .Targets}}<th>Cible</th>{{end}}<th>Chip</th><th>Capteur</th><th>Libellé</th><th>°C</th><th>Max</th><th>Crit</th></tr>
+{{- $targets := .Targets}}
+{{- range .Rows}}
+<tr{{with .Level}} class="{{.}}"{{end}}><td>{{.Source}}</td>{{if $targets}}<td>{{.Target}}</td>{{end}}<td>{{.Chip}}</td><td>{{.Sensor}}</td><td>{{.Label}}</td><td class="num temp">{{celsius .Celsius}}</td><td class="num">{{celsius .Max}}</td><td class="num">{{celsius .Crit}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// StatusHandler serves /status, an HTML table of the temperatures of a fresh collection (the
+// background snapshot with -collect-interval) colored against their max and crit thresholds
+// when the sensor has them. ?refresh=N reloads the page every N seconds.
+func (c *Collector) StatusHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodGet && r.Method != http.MethodHead {
+            w.Header().Set("Allow", "GET, HEAD")
+            http.Error(w, "méthode non autorisée", http.StatusMethodNotAllowed)
+            return
+        }
+        page := statusPage{Version: Version}
+        if v := r.URL.Query().Get("refresh"); v != "" {
+            n, err := strconv.Atoi(v)
+            if err != nil || n < 0 || n > maxStatusRefresh {
+                http.Error(w, "refresh: nombre de secondes entre 0 et "+strconv.Itoa(maxStatusRefresh)+" attendu", http.StatusBadRequest)
+                return
+            }
+            page.Refresh = n
+        }
+
+        start := time.Now()
+        c.inflight.Add(1)
+        readings, stats := c.latest(c.ctx, start)
+        c.inflight.Done()
+        page.At, page.Duration = start, time.Since(start)
+        if s := c.recent.Load(); s != nil {
+            page.At = s.at
+        }
+        counts := make(map[string]int)
+        for _, rd := range readings {
+            if rd.kind == kindTemperature {
+                counts[rd.source]++
+            }
+        }
+        for _, st := range stats {
+            page.Sources = append(page.Sources, statusSource{Name: st.source, OK: st.success, Sensors: counts[st.source], Duration: st.duration})
+        }
+        page.Rows = statusRows(readings)
+        for _, row := range page.Rows {
+            page.Targets = page.Targets || row.Target != ""
+        }
+
+        w.Header().Set("Content-Type", "text/html; charset=utf-8")
+        w.Header().Set("Cache-Control", "no-store")
+        if err := statusTemplate.Execute(w, page); err != nil {
+            slog.Debug("page de statut", "err", err)
+        }
+    })
+}
+
+// statusRows pairs every temperature with the max and crit thresholds of the same sensor
+func statusRows(readings []reading) []statusRow {
+    type sensorKey struct{ source, target, chip, name, label, pkg, device string }
+    key := func(r reading) sensorKey {
+        return sensorKey{r.source, r.target, r.chip, r.name, r.label, r.pkg, r.device}
+    }
+    maxima := make(map[sensorKey]float64)
+    crits := make(map[sensorKey]float64)
+    for _, r := range readings {
+        switch r.kind {
+        case kindMax:
+            maxima[key(r)] = r.value
+        case kindCrit:
+            crits[key(r)] = r.value
+        }
+    }
+    var rows []statusRow
+    for _, r := range readings {
+        if r.kind != kindTemperature {
+            continue
+        }
+        row := statusRow{Source: r.source, Target: r.target, Chip: r.chip, Sensor: r.name, Label: r.label, Celsius: r.value, Max: math.NaN(), Crit: math.NaN()}
+        if v, ok := maxima[key(r)]; ok {
+            row.Max, row.Level = v, "ok"
+            if r.value >= v {
+                row.Level = "warn"
+            }
+        }
+        if v, ok := crits[key(r)]; ok {
+            row.Crit = v
+            switch {
+            case r.value >= v:
+                row.Level = "crit"
+            case row.Level == "":
+                row.Level = "ok"
+            }
+        }
+        rows = append(rows, row)
+    }
+    return rows
+}
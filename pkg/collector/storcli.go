@@ -0,0 +1,118 @@
+package collector
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+var storcliWarned bool
+
+var (
+    // "Drive /c0/e252/s3 State" (enclosure-less drives are "/c0/s3")
+    storcliDriveStateRe = regexp.MustCompile(`^Drive /c\d+(?:/e(\d+))?/s(\d+) State$`)
+    // "33C (91.40 F)"
+    storcliTempRe = regexp.MustCompile(`(-?\d+(?:\.\d+)?)\s*C\b`)
+)
+
+// discoverStorcli runs `storcli64 /call show all J` (or perccli) and extracts controller and drive temperatures.
+func discoverStorcli(ctx context.Context, bin string, timeout time.Duration) ([]reading, error) {
+    out, err := sources.RunCommand(ctx, timeout, bin, "/call", "show", "all", "J")
+    if err != nil {
+        return nil, err
+    }
+    return parseStorcli(out)
+}
+
+// parseStorcli walks the JSON defensively: key names and nesting differ between firmware versions,
+// so the ROC temperature and the per-drive "State" blocks are searched at any depth.
+func parseStorcli(out []byte) ([]reading, error) {
+    var root map[string]interface{}
+    if err := json.Unmarshal(out, &root); err != nil {
+        return nil, err
+    }
+    controllers, ok := root["Controllers"].([]interface{})
+    if !ok {
+        return nil, fmt.Errorf("storcli: missing Controllers array")
+    }
+    var res []reading
+    for i, cv := range controllers {
+        ctrl, ok := cv.(map[string]interface{})
+        if !ok {
+            continue
+        }
+        sensor := fmt.Sprintf("controller%d", i)
+        if status, ok := ctrl["Command Status"].(map[string]interface{}); ok {
+            if id, ok := status["Controller"]; ok {
+                sensor = "controller" + strings.TrimSpace(fmt.Sprint(id))
+            }
+        }
+        data, ok := ctrl["Response Data"].(map[string]interface{})
+        if !ok {
+            continue
+        }
+        walkJSON(data, func(key string, val interface{}) {
+            if strings.HasPrefix(key, "ROC temperature") {
+                if v, ok := storcliTemperature(val); ok {
+                    res = append(res, reading{chip: "storcli", name: sensor, label: "ROC", value: v})
+                }
+                return
+            }
+            match := storcliDriveStateRe.FindStringSubmatch(key)
+            if match == nil {
+                return
+            }
+            state, ok := val.(map[string]interface{})
+            if !ok {
+                return
+            }
+            label := match[2]
+            if match[1] != "" {
+                label = match[1] + ":" + match[2]
+            }
+            if v, ok := storcliTemperature(state["Drive Temperature"]); ok {
+                res = append(res, reading{chip: "storcli", name: sensor, label: label, value: v})
+            }
+        })
+    }
+    return res, nil
+}
+
+// walkJSON calls fn for every key/value pair of every object nested in v
+func walkJSON(v interface{}, fn func(key string, val interface{})) {
+    switch t := v.(type) {
+    case map[string]interface{}:
+        for k, val := range t {
+            fn(k, val)
+            walkJSON(val, fn)
+        }
+    case []interface{}:
+        for _, val := range t {
+            walkJSON(val, fn)
+        }
+    }
+}
+
+// storcliTemperature accepts a bare number, "52" or "33C (91.40 F)"; "N/A" and friends are skipped.
+func storcliTemperature(val interface{}) (float64, bool) {
+    switch t := val.(type) {
+    case float64:
+        return t, true
+    case string:
+        s := strings.TrimSpace(t)
+        if v, err := strconv.ParseFloat(s, 64); err == nil {
+            return v, true
+        }
+        if m := storcliTempRe.FindStringSubmatch(s); m != nil {
+            v, err := strconv.ParseFloat(m[1], 64)
+            return v, err == nil
+        }
+    }
+    return 0, false
+}
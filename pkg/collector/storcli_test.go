@@ -0,0 +1,68 @@
+package collector
+
+import (
+    "slices"
+    "testing"
+)
+
+func TestParseStorcli(t *testing.T) {
+    tests := []struct {
+        name    string
+        out     []byte
+        want    []string
+        wantErr bool
+    }{
+        {
+            // drives with or without enclosure, ROC as a number or a string, N/A drives skipped
+            name: "two controllers",
+            out:  readFixture(t, "storcli-call-show-all.json"),
+            want: []string{
+                "storcli/controller0/32:0 temperature=33",
+                "storcli/controller0/ROC temperature=52",
+                "storcli/controller1/4 temperature=38",
+                "storcli/controller1/ROC temperature=61",
+            },
+        },
+        {
+            name: "controller without Command Status",
+            out:  []byte(`{"Controllers":[{"Response Data":{"HwCfg":{"ROC temperature(Degree Celsius)":47}}}]}`),
+            want: []string{"storcli/controller0/ROC temperature=47"},
+        },
+        {name: "controller without Response Data", out: []byte(`{"Controllers":[{"Command Status":{"Controller":0,"Status":"Failure"}}]}`)},
+        {name: "no Controllers array", out: []byte(`{"Status":"Failure"}`), wantErr: true},
+        {name: "not JSON", out: []byte("storcli: command not found\n"), wantErr: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rs, err := parseStorcli(tt.out)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+            }
+            if got := describe(rs); !slices.Equal(got, tt.want) {
+                t.Errorf("got %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestStorcliTemperature(t *testing.T) {
+    tests := []struct {
+        val  interface{}
+        want float64
+        ok   bool
+    }{
+        {float64(52), 52, true},
+        {"52", 52, true},
+        {" 33C (91.40 F)", 33, true},
+        {"-5C (23.00 F)", -5, true},
+        {"N/A", 0, false},
+        {"", 0, false},
+        {nil, 0, false},
+        {true, 0, false},
+    }
+    for _, tt := range tests {
+        if got, ok := storcliTemperature(tt.val); got != tt.want || ok != tt.ok {
+            t.Errorf("storcliTemperature(%#v) = %v, %v, want %v, %v", tt.val, got, ok, tt.want, tt.ok)
+        }
+    }
+}
@@ -0,0 +1,76 @@
+package collector
+
+import (
+    "context"
+    "path/filepath"
+    "regexp"
+    "slices"
+    "strconv"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+// throttleCount is one thermal_throttle counter: how many times the CPU actually slowed down
+type throttleCount struct {
+    cpu   string // first logical CPU of the core, empty for the package counter
+    pkg   string // physical_package_id
+    scope string // core or package
+    count float64
+}
+
+var cpuDirRe = regexp.MustCompile(`^cpu(\d+)$`)
+
+// discoverThrottle reads the thermal_throttle counters of the CPUs under cpuBase (default
+// /sys/devices/system/cpu) in fsys (the host when nil). SMT siblings share their core counter and every CPU of a socket the
+// package one, so each counter is returned once and summing them never counts twice. CPUs
+// without the directory (AMD, virtual machines, offline CPUs) are skipped. Reading stops when
+// ctx ends.
+func discoverThrottle(ctx context.Context, fsys sources.FS, cpuBase string) ([]throttleCount, error) {
+    if fsys == nil {
+        fsys = sources.OS
+    }
+    entries, err := fsys.ReadDir(cpuBase)
+    if err != nil {
+        return nil, err
+    }
+    var cpus []int
+    for _, e := range entries {
+        if m := cpuDirRe.FindStringSubmatch(e.Name()); m != nil {
+            n, _ := strconv.Atoi(m[1])
+            cpus = append(cpus, n)
+        }
+    }
+    // numeric order, so a core is reported under its lowest CPU
+    slices.Sort(cpus)
+    var counts []throttleCount
+    cores := make(map[string]bool)
+    packages := make(map[string]bool)
+    for _, n := range cpus {
+        if ctx.Err() != nil {
+            break
+        }
+        cpu := strconv.Itoa(n)
+        dir := filepath.Join(cpuBase, "cpu"+cpu)
+        v, err := sources.ReadValue(fsys, filepath.Join(dir, "thermal_throttle", "core_throttle_count"))
+        if err != nil {
+            continue
+        }
+        pkg, _ := sources.ReadFirstLine(fsys, filepath.Join(dir, "topology", "physical_package_id"))
+        core := "cpu" + cpu
+        if id, err := sources.ReadFirstLine(fsys, filepath.Join(dir, "topology", "core_id")); err == nil {
+            core = pkg + "/" + id
+        }
+        if !cores[core] {
+            cores[core] = true
+            counts = append(counts, throttleCount{cpu: cpu, pkg: pkg, scope: "core", count: v})
+        }
+        if packages[pkg] {
+            continue
+        }
+        if v, err := sources.ReadValue(fsys, filepath.Join(dir, "thermal_throttle", "package_throttle_count")); err == nil {
+            packages[pkg] = true
+            counts = append(counts, throttleCount{pkg: pkg, scope: "package", count: v})
+        }
+    }
+    return counts, nil
+}
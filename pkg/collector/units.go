@@ -0,0 +1,38 @@
+package collector
+
+import (
+    "math"
+)
+
+func celsiusToFahrenheit(c float64) float64 {
+    return c*9/5 + 32
+}
+
+func celsiusToKelvin(c float64) float64 {
+    return c + 273.15
+}
+
+// roundReadings rounds every value but the energy counters to precision decimal places, the
+// same way whatever the source, so hwmon and sensors -j give one value for the same sensor
+func roundReadings(readings []reading, precision int) {
+    for i, r := range readings {
+        if r.kind != kindEnergy {
+            readings[i].value = roundTo(r.value, precision)
+        }
+    }
+}
+
+// roundTo rounds half to even, so a value hovering around a midpoint does not flip between the
+// two neighbours a half-up rounding would alternate on
+func roundTo(v float64, precision int) float64 {
+    p := math.Pow10(precision)
+    return math.RoundToEven(v*p) / p
+}
+
+// round applies -precision to the values converted at export time, Fahrenheit and Kelvin
+func (c *Collector) round(v float64) float64 {
+    if !c.Round {
+        return v
+    }
+    return roundTo(v, c.Precision)
+}
@@ -0,0 +1,36 @@
+package collector
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/Tutanka01/Temperature-Exporter-Proxmox/pkg/sources"
+)
+
+var vcgencmdWarned bool
+
+// discoverVcgencmd reads the Raspberry Pi firmware SoC temperature via `vcgencmd measure_temp`.
+func discoverVcgencmd(ctx context.Context, bin string, timeout time.Duration) ([]reading, error) {
+    out, err := sources.RunCommand(ctx, timeout, bin, "measure_temp")
+    if err != nil {
+        return nil, err
+    }
+    v, err := parseVcgencmdTemp(string(out))
+    if err != nil {
+        return nil, err
+    }
+    return []reading{{chip: "vcgencmd", name: "soc", value: v}}, nil
+}
+
+// parseVcgencmdTemp parses output like "temp=48.3'C"
+func parseVcgencmdTemp(out string) (float64, error) {
+    s := strings.TrimSpace(out)
+    if !strings.HasPrefix(s, "temp=") {
+        return 0, fmt.Errorf("unexpected vcgencmd output %q", s)
+    }
+    s = strings.TrimSuffix(strings.TrimPrefix(s, "temp="), "'C")
+    return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,69 @@
+package sources
+
+import (
+    "path/filepath"
+    "regexp"
+    "strconv"
+)
+
+// AmdgpuCard links an amdgpu hwmon chip to its /sys/class/drm card and PCI device
+type AmdgpuCard struct {
+    Card        string  // drm card name, e.g. card0
+    PCIAddress  string  // e.g. 0000:03:00.0
+    PowerCap    float64 // power1_cap in watts
+    HasPowerCap bool
+}
+
+var drmCardRe = regexp.MustCompile(`^card\d+$`)
+
+// resolveAmdgpuCard follows the chip's device symlink back to the PCI device and looks up its drm cardN.
+func resolveAmdgpuCard(fsys FS, chipDir string) (AmdgpuCard, bool) {
+    dev, err := fsys.EvalSymlinks(filepath.Join(chipDir, "device"))
+    if err != nil {
+        return AmdgpuCard{}, false
+    }
+    entries, err := fsys.ReadDir(filepath.Join(dev, "drm"))
+    if err != nil {
+        return AmdgpuCard{}, false
+    }
+    for _, e := range entries {
+        if drmCardRe.MatchString(e.Name()) {
+            card := AmdgpuCard{Card: e.Name(), PCIAddress: filepath.Base(dev)}
+            if raw, err := readFirstLine(fsys, filepath.Join(chipDir, "power1_cap")); err == nil {
+                if uw, err := strconv.ParseFloat(raw, 64); err == nil {
+                    card.PowerCap = uw / 1e6 // microwatts
+                    card.HasPowerCap = true
+                }
+            }
+            return card, true
+        }
+    }
+    return AmdgpuCard{}, false
+}
+
+// DiscoverAmdgpuCards returns every amdgpu chip under the hwmon base directories of fsys (the
+// host when nil) that could be mapped to a drm card.
+func DiscoverAmdgpuCards(fsys FS, basePaths []string) []AmdgpuCard {
+    fsys = orOS(fsys)
+    var cards []AmdgpuCard
+    for _, basePath := range basePaths {
+        entries, err := fsys.ReadDir(basePath)
+        if err != nil {
+            continue
+        }
+        visited := make(map[string]bool)
+        for _, e := range entries {
+            chipDir := filepath.Join(basePath, e.Name())
+            if !visitDir(fsys, chipDir, visited) {
+                continue
+            }
+            if n, err := readFirstLine(fsys, filepath.Join(chipDir, "name")); err != nil || n != "amdgpu" {
+                continue
+            }
+            if card, ok := resolveAmdgpuCard(fsys, chipDir); ok {
+                cards = append(cards, card)
+            }
+        }
+    }
+    return cards
+}
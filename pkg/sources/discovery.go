@@ -0,0 +1,173 @@
+package sources
+
+import (
+    "context"
+    "errors"
+    "log/slog"
+    "path/filepath"
+    "sync"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+// hotplugDebounce groups the burst of events a single device produces into one rediscovery
+const hotplugDebounce = 2 * time.Second
+
+// DiscoveryCache keeps the result of a sysfs scan until it expires or is invalidated by hotplug.
+type DiscoveryCache struct {
+    mu      sync.Mutex
+    ttl     time.Duration
+    fetched time.Time
+    sensors []Sensor
+}
+
+// NewDiscoveryCache returns a cache keeping a scan for ttl, 0 to rescan on every call
+func NewDiscoveryCache(ttl time.Duration) *DiscoveryCache {
+    return &DiscoveryCache{ttl: ttl}
+}
+
+// get returns a copy of the cached sensors while fresh, otherwise rescans with discover.
+// Errors are not cached so a missing directory is retried on the next scrape; the partial
+// result of a scan cut short by its deadline is returned along with the error.
+func (dc *DiscoveryCache) get(discover func() ([]Sensor, error)) ([]Sensor, error) {
+    dc.mu.Lock()
+    defer dc.mu.Unlock()
+    if dc.ttl <= 0 || dc.fetched.IsZero() || time.Since(dc.fetched) >= dc.ttl {
+        sensors, err := discover()
+        if err != nil {
+            return sensors, err
+        }
+        dc.sensors, dc.fetched = sensors, time.Now()
+    }
+    // callers filter in place
+    return append([]Sensor(nil), dc.sensors...), nil
+}
+
+// Invalidate forces the next Discover to rescan
+func (dc *DiscoveryCache) Invalidate() {
+    dc.mu.Lock()
+    dc.fetched = time.Time{}
+    dc.mu.Unlock()
+}
+
+// VisitDir reports whether dir of fsys (the host when nil), once its symlinks are resolved, is
+// a directory not seen yet, like visitDir
+func VisitDir(fsys FS, dir string, visited map[string]bool) bool {
+    return visitDir(orOS(fsys), dir, visited)
+}
+
+// visitDir reports whether dir, once its symlinks are resolved, is a directory not seen yet.
+// Class directories like /sys/class/hwmon are made of symlinks that DirEntry.IsDir does not
+// follow, and a copied sysfs tree may have turned them into plain directories or left two
+// entries pointing at the same device: resolving first handles every layout and scans each
+// chip once. Broken links and symlink loops fail to resolve and are skipped.
+func visitDir(fsys FS, dir string, visited map[string]bool) bool {
+    real, err := fsys.EvalSymlinks(dir)
+    if err != nil {
+        return false
+    }
+    if fi, err := fsys.Stat(real); err != nil || !fi.IsDir() || visited[real] {
+        return false
+    }
+    visited[real] = true
+    return true
+}
+
+// discoverPaths runs discover on every base directory of -hwmon or -thermal. A directory that
+// cannot be scanned is logged and skipped so the others are still read: the source only fails
+// when all of them do. When ctx expires the sensors found so far are returned with its error.
+func discoverPaths(ctx context.Context, paths []string, discover func(context.Context, string) ([]Sensor, error)) ([]Sensor, error) {
+    var sensors []Sensor
+    var errs []error
+    for _, p := range paths {
+        s, err := discover(ctx, p)
+        sensors = append(sensors, s...)
+        if err == nil {
+            continue
+        }
+        if ctx.Err() != nil {
+            return sensors, err
+        }
+        errs = append(errs, err)
+    }
+    if len(errs) == len(paths) {
+        return sensors, errors.Join(errs...)
+    }
+    for _, err := range errs {
+        slog.Warn("répertoire de capteurs ignoré", "err", err)
+    }
+    return sensors, nil
+}
+
+// Watch invalidates dc when entries are created or removed under dir, at most once per
+// hotplugDebounce, until ctx is cancelled. Without inotify (restricted containers) it logs once
+// and the TTL applies.
+func (dc *DiscoveryCache) Watch(ctx context.Context, dir string) {
+    w, err := fsnotify.NewWatcher()
+    if err == nil {
+        err = w.Add(dir)
+        if err != nil {
+            w.Close()
+        }
+    }
+    if err != nil {
+        slog.Warn("hotplug: surveillance impossible, redécouverte périodique", "dir", dir, "err", err, "ttl", dc.ttl)
+        return
+    }
+    go func() {
+        defer w.Close()
+        var next time.Time
+        var pending *time.Timer
+        defer func() {
+            if pending != nil {
+                pending.Stop()
+            }
+        }()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case ev, ok := <-w.Events:
+                if !ok {
+                    return
+                }
+                if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Remove) {
+                    continue
+                }
+                // events inside a pending window are covered by the scheduled invalidation
+                if time.Now().Before(next) {
+                    continue
+                }
+                next = time.Now().Add(hotplugDebounce)
+                pending = time.AfterFunc(hotplugDebounce, dc.Invalidate)
+            case err, ok := <-w.Errors:
+                if !ok {
+                    return
+                }
+                slog.Warn("hotplug", "err", err)
+            }
+        }
+    }()
+}
+
+// deviceDriver returns the name of the kernel driver bound to the device behind a hwmon or
+// thermal directory, or "" for virtual devices that have no device/driver link
+func deviceDriver(fsys FS, dir string) string {
+    target, err := fsys.Readlink(filepath.Join(dir, "device", "driver"))
+    if err != nil {
+        return ""
+    }
+    return filepath.Base(target)
+}
+
+// deviceName returns the device behind a hwmon or thermal directory as the kernel names it:
+// 0000:41:00.0 for a PCI function, 1-1.2:1.0 for a USB interface, 0-004c for an i2c client.
+// Virtual chips have no device link and get "".
+func deviceName(fsys FS, dir string) string {
+    target, err := fsys.EvalSymlinks(filepath.Join(dir, "device"))
+    if err != nil {
+        return ""
+    }
+    return filepath.Base(target)
+}
@@ -0,0 +1,108 @@
+package sources
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "testing"
+)
+
+// symlinkedHwmon builds the sysfs layout in a temp dir: the device directories under devices/
+// and the class directory made of links to them, plus the odd entries a copied or bind-mounted
+// tree can hold. It returns the root and the class directory.
+func symlinkedHwmon(t *testing.T) (string, string) {
+    t.Helper()
+    root := t.TempDir()
+    write := func(path, content string) {
+        t.Helper()
+        p := filepath.Join(root, path)
+        if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    link := func(target, path string) {
+        t.Helper()
+        if err := os.Symlink(target, filepath.Join(root, path)); err != nil {
+            t.Skipf("symlinks unavailable: %v", err)
+        }
+    }
+    write("devices/pci0000:00/0000:00:18.3/hwmon/hwmon0/name", "k10temp\n")
+    write("devices/pci0000:00/0000:00:18.3/hwmon/hwmon0/temp1_input", "45000\n")
+    write("devices/pci0000:00/0000:00:18.3/hwmon/hwmon0/temp1_label", "Tctl\n")
+    write("devices/pci0000:00/0000:01:00.0/nvme/nvme0/hwmon1/name", "nvme\n")
+    write("devices/pci0000:00/0000:01:00.0/nvme/nvme0/hwmon1/temp1_input", "38850\n")
+    if err := os.MkdirAll(filepath.Join(root, "class/hwmon"), 0o755); err != nil {
+        t.Fatal(err)
+    }
+    // the usual class entries, relative links to the device directories
+    link("../../devices/pci0000:00/0000:00:18.3/hwmon/hwmon0", "class/hwmon/hwmon0")
+    link("../../devices/pci0000:00/0000:01:00.0/nvme/nvme0/hwmon1", "class/hwmon/hwmon1")
+    // a second entry for the same device must not scan it twice
+    link("../../devices/pci0000:00/0000:00:18.3/hwmon/hwmon0", "class/hwmon/hwmon9")
+    // a device that went away
+    link("../../devices/platform/gone/hwmon/hwmon2", "class/hwmon/hwmon2")
+    // links pointing at each other never resolve
+    link("hwmon4", "class/hwmon/hwmon3")
+    link("hwmon3", "class/hwmon/hwmon4")
+    // a link back to the class directory itself
+    link(".", "class/hwmon/hwmon5")
+    // a copied snapshot where the chip is a plain directory
+    write("class/hwmon/hwmon6/name", "acpitz\n")
+    write("class/hwmon/hwmon6/temp1_input", "27800\n")
+    return root, filepath.Join(root, "class/hwmon")
+}
+
+func TestDiscoverSymlinkedHwmon(t *testing.T) {
+    root, class := symlinkedHwmon(t)
+    // the base directory itself may be a link too (-sysfs-prefix pointing at a bind mount)
+    base := filepath.Join(root, "hwmon-link")
+    if err := os.Symlink(class, base); err != nil {
+        t.Skipf("symlinks unavailable: %v", err)
+    }
+    for _, dir := range []string{class, base} {
+        t.Run(filepath.Base(dir), func(t *testing.T) {
+            h := &Hwmon{Paths: []string{dir}}
+            sensors, err := h.Discover(context.Background())
+            if err != nil {
+                t.Fatal(err)
+            }
+            var got []string
+            for _, s := range sensors {
+                got = append(got, s.Chip+" "+filepath.Base(filepath.Dir(s.Path)))
+            }
+            sort.Strings(got)
+            want := []string{"acpitz hwmon6", "k10temp hwmon0", "nvme hwmon1"}
+            if !reflect.DeepEqual(got, want) {
+                t.Errorf("Discover() chips = %q, want %q", got, want)
+            }
+        })
+    }
+}
+
+func TestVisitDir(t *testing.T) {
+    _, class := symlinkedHwmon(t)
+    visited := make(map[string]bool)
+    for _, tt := range []struct {
+        entry string
+        want  bool
+    }{
+        {"hwmon0", true},
+        {"hwmon1", true},
+        {"hwmon9", false}, // same device as hwmon0
+        {"hwmon0", false}, // already seen
+        {"hwmon2", false}, // broken link
+        {"hwmon3", false}, // link loop
+        {"hwmon5", true},  // the class directory, seen for the first time
+        {"hwmon6", true},  // plain directory
+        {"hwmon6/name", false},
+    } {
+        if got := VisitDir(nil, filepath.Join(class, tt.entry), visited); got != tt.want {
+            t.Errorf("VisitDir(%s) = %v, want %v", tt.entry, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,27 @@
+package sources
+
+import (
+    "context"
+    "os/exec"
+    "time"
+)
+
+// commandWaitDelay bounds how long Output waits for pipes still held by grandchildren once
+// the command itself has been killed
+const commandWaitDelay = time.Second
+
+// RunCommand runs bin and returns its stdout. The timeout, 0 for none, is derived from ctx, so
+// cancelling the collection context at shutdown kills the command too. Where the system allows it the
+// command runs in its own process group and the whole group is killed, so wrapper scripts do
+// not leave orphans behind; the command itself is always waited for (reaped).
+func RunCommand(ctx context.Context, timeout time.Duration, bin string, args ...string) ([]byte, error) {
+    cancel := context.CancelFunc(func() {})
+    if timeout > 0 {
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+    }
+    defer cancel()
+    cmd := exec.CommandContext(ctx, bin, args...)
+    killGroupOnCancel(cmd)
+    cmd.WaitDelay = commandWaitDelay
+    return cmd.Output()
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package sources
+
+import "os/exec"
+
+// killGroupOnCancel leaves the default cancellation, which kills the command alone: there are
+// no process groups to signal
+func killGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package sources
+
+import (
+    "os/exec"
+    "syscall"
+)
+
+// killGroupOnCancel starts cmd in its own process group and has the context kill the group
+func killGroupOnCancel(cmd *exec.Cmd) {
+    cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+    cmd.Cancel = func() error {
+        return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+    }
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package sources
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+// TestRunCommandTimeout checks that a zero timeout means none, as for the other timeouts of
+// Options, rather than a deadline already expired
+func TestRunCommandTimeout(t *testing.T) {
+    out, err := RunCommand(context.Background(), 0, "echo", "ok")
+    if err != nil || string(out) != "ok\n" {
+        t.Errorf("RunCommand without timeout = %q, %v, want \"ok\\n\"", out, err)
+    }
+    if _, err := RunCommand(context.Background(), 50*time.Millisecond, "sleep", "5"); err == nil {
+        t.Error("RunCommand outlived its timeout")
+    }
+}
@@ -0,0 +1,293 @@
+package sources
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "errors"
+    "io"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// lineBufSize covers any sysfs attribute: the kernel returns at most one page per file
+const lineBufSize = 4096
+
+// lineBufs recycles the read buffers, sensor files being read on every scrape
+var lineBufs = sync.Pool{New: func() any { return new([lineBufSize]byte) }}
+
+// ErrEmptyFile is returned for a file without any content
+var ErrEmptyFile = errors.New("empty file")
+
+// errLongLine reports a first line that does not fit in a pooled buffer
+var errLongLine = errors.New("line longer than the read buffer")
+
+// readLine reads the first line of path into buf and returns it without surrounding spaces
+func readLine(fsys FS, path string, buf []byte) ([]byte, error) {
+    f, err := fsys.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    n := 0
+    for n < len(buf) {
+        m, err := f.Read(buf[n:])
+        n += m
+        if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+            return bytes.TrimSpace(buf[:i]), nil
+        }
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+    }
+    if n == len(buf) {
+        return nil, errLongLine
+    }
+    if n == 0 {
+        return nil, ErrEmptyFile
+    }
+    return bytes.TrimSpace(buf[:n]), nil
+}
+
+// ReadFirstLine returns the first line of path in fsys (the host when nil) without surrounding spaces
+func ReadFirstLine(fsys FS, path string) (string, error) {
+    return readFirstLine(orOS(fsys), path)
+}
+
+// readFirstLine reads the first line of path in fsys. Lines too long for the pooled buffer
+// (never seen in sysfs) go through a Scanner as before.
+func readFirstLine(fsys FS, path string) (string, error) {
+    buf := lineBufs.Get().(*[lineBufSize]byte)
+    defer lineBufs.Put(buf)
+    line, err := readLine(fsys, path, buf[:])
+    if errors.Is(err, errLongLine) {
+        return scanFirstLine(fsys, path)
+    }
+    return string(line), err
+}
+
+func scanFirstLine(fsys FS, path string) (string, error) {
+    f, err := fsys.Open(path)
+    if err != nil {
+        return "", err
+    }
+    defer f.Close()
+    s := bufio.NewScanner(f)
+    if s.Scan() {
+        return strings.TrimSpace(s.Text()), nil
+    }
+    if err := s.Err(); err != nil {
+        return "", err
+    }
+    return "", ErrEmptyFile
+}
+
+// ReadValue reads the number on the first line of the sensor file path in fsys (the host when nil)
+func ReadValue(fsys FS, path string) (float64, error) {
+    return readValue(orOS(fsys), path)
+}
+
+// readValue parses the first line of path in fsys without building a string for the usual
+// integer values (millidegrees, microvolts...)
+func readValue(fsys FS, path string) (float64, error) {
+    buf := lineBufs.Get().(*[lineBufSize]byte)
+    defer lineBufs.Put(buf)
+    line, err := readLine(fsys, path, buf[:])
+    if errors.Is(err, errLongLine) {
+        raw, err := scanFirstLine(fsys, path)
+        if err != nil {
+            return 0, err
+        }
+        return strconv.ParseFloat(raw, 64)
+    }
+    if err != nil {
+        return 0, err
+    }
+    return parseValue(line)
+}
+
+// parseValue parses a sysfs number: an optionally signed integer directly, anything else
+// (decimals, exponents, garbage) through strconv.ParseFloat
+func parseValue(b []byte) (float64, error) {
+    digits := b
+    if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+        digits = digits[1:]
+    }
+    if len(digits) == 0 || len(digits) > 18 {
+        return strconv.ParseFloat(string(b), 64)
+    }
+    var v int64
+    for _, c := range digits {
+        if c < '0' || c > '9' {
+            return strconv.ParseFloat(string(b), 64)
+        }
+        v = v*10 + int64(c-'0')
+    }
+    if b[0] == '-' {
+        v = -v
+    }
+    return float64(v), nil
+}
+
+// ErrReadAbandoned is returned for a sensor file still blocked in the kernel after the read deadline
+var ErrReadAbandoned = errors.New("lecture abandonnée (délai dépassé)")
+
+// FileReader reads sensor files with a per-file deadline, so one hung driver cannot hold the
+// whole collection until the source timeout. The goroutine of an abandoned read cannot be
+// interrupted and is left to finish on its own; until it does, its path is marked hung and
+// not read again, so a sensor that hangs on every scrape costs a single goroutine.
+type FileReader struct {
+    timeout time.Duration // 0 reads without deadline
+    mu      sync.Mutex
+    hung    map[string]bool
+}
+
+// NewFileReader returns a FileReader giving up on a file after timeout, 0 for never
+func NewFileReader(timeout time.Duration) *FileReader {
+    return &FileReader{timeout: timeout, hung: make(map[string]bool)}
+}
+
+// Read returns the value of a sensor file of fsys, the host's when nil, or ErrReadAbandoned when
+// it takes longer than the deadline or an earlier read of path is still blocked
+func (fr *FileReader) Read(fsys FS, path string) (float64, error) {
+    fsys = orOS(fsys)
+    if fr.timeout <= 0 {
+        return readValue(fsys, path)
+    }
+    fr.mu.Lock()
+    hung := fr.hung[path]
+    fr.mu.Unlock()
+    if hung {
+        return 0, ErrReadAbandoned
+    }
+    type result struct {
+        value float64
+        err   error
+    }
+    // buffered so an abandoned read never blocks on its send
+    done := make(chan result, 1)
+    finished := false
+    go func() {
+        v, err := readValue(fsys, path)
+        fr.mu.Lock()
+        finished = true
+        delete(fr.hung, path)
+        fr.mu.Unlock()
+        done <- result{v, err}
+    }()
+    t := time.NewTimer(fr.timeout)
+    defer t.Stop()
+    select {
+    case r := <-done:
+        return r.value, r.err
+    case <-t.C:
+        fr.mu.Lock()
+        if !finished {
+            fr.hung[path] = true
+        }
+        fr.mu.Unlock()
+        return 0, ErrReadAbandoned
+    }
+}
+
+// ReadFiles reads the sysfs files found by discovery in fsys, the host's when nil, and applies their factor (millidegrees to
+// degrees C...). Files are read by up to workers goroutines so one slow driver does not delay
+// every other sensor; results keep the discovery order. Once ctx is done the remaining files
+// are skipped and reads still blocked in the kernel are abandoned: their result is simply
+// dropped. Each file is also read under the reader's own deadline, none when reader is nil.
+// The files that could not be read or parsed, or whose read was given up, are returned as
+// failures, in discovery order too.
+func ReadFiles(ctx context.Context, fsys FS, source string, sensors []Sensor, workers int, reader *FileReader) (res []Reading, failures ReadErrors) {
+    type result struct {
+        i     int
+        value float64
+        err   error
+    }
+    if reader == nil {
+        reader = NewFileReader(0)
+    }
+    if workers < 1 {
+        workers = 1
+    }
+    if workers > len(sensors) {
+        workers = len(sensors)
+    }
+    // buffered for every sensor so an abandoned worker never blocks on its send
+    results := make(chan result, len(sensors))
+    jobs := make(chan int)
+    var wg sync.WaitGroup
+    for w := 0; w < workers; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range jobs {
+                // missing/permission issues and empty/non-number values only drop this sensor
+                v, err := reader.Read(fsys, sensors[i].Path)
+                results <- result{i, v, err}
+            }
+        }()
+    }
+    go func() {
+        defer close(jobs)
+        for i := range sensors {
+            select {
+            case jobs <- i:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    done := make(chan struct{})
+    go func() {
+        wg.Wait()
+        close(done)
+    }()
+
+    values := make([]float64, len(sensors))
+    ok := make([]bool, len(sensors))
+    errs := make([]error, len(sensors))
+    collect := func(r result) {
+        if r.err != nil {
+            errs[r.i] = r.err
+            return
+        }
+        values[r.i], ok[r.i] = r.value, true
+    }
+wait:
+    for {
+        select {
+        case r := <-results:
+            collect(r)
+        case <-done:
+            break wait
+        case <-ctx.Done():
+            break wait
+        }
+    }
+    // keep what finished before the workers exited or the deadline hit
+    for drained := false; !drained; {
+        select {
+        case r := <-results:
+            collect(r)
+        default:
+            drained = true
+        }
+    }
+
+    now := time.Now()
+    for i, s := range sensors {
+        if errs[i] != nil {
+            failures = append(failures, ReadError{Chip: s.Chip, Name: s.Name, Label: s.Label, Path: s.Path, Err: errs[i]})
+        }
+        if !ok[i] {
+            continue
+        }
+        res = append(res, Reading{Source: source, Path: s.Path, Chip: s.Chip, Name: s.Name, Label: s.Label, Driver: s.Driver, Device: s.Device, Value: values[i] * s.Factor, Factor: s.Factor, Kind: s.Kind, At: now})
+    }
+    return res, failures
+}
@@ -0,0 +1,198 @@
+package sources
+
+import (
+    "context"
+    "errors"
+    "math"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+    "testing/fstest"
+    "time"
+)
+
+// firstLineCases are file contents with the first line the previous Scanner based reader returned
+var firstLineCases = []struct {
+    name    string
+    content string
+    want    string
+    err     error
+}{
+    {"value", "45000\n", "45000", nil},
+    {"no trailing newline", "45000", "45000", nil},
+    {"multi-line", "45000\n46000\n", "45000", nil},
+    {"surrounding spaces", " \t45000 \n", "45000", nil},
+    {"crlf", "45000\r\n", "45000", nil},
+    {"label", "Package id 0\n", "Package id 0", nil},
+    {"blank first line", "\n45000\n", "", nil},
+    {"empty", "", "", ErrEmptyFile},
+    {"longer than the buffer", strings.Repeat("7", lineBufSize+10) + "\nx\n", strings.Repeat("7", lineBufSize+10), nil},
+    {"exactly the buffer", strings.Repeat("8", lineBufSize), strings.Repeat("8", lineBufSize), nil},
+}
+
+func TestReadFirstLine(t *testing.T) {
+    for _, tt := range firstLineCases {
+        t.Run(tt.name, func(t *testing.T) {
+            fsys := FromFS(fstest.MapFS{"f": file(tt.content)})
+            got, err := readFirstLine(fsys, "/f")
+            if got != tt.want || !errors.Is(err, tt.err) {
+                t.Errorf("readFirstLine() = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+            }
+            // same answer as the Scanner it replaced
+            if sGot, sErr := scanFirstLine(fsys, "/f"); sGot != got || !errors.Is(sErr, tt.err) {
+                t.Errorf("scanFirstLine() = %q, %v, differs from readFirstLine()", sGot, sErr)
+            }
+        })
+    }
+}
+
+func TestReadValue(t *testing.T) {
+    tests := []struct {
+        name    string
+        content string
+        want    float64
+        wantErr bool
+    }{
+        {"millidegrees", "45125\n", 45125, false},
+        {"negative", "-5500\n", -5500, false},
+        {"plus sign", "+12\n", 12, false},
+        {"decimal", "45.5\n", 45.5, false},
+        {"exponent", "4.5e4\n", 45000, false},
+        {"multi-line", "45000\nfoo\n", 45000, false},
+        {"beyond int64 fast path", "1234567890123456789\n", 1234567890123456789, false},
+        {"empty", "", 0, true},
+        {"blank line", "\n", 0, true},
+        {"sign only", "-\n", 0, true},
+        {"not a number", "N/A\n", 0, true},
+        {"long line", strings.Repeat("0", lineBufSize) + "45\n", 45, false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := readValue(FromFS(fstest.MapFS{"f": file(tt.content)}), "/f")
+            if tt.wantErr {
+                if err == nil {
+                    t.Errorf("readValue() = %v, want an error", got)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("readValue() error = %v", err)
+            }
+            if got != tt.want {
+                t.Errorf("readValue() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseValueMatchesParseFloat(t *testing.T) {
+    for _, s := range []string{"0", "-0", "7", "-128000", "+45000", "999999999999999999", "0012", "1.5", "-.5", "1e3", "0x1p4", "NaN", "Inf", "12a", "", "-", "+"} {
+        got, err := parseValue([]byte(s))
+        want, wantErr := strconv.ParseFloat(s, 64)
+        if (err != nil) != (wantErr != nil) || (err == nil && got != want && !(math.IsNaN(got) && math.IsNaN(want))) {
+            t.Errorf("parseValue(%q) = %v, %v; strconv.ParseFloat gives %v, %v", s, got, err, want, wantErr)
+        }
+    }
+}
+
+// sensorFile writes a typical temp*_input in a temp dir and returns its path
+func sensorFile(b *testing.B) string {
+    b.Helper()
+    path := filepath.Join(b.TempDir(), "temp1_input")
+    if err := os.WriteFile(path, []byte("45125\n"), 0o644); err != nil {
+        b.Fatal(err)
+    }
+    return path
+}
+
+func BenchmarkReadValue(b *testing.B) {
+    path := sensorFile(b)
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        if _, err := ReadValue(nil, path); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+// BenchmarkReadValueScanner is the bufio.Scanner and strconv.ParseFloat reader readValue replaced,
+// for comparing allocations
+func BenchmarkReadValueScanner(b *testing.B) {
+    path := sensorFile(b)
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        line, err := scanFirstLine(OS, path)
+        if err != nil {
+            b.Fatal(err)
+        }
+        if _, err := strconv.ParseFloat(line, 64); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+func BenchmarkParseValue(b *testing.B) {
+    line := []byte("45125")
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        if _, err := parseValue(line); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
+// syntheticHwmon writes chips hwmon directories of channels temp*_input each, as a big
+// dual-socket host with a shelf of drivetemp disks has, and returns their sensors
+func syntheticHwmon(tb testing.TB, chips, channels int) []Sensor {
+    tb.Helper()
+    root := tb.TempDir()
+    var sensors []Sensor
+    for c := 0; c < chips; c++ {
+        dir := filepath.Join(root, "hwmon"+strconv.Itoa(c))
+        if err := os.Mkdir(dir, 0o755); err != nil {
+            tb.Fatal(err)
+        }
+        for ch := 1; ch <= channels; ch++ {
+            path := filepath.Join(dir, "temp"+strconv.Itoa(ch)+"_input")
+            if err := os.WriteFile(path, []byte(strconv.Itoa(30000+c*100+ch)+"\n"), 0o644); err != nil {
+                tb.Fatal(err)
+            }
+            sensors = append(sensors, Sensor{Chip: "hwmon" + strconv.Itoa(c), Path: path, Factor: 0.001})
+        }
+    }
+    return sensors
+}
+
+// TestReadFilesOrder checks the pool returns the readings in sensor order whatever the workers
+func TestReadFilesOrder(t *testing.T) {
+    sensors := syntheticHwmon(t, 10, 30)
+    for _, workers := range []int{1, 8, 1000} {
+        rs, failures := ReadFiles(context.Background(), OS, "hwmon", sensors, workers, nil)
+        if len(failures) != 0 || len(rs) != len(sensors) {
+            t.Fatalf("workers %d: %d readings, failures %v; want %d readings", workers, len(rs), failures, len(sensors))
+        }
+        for i, r := range rs {
+            if r.Path != sensors[i].Path {
+                t.Fatalf("workers %d: reading %d is %s, want %s", workers, i, r.Path, sensors[i].Path)
+            }
+        }
+    }
+}
+
+// BenchmarkReadFiles compares sequential and pooled reads of a few hundred sensor files
+func BenchmarkReadFiles(b *testing.B) {
+    sensors := syntheticHwmon(b, 12, 25)
+    for _, workers := range []int{1, 8} {
+        b.Run("workers="+strconv.Itoa(workers), func(b *testing.B) {
+            reader := NewFileReader(time.Second)
+            b.ReportAllocs()
+            for i := 0; i < b.N; i++ {
+                if _, failures := ReadFiles(context.Background(), OS, "hwmon", sensors, workers, reader); len(failures) != 0 {
+                    b.Fatal(failures)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,112 @@
+package sources
+
+import (
+    "io/fs"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+)
+
+// FS is the part of the filesystem the sysfs sources scan and read. Names are the host paths
+// given in Paths (/sys/class/hwmon...), symlinks included: class directories are made of them.
+type FS interface {
+    Open(name string) (fs.File, error)
+    ReadDir(name string) ([]fs.DirEntry, error)
+    Stat(name string) (fs.FileInfo, error)
+    Readlink(name string) (string, error)
+    EvalSymlinks(name string) (string, error)
+}
+
+// OS is the filesystem of the running host, used when a source has no FS
+var OS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+    return os.Open(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+    return os.ReadDir(name)
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+    return os.Stat(name)
+}
+
+func (osFS) Readlink(name string) (string, error) {
+    return os.Readlink(name)
+}
+
+func (osFS) EvalSymlinks(name string) (string, error) {
+    return filepath.EvalSymlinks(name)
+}
+
+// FromFS serves the host paths from fsys, whose root stands for /, e.g. an fstest.MapFS or a
+// sysfs tree archived from another machine. fs.FS knows nothing of symlinks: every path
+// resolves to itself and Readlink fails, so the driver label stays empty and a chip with a
+// device directory gets the device label "device".
+func FromFS(fsys fs.FS) FS {
+    return ioFS{fsys}
+}
+
+type ioFS struct {
+    fsys fs.FS
+}
+
+// name turns a host path into an fs.FS one: cleaned, slash separated, without the leading /
+func (f ioFS) name(op, p string) (string, error) {
+    n := strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "/")
+    if n == "" {
+        n = "."
+    }
+    if !fs.ValidPath(n) {
+        return "", &fs.PathError{Op: op, Path: p, Err: fs.ErrInvalid}
+    }
+    return n, nil
+}
+
+func (f ioFS) Open(p string) (fs.File, error) {
+    n, err := f.name("open", p)
+    if err != nil {
+        return nil, err
+    }
+    return f.fsys.Open(n)
+}
+
+func (f ioFS) ReadDir(p string) ([]fs.DirEntry, error) {
+    n, err := f.name("readdir", p)
+    if err != nil {
+        return nil, err
+    }
+    return fs.ReadDir(f.fsys, n)
+}
+
+func (f ioFS) Stat(p string) (fs.FileInfo, error) {
+    n, err := f.name("stat", p)
+    if err != nil {
+        return nil, err
+    }
+    return fs.Stat(f.fsys, n)
+}
+
+func (f ioFS) Readlink(p string) (string, error) {
+    return "", &fs.PathError{Op: "readlink", Path: p, Err: fs.ErrInvalid}
+}
+
+// EvalSymlinks checks that p exists and returns it cleaned
+func (f ioFS) EvalSymlinks(p string) (string, error) {
+    if _, err := f.Stat(p); err != nil {
+        return "", err
+    }
+    return filepath.Clean(p), nil
+}
+
+// orOS returns fsys, or OS when it is nil
+func orOS(fsys FS) FS {
+    if fsys == nil {
+        return OS
+    }
+    return fsys
+}
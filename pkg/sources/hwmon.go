@@ -0,0 +1,199 @@
+package sources
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "path/filepath"
+    "strings"
+)
+
+// Sensor is a sysfs file found by discovery, with an optional label name (e.g., CPU, GPU, etc.)
+type Sensor struct {
+    Chip   string  // hwmon chip directory name
+    Name   string  // sensor name from name file when available
+    Label  string  // content of temp*_label when present
+    Path   string  // path to temp*_input
+    Driver string  // kernel driver bound to the chip's device, empty for virtual chips
+    Device string  // resolved device link (PCI address, USB path...), empty for virtual chips
+    Factor float64 // multiplier (usually 0.001) to convert millidegree C to degree C
+    Kind   Kind    // thresholds (temp*_max, temp*_crit...) share the labels of their input
+}
+
+// Hwmon reads the temperatures, their thresholds and the energy counters of the hwmon class
+type Hwmon struct {
+    Paths           []string        // base directories, /sys/class/hwmon by default
+    FS              FS              // filesystem holding Paths, OS when nil
+    IncludeDisabled bool            // keep the channels whose *_enable reads 0
+    Workers         int             // files read in parallel
+    Reader          *FileReader     // per file deadline, nil for none
+    Cache           *DiscoveryCache // nil scans on every call
+}
+
+// Name implements Source
+func (h *Hwmon) Name() string {
+    return "hwmon"
+}
+
+// Discover lists the sensor files of every base directory, from the cache while it is fresh
+func (h *Hwmon) Discover(ctx context.Context) ([]Sensor, error) {
+    paths := h.Paths
+    if len(paths) == 0 {
+        paths = []string{"/sys/class/hwmon"}
+    }
+    discover := func() ([]Sensor, error) {
+        return discoverPaths(ctx, paths, func(ctx context.Context, basePath string) ([]Sensor, error) {
+            return discoverHwmon(ctx, orOS(h.FS), basePath, h.IncludeDisabled)
+        })
+    }
+    if h.Cache == nil {
+        return discover()
+    }
+    return h.Cache.get(discover)
+}
+
+// Read reads the sensors returned by Discover, possibly narrowed down by the caller
+func (h *Hwmon) Read(ctx context.Context, sensors []Sensor) ([]Reading, ReadErrors) {
+    return ReadFiles(ctx, h.FS, h.Name(), sensors, h.Workers, h.Reader)
+}
+
+// Readings implements Source
+func (h *Hwmon) Readings(ctx context.Context) ([]Reading, error) {
+    s, err := h.Discover(ctx)
+    rs, failures := h.Read(ctx, s)
+    return rs, failures.join(err)
+}
+
+// discoverHwmon scans basePath (default /sys/class/hwmon) to find temp*_input files and their labels.
+// Channels switched off through temp*_enable are skipped unless includeDisabled is set.
+// When ctx expires the chips scanned so far are returned with ctx's error.
+func discoverHwmon(ctx context.Context, fsys FS, basePath string, includeDisabled bool) ([]Sensor, error) {
+    var sensors []Sensor
+    // iterate hwmon devices
+    entries, err := fsys.ReadDir(basePath)
+    if err != nil {
+        return sensors, err
+    }
+    visited := make(map[string]bool)
+    for _, e := range entries {
+        if err := ctx.Err(); err != nil {
+            return sensors, err
+        }
+        // hwmonN entries are symlinks to the device directories
+        chipDir := filepath.Join(basePath, e.Name())
+        if !visitDir(fsys, chipDir, visited) {
+            continue
+        }
+        // try to obtain a human friendly chip name
+        chipName := e.Name()
+        if n, err := readFirstLine(fsys, filepath.Join(chipDir, "name")); err == nil && n != "" {
+            chipName = n
+        }
+        driver, device := deviceDriver(fsys, chipDir), deviceName(fsys, chipDir)
+
+        // list files to find temp*_input
+        files, err := fsys.ReadDir(chipDir)
+        if err != nil {
+            // ignore unreadable chips, continue
+            continue
+        }
+        present := make(map[string]bool, len(files))
+        for _, f := range files {
+            present[f.Name()] = true
+        }
+        // sensor name from chip name
+        sensorName := chipName
+        for _, f := range files {
+            fname := f.Name()
+            if !strings.HasSuffix(fname, "_input") {
+                continue
+            }
+            // energyN_input: cumulative microjoules (amdgpu, some BMC bridges)
+            if strings.HasPrefix(fname, "energy") {
+                channel := strings.TrimSuffix(fname, "_input")
+                if !includeDisabled && channelDisabled(fsys, chipDir, channel) {
+                    slog.Debug("canal hwmon désactivé ignoré", "path", filepath.Join(chipDir, fname))
+                    continue
+                }
+                // unlabeled channels keep their name so energy1 and energy2 stay apart
+                label := channel
+                if l, err := readFirstLine(fsys, filepath.Join(chipDir, channel+"_label")); err == nil && l != "" {
+                    label = l
+                }
+                sensors = append(sensors, Sensor{
+                    Chip:   chipName,
+                    Name:   sensorName,
+                    Label:  label,
+                    Path:   filepath.Join(chipDir, fname),
+                    Driver: driver,
+                    Device: device,
+                    Factor: 1e-6,
+                    Kind:   KindEnergy,
+                })
+                continue
+            }
+            if !strings.HasPrefix(fname, "temp") {
+                continue
+            }
+            // extract index between temp and _input
+            idx := strings.TrimSuffix(strings.TrimPrefix(fname, "temp"), "_input")
+            // a disabled channel keeps returning a stale or zero value
+            if !includeDisabled && channelDisabled(fsys, chipDir, "temp"+idx) {
+                slog.Debug("canal hwmon désactivé ignoré", "path", filepath.Join(chipDir, fname))
+                continue
+            }
+            label := ""
+            // prefer temp{idx}_label when available
+            if l, err := readFirstLine(fsys, filepath.Join(chipDir, fmt.Sprintf("temp%v_label", idx))); err == nil {
+                label = l
+            } else if tname, err := readFirstLine(fsys, filepath.Join(chipDir, fmt.Sprintf("temp%v_type", idx))); err == nil {
+                // fallback to type (like Tctl, Tdie)
+                label = tname
+            }
+            sensors = append(sensors, Sensor{
+                Chip:   chipName,
+                Name:   sensorName,
+                Label:  label,
+                Path:   filepath.Join(chipDir, fname),
+                Driver: driver,
+                Device: device,
+                Factor: 0.001, // default millidegree to degree
+            })
+            for _, t := range thresholdSuffixes {
+                tname := fmt.Sprintf("temp%v_%s", idx, t.suffix)
+                if !present[tname] {
+                    continue
+                }
+                sensors = append(sensors, Sensor{
+                    Chip:   chipName,
+                    Name:   sensorName,
+                    Label:  label,
+                    Path:   filepath.Join(chipDir, tname),
+                    Driver: driver,
+                    Device: device,
+                    Factor: 0.001,
+                    Kind:   t.kind,
+                })
+            }
+        }
+    }
+    return sensors, nil
+}
+
+// channelDisabled reports whether the hwmon channel (temp1, fan2, in0...) of chipDir has an
+// enable attribute reading 0. Drivers without the attribute are always enabled.
+func channelDisabled(fsys FS, chipDir, channel string) bool {
+    v, err := readFirstLine(fsys, filepath.Join(chipDir, channel+"_enable"))
+    return err == nil && v == "0"
+}
+
+// CountSensors counts discovered sysfs sensors, leaving out their threshold files
+func CountSensors(sensors []Sensor) int {
+    n := 0
+    for _, s := range sensors {
+        if !s.Kind.Threshold() {
+            n++
+        }
+    }
+    return n
+}
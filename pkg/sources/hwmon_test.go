@@ -0,0 +1,191 @@
+package sources
+
+import (
+    "context"
+    "errors"
+    "io/fs"
+    "reflect"
+    "strings"
+    "testing"
+    "testing/fstest"
+)
+
+// file returns a MapFS file holding content
+func file(content string) *fstest.MapFile {
+    return &fstest.MapFile{Data: []byte(content)}
+}
+
+// hwmonTree is a hwmon class with the layouts discovery has to cope with
+func hwmonTree() fstest.MapFS {
+    return fstest.MapFS{
+        // labelled channels and a threshold
+        "sys/class/hwmon/hwmon0/name":        file("k10temp\n"),
+        "sys/class/hwmon/hwmon0/temp1_input": file("45125\n"),
+        "sys/class/hwmon/hwmon0/temp1_label": file("Tctl\n"),
+        "sys/class/hwmon/hwmon0/temp1_crit":  file("90000\n"),
+        "sys/class/hwmon/hwmon0/temp3_input": file("41000\n"),
+        "sys/class/hwmon/hwmon0/temp3_label": file("Tccd1\n"),
+        // no name file, an unlabelled channel and one typed instead of labelled
+        "sys/class/hwmon/hwmon1/temp1_input": file("38850\n"),
+        "sys/class/hwmon/hwmon1/temp2_input": file("52000\n"),
+        "sys/class/hwmon/hwmon1/temp2_type":  file("Tdie\n"),
+        // a disabled channel, an empty input and one that is not a number
+        "sys/class/hwmon/hwmon2/name":         file("nct6798\n"),
+        "sys/class/hwmon/hwmon2/temp1_input":  file("31000\n"),
+        "sys/class/hwmon/hwmon2/temp1_label":  file("SYSTIN\n"),
+        "sys/class/hwmon/hwmon2/temp2_input":  file("0\n"),
+        "sys/class/hwmon/hwmon2/temp2_enable": file("0\n"),
+        "sys/class/hwmon/hwmon2/temp3_input":  file(""),
+        "sys/class/hwmon/hwmon2/temp4_input":  file("N/A\n"),
+        // a chip without any temperature
+        "sys/class/hwmon/hwmon3/name":       file("acpi_fan\n"),
+        "sys/class/hwmon/hwmon3/fan1_input": file("1200\n"),
+    }
+}
+
+func TestHwmonDiscover(t *testing.T) {
+    dir := "/sys/class/hwmon/"
+    enabled := []Sensor{
+        {Chip: "k10temp", Name: "k10temp", Label: "Tctl", Path: dir + "hwmon0/temp1_input", Factor: 0.001},
+        {Chip: "k10temp", Name: "k10temp", Label: "Tctl", Path: dir + "hwmon0/temp1_crit", Factor: 0.001, Kind: KindCrit},
+        {Chip: "k10temp", Name: "k10temp", Label: "Tccd1", Path: dir + "hwmon0/temp3_input", Factor: 0.001},
+        {Chip: "hwmon1", Name: "hwmon1", Path: dir + "hwmon1/temp1_input", Factor: 0.001},
+        {Chip: "hwmon1", Name: "hwmon1", Label: "Tdie", Path: dir + "hwmon1/temp2_input", Factor: 0.001},
+        {Chip: "nct6798", Name: "nct6798", Label: "SYSTIN", Path: dir + "hwmon2/temp1_input", Factor: 0.001},
+    }
+    empty := Sensor{Chip: "nct6798", Name: "nct6798", Path: dir + "hwmon2/temp3_input", Factor: 0.001}
+    garbage := Sensor{Chip: "nct6798", Name: "nct6798", Path: dir + "hwmon2/temp4_input", Factor: 0.001}
+    disabled := Sensor{Chip: "nct6798", Name: "nct6798", Path: dir + "hwmon2/temp2_input", Factor: 0.001}
+
+    tests := []struct {
+        name            string
+        includeDisabled bool
+        want            []Sensor
+    }{
+        {"disabled channels skipped", false, append(enabled[:len(enabled):len(enabled)], empty, garbage)},
+        {"disabled channels included", true, append(enabled[:len(enabled):len(enabled)], disabled, empty, garbage)},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            h := &Hwmon{Paths: []string{"/sys/class/hwmon"}, FS: FromFS(hwmonTree()), IncludeDisabled: tt.includeDisabled}
+            got, err := h.Discover(context.Background())
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Discover() =\n%s\nwant\n%s", sensorList(got), sensorList(tt.want))
+            }
+        })
+    }
+}
+
+func TestHwmonReadings(t *testing.T) {
+    h := &Hwmon{Paths: []string{"/sys/class/hwmon"}, FS: FromFS(hwmonTree()), Workers: 4}
+    rs, err := h.Readings(context.Background())
+    failures, err := SplitErrors(err)
+    if err != nil {
+        t.Fatalf("Readings() source error = %v", err)
+    }
+    got := make(map[string]float64)
+    for _, r := range rs {
+        if r.Source != "hwmon" || r.At.IsZero() {
+            t.Errorf("reading %+v: want source hwmon and a read time", r)
+        }
+        got[r.Path] = r.Value
+    }
+    want := map[string]float64{
+        "/sys/class/hwmon/hwmon0/temp1_input": 45.125,
+        "/sys/class/hwmon/hwmon0/temp1_crit":  90,
+        "/sys/class/hwmon/hwmon0/temp3_input": 41,
+        "/sys/class/hwmon/hwmon1/temp1_input": 38.85,
+        "/sys/class/hwmon/hwmon1/temp2_input": 52,
+        "/sys/class/hwmon/hwmon2/temp1_input": 31,
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("Readings() values = %v, want %v", got, want)
+    }
+    if len(failures) != 2 {
+        t.Fatalf("Readings() failures = %v, want the empty and the non-numeric input", failures)
+    }
+    if f := failures[0]; f.Path != "/sys/class/hwmon/hwmon2/temp3_input" || !errors.Is(f, ErrEmptyFile) {
+        t.Errorf("failure %v: want temp3_input with ErrEmptyFile", f)
+    }
+    if f := failures[1]; f.Path != "/sys/class/hwmon/hwmon2/temp4_input" || errors.Is(f, ErrEmptyFile) {
+        t.Errorf("failure %v: want temp4_input with a parse error", f)
+    }
+}
+
+// TestHwmonReadMissingFile reads a sensor removed after discovery: only that sensor fails
+func TestHwmonReadMissingFile(t *testing.T) {
+    tree := hwmonTree()
+    h := &Hwmon{Paths: []string{"/sys/class/hwmon"}, FS: FromFS(tree)}
+    sensors, err := h.Discover(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    delete(tree, "sys/class/hwmon/hwmon0/temp3_input")
+    rs, failures := h.Read(context.Background(), sensors)
+    if len(rs) != 5 {
+        t.Errorf("Read() returned %d readings, want the 5 others", len(rs))
+    }
+    var missing []string
+    for _, f := range failures {
+        if errors.Is(f, fs.ErrNotExist) {
+            missing = append(missing, f.Path)
+        }
+    }
+    if want := []string{"/sys/class/hwmon/hwmon0/temp3_input"}; !reflect.DeepEqual(missing, want) {
+        t.Errorf("missing files = %v, want %v", missing, want)
+    }
+}
+
+// denyFS fails every ReadDir of one directory as an unreadable chip would
+type denyFS struct {
+    FS
+    dir string
+}
+
+func (d denyFS) ReadDir(name string) ([]fs.DirEntry, error) {
+    if name == d.dir {
+        return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+    }
+    return d.FS.ReadDir(name)
+}
+
+func TestHwmonDiscoverSkipsUnreadableChip(t *testing.T) {
+    h := &Hwmon{Paths: []string{"/sys/class/hwmon"}, FS: denyFS{FromFS(hwmonTree()), "/sys/class/hwmon/hwmon0"}}
+    sensors, err := h.Discover(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, s := range sensors {
+        if s.Chip == "k10temp" {
+            t.Errorf("sensor %+v of the unreadable chip discovered", s)
+        }
+    }
+    if len(sensors) != 5 {
+        t.Errorf("Discover() found %d sensors, want the 5 of the readable chips", len(sensors))
+    }
+}
+
+func TestHwmonDiscoverMissingBase(t *testing.T) {
+    h := &Hwmon{Paths: []string{"/sys/class/hwmon", "/host/sys/class/hwmon"}, FS: FromFS(hwmonTree())}
+    if sensors, err := h.Discover(context.Background()); err != nil || len(sensors) == 0 {
+        t.Errorf("Discover() with one missing base = %d sensors, %v; want the other base read", len(sensors), err)
+    }
+    h.Paths = []string{"/host/sys/class/hwmon"}
+    if _, err := h.Discover(context.Background()); !errors.Is(err, fs.ErrNotExist) {
+        t.Errorf("Discover() without any base = %v, want fs.ErrNotExist", err)
+    }
+}
+
+// sensorList formats sensors one per line for failure messages
+func sensorList(sensors []Sensor) string {
+    var b strings.Builder
+    for _, s := range sensors {
+        b.WriteString("  ")
+        b.WriteString(s.Chip + " " + s.Name + " " + s.Label + " " + s.Path + " " + s.Kind.String())
+        b.WriteString("\n")
+    }
+    return b.String()
+}
@@ -0,0 +1,306 @@
+package sources
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log/slog"
+    "os/exec"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "time"
+)
+
+// sensorsInputs maps the <type>N_input keys of sensors -j to the kind they feed
+var sensorsInputs = []struct {
+    re   *regexp.Regexp
+    kind Kind
+}{
+    {regexp.MustCompile(`^(temp)(\d+)_input$`), KindTemperature},
+    {regexp.MustCompile(`^(fan)(\d+)_input$`), KindFan},
+    {regexp.MustCompile(`^(in)(\d+)_input$`), KindVoltage},
+}
+
+// SensorsCLI reads temperatures, fans and voltages through the sensors command of lm-sensors
+type SensorsCLI struct {
+    Path    string   // sensors binary, looked up in $PATH when empty
+    Format  string   // auto (the default when empty), json or raw
+    Config  string   // passed with -c when set
+    Args    []string // extra arguments, typically chip names like nct6798-*
+    Timeout time.Duration
+    // raw is set once auto mode found that the installed sensors does not know -j
+    raw atomic.Bool
+}
+
+// Name implements Source
+func (s *SensorsCLI) Name() string {
+    return "sensors-cli"
+}
+
+// path returns the sensors binary to run
+func (s *SensorsCLI) path() string {
+    if s.Path == "" {
+        return "sensors"
+    }
+    return s.Path
+}
+
+// auto reports whether -j is tried first, falling back to -u on an lm-sensors without it
+func (s *SensorsCLI) auto() bool {
+    return s.Format == "" || s.Format == "auto"
+}
+
+// sensorsArgs builds the argv for sensors: optional -c config, the output mode flag, then the user's
+// extra arguments (typically chip names like nct6798-*). No shell is involved.
+func sensorsArgs(mode, config string, extra []string) []string {
+    var args []string
+    if config != "" {
+        args = append(args, "-c", config)
+    }
+    args = append(args, mode)
+    return append(args, extra...)
+}
+
+// Readings implements Source: it runs `sensors -j` and parses temperatures, fans and voltages
+// generically. In auto format an lm-sensors too old for -j (Debian 10 era) is detected from
+// its error message and `sensors -u` is used from then on. Inputs whose value is not a number
+// are returned as read errors.
+func (s *SensorsCLI) Readings(ctx context.Context) ([]Reading, error) {
+    var (
+        res      []Reading
+        failures ReadErrors
+        err      error
+    )
+    if s.Format == "raw" || (s.auto() && s.raw.Load()) {
+        res, failures, err = s.readRaw(ctx)
+    } else {
+        res, failures, err = s.readJSON(ctx)
+    }
+    for i := range res {
+        res[i].Source = s.Name()
+    }
+    return res, failures.join(err)
+}
+
+func (s *SensorsCLI) readJSON(ctx context.Context) ([]Reading, ReadErrors, error) {
+    out, err := RunCommand(ctx, s.Timeout, s.path(), sensorsArgs("-j", s.Config, s.Args)...)
+    if err != nil {
+        if s.auto() && unknownFlagError(err) {
+            slog.Info("sensors ne supporte pas -j, bascule sur 'sensors -u'")
+            s.raw.Store(true)
+            return s.readRaw(ctx)
+        }
+        return nil, nil, err
+    }
+    return parseSensorsJSON(out)
+}
+
+// unknownFlagError reports whether sensors rejected its command line ("invalid option -- 'j'")
+func unknownFlagError(err error) bool {
+    var ee *exec.ExitError
+    if !errors.As(err, &ee) {
+        return false
+    }
+    msg := strings.ToLower(string(ee.Stderr))
+    return strings.Contains(msg, "invalid option") || strings.Contains(msg, "unrecognized option") || strings.Contains(msg, "unknown option")
+}
+
+// readRaw runs `sensors -u`, the raw output supported by every lm-sensors version.
+func (s *SensorsCLI) readRaw(ctx context.Context) ([]Reading, ReadErrors, error) {
+    out, err := RunCommand(ctx, s.Timeout, s.path(), sensorsArgs("-u", s.Config, s.Args)...)
+    if err != nil {
+        return nil, nil, err
+    }
+    res, failures := parseSensorsRaw(out)
+    return res, failures, nil
+}
+
+// parseSensorsRaw parses `sensors -u` output into the same chip → section → key layout as -j
+// and extracts readings with the same rules:
+//
+//  k10temp-pci-00c3
+//  Adapter: PCI adapter
+//  Tctl:
+//    temp1_input: 45.000
+func parseSensorsRaw(out []byte) ([]Reading, ReadErrors) {
+    var res []Reading
+    var failures ReadErrors
+    var (
+        chip     string
+        adapter  string
+        sections map[string]map[string]interface{}
+        order    []string
+        current  map[string]interface{}
+    )
+    flush := func() {
+        start := len(res)
+        for _, name := range order {
+            res = append(res, sectionInputs(chip, name, sections[name], &failures)...)
+        }
+        setAdapter(res[start:], adapter)
+    }
+    s := bufio.NewScanner(bytes.NewReader(out))
+    for s.Scan() {
+        line := s.Text()
+        trimmed := strings.TrimSpace(line)
+        switch {
+        case trimmed == "":
+            // blank line ends the chip
+            flush()
+            chip, adapter, sections, order, current = "", "", nil, nil, nil
+        case line[0] == ' ' || line[0] == '\t':
+            if current == nil {
+                continue
+            }
+            k, v, ok := strings.Cut(trimmed, ":")
+            if !ok {
+                continue
+            }
+            // values that are not numbers stay strings so the input is reported as a failure
+            if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+                current[strings.TrimSpace(k)] = f
+            } else {
+                current[strings.TrimSpace(k)] = strings.TrimSpace(v)
+            }
+        case chip == "":
+            chip = trimmed
+            sections = map[string]map[string]interface{}{}
+        case strings.HasSuffix(trimmed, ":"):
+            name := strings.TrimSuffix(trimmed, ":")
+            current = map[string]interface{}{}
+            sections[name] = current
+            order = append(order, name)
+        case strings.HasPrefix(trimmed, "Adapter:"):
+            adapter = strings.TrimSpace(strings.TrimPrefix(trimmed, "Adapter:"))
+            current = nil
+        default:
+            // other chip level lines
+            current = nil
+        }
+    }
+    flush()
+    return res, failures
+}
+
+// parseSensorsJSON walks the sensors -j document. The usual layout is chip → section → tempN_input,
+// but some chips nest sub-devices (or aliased features) one or more levels deeper, so any object
+// holding tempN_input (or fanN_input, inN_input) keys is accepted at any depth. chip is the top-level key and sensor the path
+// of keys below it joined with "/", which for the flat layout is simply the section name.
+func parseSensorsJSON(out []byte) ([]Reading, ReadErrors, error) {
+    var root map[string]interface{}
+    if err := json.Unmarshal(out, &root); err != nil {
+        return nil, nil, err
+    }
+    var res []Reading
+    var failures ReadErrors
+    // maps are walked in key order: readings keep the same order from one run to the next, which
+    // the collector relies on to name colliding sensors
+    for _, chip := range sortedKeys(root) {
+        m, ok := root[chip].(map[string]interface{})
+        if !ok {
+            continue
+        }
+        start := len(res)
+        for _, section := range sortedKeys(m) {
+            // "Adapter" is a chip attribute, not a sensor group
+            if section == "Adapter" {
+                continue
+            }
+            sm, ok := m[section].(map[string]interface{})
+            if !ok {
+                continue
+            }
+            res = walkSensorsSection(res, chip, []string{section}, sm, &failures)
+        }
+        adapter, _ := m["Adapter"].(string)
+        setAdapter(res[start:], adapter)
+    }
+    return res, failures, nil
+}
+
+// setAdapter records the lm-sensors adapter ("PCI adapter", "ISA adapter", "Virtual device"...) of a chip's readings
+func setAdapter(rs []Reading, adapter string) {
+    for i := range rs {
+        rs[i].Adapter = adapter
+    }
+}
+
+// walkSensorsSection collects the inputs of section and recurses into nested objects.
+// Flat sections (the common case) hold only scalar values and return without recursing.
+func walkSensorsSection(res []Reading, chip string, path []string, section map[string]interface{}, failures *ReadErrors) []Reading {
+    res = append(res, sectionInputs(chip, strings.Join(path, "/"), section, failures)...)
+    // walk nested objects in a stable order so the output does not depend on map iteration
+    for _, k := range sortedKeys(section) {
+        sub, ok := section[k].(map[string]interface{})
+        if !ok {
+            continue
+        }
+        res = walkSensorsSection(res, chip, append(append([]string{}, path...), k), sub, failures)
+    }
+    return res
+}
+
+// sectionInputs extracts tempN_input, fanN_input and inN_input values (already in degree C, RPM
+// and volts), their optional <type>N_label and the tempN_max/crit/crit_hyst/lcrit thresholds.
+// An input that is not a number is appended to failures.
+func sectionInputs(chip, name string, section map[string]interface{}, failures *ReadErrors) []Reading {
+    var res []Reading
+    for _, k := range sortedKeys(section) {
+        val := section[k]
+        for _, in := range sensorsInputs {
+            match := in.re.FindStringSubmatch(k)
+            if match == nil {
+                continue
+            }
+            prefix, idx := match[1], match[2]
+            label := ""
+            if s, ok := section[fmt.Sprintf("%s%s_label", prefix, idx)].(string); ok {
+                label = s
+            }
+            f, ok := jsonFloat(val)
+            if !ok {
+                *failures = append(*failures, ReadError{Chip: chip, Name: name, Label: label, Err: fmt.Errorf("%s: %w", k, ErrNotNumber)})
+                break
+            }
+            res = append(res, Reading{Chip: chip, Name: name, Label: label, Value: f, Kind: in.kind})
+            if in.kind != KindTemperature {
+                break
+            }
+            for _, t := range thresholdSuffixes {
+                if tv, ok := jsonFloat(section[fmt.Sprintf("temp%s_%s", idx, t.suffix)]); ok {
+                    res = append(res, Reading{Chip: chip, Name: name, Label: label, Value: tv, Kind: t.kind})
+                }
+            }
+            break
+        }
+    }
+    return res
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]interface{}) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// jsonFloat accepts the numeric representations encoding/json can produce
+func jsonFloat(val interface{}) (float64, bool) {
+    switch tv := val.(type) {
+    case float64:
+        return tv, true
+    case json.Number:
+        f, err := tv.Float64()
+        return f, err == nil
+    }
+    return 0, false
+}
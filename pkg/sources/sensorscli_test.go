@@ -0,0 +1,269 @@
+package sources
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strings"
+    "testing"
+)
+
+// readingKeys formats readings as "chip|sensor|label|kind|value", sorted since sensors -j is
+// decoded into maps
+func readingKeys(rs []Reading) []string {
+    var keys []string
+    for _, r := range rs {
+        keys = append(keys, fmt.Sprintf("%s|%s|%s|%s|%g", r.Chip, r.Name, r.Label, r.Kind, r.Value))
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func readFixture(t *testing.T, name string) []byte {
+    t.Helper()
+    out, err := os.ReadFile(filepath.Join("testdata", name))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return out
+}
+
+func TestParseSensorsJSON(t *testing.T) {
+    tests := []struct {
+        fixture  string
+        adapter  string
+        want     []string
+        failures []string
+    }{
+        {
+            fixture: "sensors-k10temp.json",
+            adapter: "PCI adapter",
+            want: []string{
+                "k10temp-pci-00c3|Tccd1||temperature|41",
+                "k10temp-pci-00c3|Tccd2||temperature|39.75",
+                "k10temp-pci-00c3|Tctl||temperature|45.25",
+            },
+        },
+        {
+            // the _max_hyst, _alarm, _type, _offset, _beep, intrusion and beep_enable keys are not readings
+            fixture: "sensors-nct6775.json",
+            adapter: "ISA adapter",
+            want: []string{
+                "nct6775-isa-0290|CPUTIN||max|80",
+                "nct6775-isa-0290|CPUTIN||temperature|40.5",
+                "nct6775-isa-0290|SYSTIN||max|80",
+                "nct6775-isa-0290|SYSTIN||temperature|31",
+                "nct6775-isa-0290|Vcore||voltage|0.88",
+                "nct6775-isa-0290|fan2||fan|1139",
+                "nct6775-isa-0290|in1||voltage|1.824",
+            },
+        },
+        {
+            // _min is not a threshold the exporter knows, the 65261.85 max is left to the bounds filter
+            fixture: "sensors-nvme.json",
+            adapter: "PCI adapter",
+            want: []string{
+                "nvme-pci-0100|Composite||crit|84.85",
+                "nvme-pci-0100|Composite||max|81.85",
+                "nvme-pci-0100|Composite||temperature|38.85",
+                "nvme-pci-0100|Sensor 1||max|65261.85",
+                "nvme-pci-0100|Sensor 1||temperature|38.85",
+                "nvme-pci-0100|Sensor 2||max|65261.85",
+                "nvme-pci-0100|Sensor 2||temperature|42.85",
+            },
+        },
+        {
+            // sensor is the path of keys below the chip, inputs next to nested objects are kept
+            fixture: "sensors-nested.json",
+            adapter: "ISA adapter",
+            want: []string{
+                "asus_ec-isa-0000|CPU/Core||temperature|45",
+                "asus_ec-isa-0000|CPU/Socket/Die||crit|95",
+                "asus_ec-isa-0000|CPU/Socket/Die||temperature|47",
+                "asus_ec-isa-0000|VRM/Phase|VRM phase|temperature|55",
+                "asus_ec-isa-0000|VRM||temperature|52",
+            },
+            failures: []string{"asus_ec-isa-0000 Chipset"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(strings.TrimSuffix(tt.fixture, ".json"), func(t *testing.T) {
+            rs, failures, err := parseSensorsJSON(readFixture(t, tt.fixture))
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := readingKeys(rs); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("parseSensorsJSON() =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+            }
+            for _, r := range rs {
+                if r.Adapter != tt.adapter {
+                    t.Errorf("reading %s %s: adapter %q, want %q", r.Chip, r.Name, r.Adapter, tt.adapter)
+                }
+            }
+            var gotFailures []string
+            for _, f := range failures {
+                if !errors.Is(f, ErrNotNumber) {
+                    t.Errorf("failure %v: want ErrNotNumber", f)
+                }
+                gotFailures = append(gotFailures, f.Chip+" "+f.Name)
+            }
+            if !reflect.DeepEqual(gotFailures, tt.failures) {
+                t.Errorf("parseSensorsJSON() failures = %q, want %q", gotFailures, tt.failures)
+            }
+        })
+    }
+}
+
+func TestParseSensorsJSONInvalid(t *testing.T) {
+    for _, out := range []string{"", "sensors: invalid option -- 'j'\n", `["k10temp-pci-00c3"]`} {
+        if _, _, err := parseSensorsJSON([]byte(out)); err == nil {
+            t.Errorf("parseSensorsJSON(%q) succeeded, want an error", out)
+        }
+    }
+}
+
+// fullDumpReadings is what a complete sensors -j (or -u) of one host gives: temperatures with
+// their thresholds, fans in RPM and voltages, power being ignored
+var fullDumpReadings = []string{
+    "acpitz-acpi-0|temp1||crit|20.8",
+    "acpitz-acpi-0|temp1||temperature|16.8",
+    "amdgpu-pci-0b00|edge||crit_hyst|-273.15",
+    "amdgpu-pci-0b00|edge||crit|100",
+    "amdgpu-pci-0b00|edge||temperature|49",
+    "amdgpu-pci-0b00|fan1||fan|824",
+    "amdgpu-pci-0b00|vddgfx||voltage|0.725",
+    "k10temp-pci-00c3|Tccd1||temperature|41",
+    "k10temp-pci-00c3|Tctl||temperature|45.25",
+    "nct6798-isa-0290|CPUTIN||max|80",
+    "nct6798-isa-0290|CPUTIN||temperature|40.5",
+    "nct6798-isa-0290|SYSTIN||max|80",
+    "nct6798-isa-0290|SYSTIN||temperature|31",
+    "nct6798-isa-0290|fan1||fan|0",
+    "nct6798-isa-0290|fan2||fan|1139",
+    "nct6798-isa-0290|in0||voltage|0.336",
+    "nct6798-isa-0290|in1||voltage|1.824",
+    "nvme-pci-0100|Composite||crit|84.85",
+    "nvme-pci-0100|Composite||max|81.85",
+    "nvme-pci-0100|Composite||temperature|38.85",
+}
+
+func TestParseSensorsJSONFullDump(t *testing.T) {
+    rs, failures, err := parseSensorsJSON(readFixture(t, "sensors-full.json"))
+    if err != nil || len(failures) != 0 {
+        t.Fatalf("parseSensorsJSON() failures = %v, error = %v", failures, err)
+    }
+    if got := readingKeys(rs); !reflect.DeepEqual(got, fullDumpReadings) {
+        t.Errorf("parseSensorsJSON() =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(fullDumpReadings, "\n  "))
+    }
+}
+
+// TestParseSensorsRaw checks sensors -u of the same host gives the readings of sensors -j
+func TestParseSensorsRaw(t *testing.T) {
+    rs, failures := parseSensorsRaw(readFixture(t, "sensors-full-u.txt"))
+    if len(failures) != 0 {
+        t.Fatalf("parseSensorsRaw() failures = %v", failures)
+    }
+    if got := readingKeys(rs); !reflect.DeepEqual(got, fullDumpReadings) {
+        t.Errorf("parseSensorsRaw() =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(fullDumpReadings, "\n  "))
+    }
+    adapters := map[string]string{
+        "k10temp-pci-00c3": "PCI adapter",
+        "nct6798-isa-0290": "ISA adapter",
+        "nvme-pci-0100":    "PCI adapter",
+        "amdgpu-pci-0b00":  "PCI adapter",
+        "acpitz-acpi-0":    "ACPI interface",
+    }
+    for _, r := range rs {
+        if r.Adapter != adapters[r.Chip] {
+            t.Errorf("reading %s %s: adapter %q, want %q", r.Chip, r.Name, r.Adapter, adapters[r.Chip])
+        }
+    }
+}
+
+func TestParseSensorsRawEdgeCases(t *testing.T) {
+    tests := []struct {
+        name     string
+        out      string
+        want     []string
+        failures []string
+    }{
+        {
+            name: "no trailing blank line",
+            out:  "coretemp-isa-0000\nAdapter: ISA adapter\nPackage id 0:\n  temp1_input: 52.000\n  temp1_crit: 100.000",
+            want: []string{"coretemp-isa-0000|Package id 0||crit|100", "coretemp-isa-0000|Package id 0||temperature|52"},
+        },
+        {
+            // several blank lines between chips and a chip without adapter line
+            name: "blank lines",
+            out:  "\n\nacpitz-acpi-0\ntemp1:\n  temp1_input: 27.800\n\n\n\nnvme-pci-0100\nAdapter: PCI adapter\nComposite:\n  temp1_input: 38.850\n",
+            want: []string{"acpitz-acpi-0|temp1||temperature|27.8", "nvme-pci-0100|Composite||temperature|38.85"},
+        },
+        {
+            // inputs before any section belong to nothing and are skipped
+            name: "input outside a section",
+            out:  "k10temp-pci-00c3\nAdapter: PCI adapter\n  temp1_input: 45.000\nTctl:\n  temp1_input: 45.250\n",
+            want: []string{"k10temp-pci-00c3|Tctl||temperature|45.25"},
+        },
+        {
+            name:     "value not a number",
+            out:      "it8686-isa-0a40\nAdapter: ISA adapter\ntemp3:\n  temp3_input: N/A\nfan1:\n  fan1_input: 2045.000\n",
+            want:     []string{"it8686-isa-0a40|fan1||fan|2045"},
+            failures: []string{"it8686-isa-0a40 temp3"},
+        },
+        {
+            name: "empty",
+            out:  "",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rs, failures := parseSensorsRaw([]byte(tt.out))
+            if got := readingKeys(rs); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("parseSensorsRaw() = %q, want %q", got, tt.want)
+            }
+            var gotFailures []string
+            for _, f := range failures {
+                gotFailures = append(gotFailures, f.Chip+" "+f.Name)
+            }
+            if !reflect.DeepEqual(gotFailures, tt.failures) {
+                t.Errorf("parseSensorsRaw() failures = %q, want %q", gotFailures, tt.failures)
+            }
+        })
+    }
+}
+
+// TestParseSensorsJSONOrder parses the same output repeatedly: the readings must come in the
+// same order every time, chips, sections and inputs sorted, whatever the map iteration order
+func TestParseSensorsJSONOrder(t *testing.T) {
+    out := readFixture(t, "sensors-full.json")
+    var first []string
+    for run := 0; run < 50; run++ {
+        rs, _, err := parseSensorsJSON(out)
+        if err != nil {
+            t.Fatal(err)
+        }
+        var got []string
+        for _, r := range rs {
+            got = append(got, fmt.Sprintf("%s|%s|%s|%g", r.Chip, r.Name, r.Kind, r.Value))
+        }
+        if run == 0 {
+            first = got
+            var chips []string
+            for _, r := range rs {
+                if len(chips) == 0 || chips[len(chips)-1] != r.Chip {
+                    chips = append(chips, r.Chip)
+                }
+            }
+            if !sort.StringsAreSorted(chips) || len(chips) != 5 {
+                t.Errorf("parseSensorsJSON() chips in the order %q, want each once and sorted", chips)
+            }
+            continue
+        }
+        if !reflect.DeepEqual(got, first) {
+            t.Fatalf("run %d: parseSensorsJSON() order =\n  %s\nfirst run\n  %s", run, strings.Join(got, "\n  "), strings.Join(first, "\n  "))
+        }
+    }
+}
@@ -0,0 +1,155 @@
+// Package sources reads sensor values from the Linux hwmon and thermal sysfs classes, from
+// the lm-sensors command line and from the FreeBSD temperature sysctls. Each source implements
+// Source; the collector package turns the readings into Prometheus metrics.
+package sources
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+)
+
+// Source produces the current values of a set of sensors
+type Source interface {
+    // Name identifies the source in the source label and the collect[] parameter
+    Name() string
+    // Readings returns the values read now. Sensors that could not be read are reported as a
+    // ReadErrors error along with the readings of the others; any other error means the source
+    // itself failed, possibly after returning part of its readings.
+    Readings(ctx context.Context) ([]Reading, error)
+}
+
+// Reading is a single value read from a sensor
+type Reading struct {
+    Source  string // hwmon, thermal, sensors-cli...
+    Target  string // remote host of the ssh source, empty for local readings
+    Path    string // sysfs file for file based sources, sysctl name for sysctl
+    Chip    string
+    Name    string
+    Label   string
+    Value   float64   // degrees C, RPM, volts or joules depending on Kind
+    Factor  float64   // multiplier applied to the raw sysfs value, 0 for other sources
+    Kind    Kind      // zero value is a plain temperature
+    Adapter string    // lm-sensors adapter, empty for other sources
+    Driver  string    // kernel driver of the sysfs device, empty for other sources and virtual chips
+    Device  string    // sysfs device (PCI address, USB path...), empty for other sources and virtual chips
+    At      time.Time // when the value was read
+}
+
+// Kind selects the metric family a reading is exported to
+type Kind int
+
+const (
+    KindTemperature Kind = iota
+    KindMax
+    KindCrit
+    KindCritHyst
+    KindLowCrit
+    KindFan
+    KindVoltage
+    KindUPSLineVoltage
+    KindUPSLoad
+    KindEnergy
+)
+
+var kindNames = [...]string{
+    KindTemperature:    "temperature",
+    KindMax:            "max",
+    KindCrit:           "crit",
+    KindCritHyst:       "crit_hyst",
+    KindLowCrit:        "lcrit",
+    KindFan:            "fan",
+    KindVoltage:        "voltage",
+    KindUPSLineVoltage: "ups_line_voltage",
+    KindUPSLoad:        "ups_load",
+    KindEnergy:         "energy",
+}
+
+func (k Kind) String() string {
+    if int(k) < len(kindNames) {
+        return kindNames[k]
+    }
+    return "unknown"
+}
+
+// Threshold reports the limits attached to another sensor (max, crit...)
+func (k Kind) Threshold() bool {
+    return k >= KindMax && k <= KindLowCrit
+}
+
+// thresholdSuffixes maps the tempN_<suffix> attributes shared by hwmon and sensors -j to their kind
+var thresholdSuffixes = []struct {
+    suffix string
+    kind   Kind
+}{
+    {"max", KindMax},
+    {"crit", KindCrit},
+    {"crit_hyst", KindCritHyst},
+    {"lcrit", KindLowCrit},
+}
+
+// ErrNotNumber is the error of a sensors command input whose value is not a number
+var ErrNotNumber = errors.New("valeur non numérique")
+
+// ReadError is one sensor value a source could not produce
+type ReadError struct {
+    Chip  string
+    Name  string
+    Label string
+    Path  string // sysfs file, empty for command output
+    Err   error
+}
+
+func (e ReadError) Error() string {
+    if e.Path != "" {
+        return fmt.Sprintf("%s: %v", e.Path, e.Err)
+    }
+    return fmt.Sprintf("%s/%s/%s: %v", e.Chip, e.Name, e.Label, e.Err)
+}
+
+func (e ReadError) Unwrap() error {
+    return e.Err
+}
+
+// ReadErrors lists the sensors a source could not read during one call of Readings
+type ReadErrors []ReadError
+
+func (e ReadErrors) Error() string {
+    if len(e) == 1 {
+        return e[0].Error()
+    }
+    return fmt.Sprintf("%d capteurs illisibles, dont %v", len(e), e[0])
+}
+
+// join returns err with the read errors attached, nil when there is neither
+func (e ReadErrors) join(err error) error {
+    if len(e) == 0 {
+        return err
+    }
+    if err == nil {
+        return e
+    }
+    return errors.Join(err, e)
+}
+
+// SplitErrors separates the error returned by Readings into the sensors that could not be read
+// and the failure of the source itself, nil when the source worked.
+func SplitErrors(err error) (ReadErrors, error) {
+    var failures ReadErrors
+    if !errors.As(err, &failures) {
+        return nil, err
+    }
+    if _, ok := err.(ReadErrors); ok {
+        return failures, nil
+    }
+    var rest []error
+    if j, ok := err.(interface{ Unwrap() []error }); ok {
+        for _, e := range j.Unwrap() {
+            if _, ok := e.(ReadErrors); !ok {
+                rest = append(rest, e)
+            }
+        }
+    }
+    return failures, errors.Join(rest...)
+}
@@ -0,0 +1,218 @@
+package sources
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "fmt"
+    "net"
+    "path"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "golang.org/x/crypto/ssh"
+)
+
+// SSH reads the hwmon temperatures or the sensors -j output of a host that cannot run the
+// exporter (switch, NAS appliance). Each collection is a single command on a new session of a
+// connection kept open between collections and dialed again after an error.
+type SSH struct {
+    Target      string              // target label of the readings
+    Address     string              // host:port
+    User        string
+    Signer      ssh.Signer          // private key of the user
+    HostKey     ssh.HostKeyCallback // checks the server key, typically from known_hosts
+    Mode        string              // hwmon (the default when empty) or sensors
+    HwmonPath   string              // remote hwmon class, /sys/class/hwmon when empty
+    SensorsPath string              // remote sensors command, sensors when empty
+
+    mu     sync.Mutex
+    client *ssh.Client
+}
+
+// Name implements Source
+func (s *SSH) Name() string {
+    return "ssh"
+}
+
+// Readings implements Source. A deadline on ctx bounds the dial, the handshake and the
+// command; when it expires the connection is dropped so the next collection starts afresh.
+func (s *SSH) Readings(ctx context.Context) ([]Reading, error) {
+    var (
+        res      []Reading
+        failures ReadErrors
+    )
+    if s.Mode == "sensors" {
+        sensors := s.SensorsPath
+        if sensors == "" {
+            sensors = "sensors"
+        }
+        out, err := s.run(ctx, shellQuote(sensors)+" -j")
+        if err != nil {
+            return nil, err
+        }
+        if res, failures, err = parseSensorsJSON(out); err != nil {
+            return nil, err
+        }
+    } else {
+        base := s.HwmonPath
+        if base == "" {
+            base = "/sys/class/hwmon"
+        }
+        out, err := s.run(ctx, hwmonDumpCommand(base))
+        if err != nil {
+            return nil, err
+        }
+        res, failures = parseHwmonDump(out, base)
+    }
+    now := time.Now()
+    for i := range res {
+        res[i].Source, res[i].Target, res[i].At = s.Name(), s.Target, now
+    }
+    return res, failures.join(nil)
+}
+
+// hwmonDumpCommand prints every chip name, temperature and label under base as path:value
+// lines in one round trip. grep skips empty files and, with its errors silenced, unreadable
+// ones; only a missing base directory fails.
+func hwmonDumpCommand(base string) string {
+    return "cd " + shellQuote(base) + " || exit 1; grep -H . */name */temp*_input */temp*_label 2>/dev/null; exit 0"
+}
+
+// parseHwmonDump turns the output of hwmonDumpCommand into readings, in the order of the inputs
+func parseHwmonDump(out []byte, base string) ([]Reading, ReadErrors) {
+    type input struct{ dir, file, value string }
+    var inputs []input
+    names := make(map[string]string)
+    labels := make(map[string]string)
+    s := bufio.NewScanner(bytes.NewReader(out))
+    for s.Scan() {
+        p, value, ok := strings.Cut(s.Text(), ":")
+        if !ok {
+            continue
+        }
+        dir, file := path.Split(p)
+        dir = strings.TrimSuffix(dir, "/")
+        value = strings.TrimSpace(value)
+        switch {
+        case file == "name":
+            names[dir] = value
+        case strings.HasSuffix(file, "_label"):
+            labels[dir+"/"+strings.TrimSuffix(file, "_label")] = value
+        case strings.HasSuffix(file, "_input"):
+            inputs = append(inputs, input{dir, file, value})
+        }
+    }
+    var (
+        res      []Reading
+        failures ReadErrors
+    )
+    for _, in := range inputs {
+        chip := in.dir
+        if n := names[in.dir]; n != "" {
+            chip = n
+        }
+        label := labels[in.dir+"/"+strings.TrimSuffix(in.file, "_input")]
+        p := path.Join(base, in.dir, in.file)
+        v, err := strconv.ParseFloat(in.value, 64)
+        if err != nil {
+            failures = append(failures, ReadError{Chip: chip, Name: chip, Label: label, Path: p, Err: fmt.Errorf("%q: %w", in.value, ErrNotNumber)})
+            continue
+        }
+        res = append(res, Reading{Path: p, Chip: chip, Name: chip, Label: label, Value: v * 0.001, Factor: 0.001})
+    }
+    return res, failures
+}
+
+// shellQuote quotes s for the remote POSIX shell
+func shellQuote(s string) string {
+    return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// run executes cmd on a new session and returns its standard output
+func (s *SSH) run(ctx context.Context, cmd string) ([]byte, error) {
+    client, err := s.connect(ctx)
+    if err != nil {
+        return nil, err
+    }
+    sess, err := client.NewSession()
+    if err != nil {
+        s.drop(client)
+        return nil, fmt.Errorf("session: %w", err)
+    }
+    defer sess.Close()
+    var stdout, stderr bytes.Buffer
+    sess.Stdout, sess.Stderr = &stdout, &stderr
+    done := make(chan error, 1)
+    go func() {
+        done <- sess.Run(cmd)
+    }()
+    select {
+    case err := <-done:
+        if err != nil {
+            if msg := strings.TrimSpace(stderr.String()); msg != "" {
+                return nil, fmt.Errorf("%w: %s", err, msg)
+            }
+            return nil, err
+        }
+        return stdout.Bytes(), nil
+    case <-ctx.Done():
+        // the command or the connection hangs: start from a new connection next time
+        s.drop(client)
+        return nil, ctx.Err()
+    }
+}
+
+// connect returns the open connection, dialing it first when there is none
+func (s *SSH) connect(ctx context.Context) (*ssh.Client, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.client != nil {
+        return s.client, nil
+    }
+    var d net.Dialer
+    conn, err := d.DialContext(ctx, "tcp", s.Address)
+    if err != nil {
+        return nil, err
+    }
+    // the handshake does not watch ctx, its deadline goes on the connection instead
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+    c, chans, reqs, err := ssh.NewClientConn(conn, s.Address, &ssh.ClientConfig{
+        User:            s.User,
+        Auth:            []ssh.AuthMethod{ssh.PublicKeys(s.Signer)},
+        HostKeyCallback: s.HostKey,
+    })
+    if err != nil {
+        conn.Close()
+        return nil, err
+    }
+    conn.SetDeadline(time.Time{})
+    s.client = ssh.NewClient(c, chans, reqs)
+    return s.client, nil
+}
+
+// drop closes client and forgets it unless another collection already replaced it
+func (s *SSH) drop(client *ssh.Client) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    client.Close()
+    if s.client == client {
+        s.client = nil
+    }
+}
+
+// Close closes the connection, if any
+func (s *SSH) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.client == nil {
+        return nil
+    }
+    err := s.client.Close()
+    s.client = nil
+    return err
+}
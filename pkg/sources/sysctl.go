@@ -0,0 +1,28 @@
+package sources
+
+import "context"
+
+// Sysctl reads the temperatures FreeBSD publishes as sysctls in place of /sys: the CPU cores
+// (dev.cpu.N.temperature, with coretemp or amdtemp loaded) and the ACPI thermal zones
+// (hw.acpi.thermal.tzN.temperature). On other systems Readings fails.
+type Sysctl struct{}
+
+// Name implements Source
+func (s *Sysctl) Name() string {
+    return "sysctl"
+}
+
+// Readings implements Source. The readings are chip "cpu" with sensor cpuN and chip
+// "acpi_thermal" with sensor tzN, the sysctl name standing for the path.
+func (s *Sysctl) Readings(ctx context.Context) ([]Reading, error) {
+    res, failures, err := readSysctl(ctx)
+    for i := range res {
+        res[i].Source = s.Name()
+    }
+    return res, failures.join(err)
+}
+
+// decikelvin converts the IK format of temperature sysctls, tenths of a kelvin, to °C
+func decikelvin(v int32) float64 {
+    return float64(v)/10 - 273.15
+}
@@ -0,0 +1,54 @@
+//go:build freebsd
+
+package sources
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "golang.org/x/sys/unix"
+)
+
+// readSysctl reads dev.cpu.N.temperature for every CPU, skipping those without a temperature
+// driver, then the ACPI thermal zones until the first missing one
+func readSysctl(ctx context.Context) ([]Reading, ReadErrors, error) {
+    ncpu, err := unix.SysctlUint32("hw.ncpu")
+    if err != nil {
+        return nil, nil, fmt.Errorf("hw.ncpu: %w", err)
+    }
+    var (
+        res      []Reading
+        failures ReadErrors
+    )
+    now := time.Now()
+    // read reports whether the sysctl exists
+    read := func(chip, sensor, name string) bool {
+        v, err := unix.SysctlUint32(name)
+        if errors.Is(err, unix.ENOENT) {
+            return false
+        }
+        if err != nil {
+            failures = append(failures, ReadError{Chip: chip, Name: sensor, Path: name, Err: err})
+            return true
+        }
+        res = append(res, Reading{Path: name, Chip: chip, Name: sensor, Value: decikelvin(int32(v)), At: now})
+        return true
+    }
+    for i := 0; i < int(ncpu); i++ {
+        if err := ctx.Err(); err != nil {
+            return res, failures, err
+        }
+        read("cpu", fmt.Sprintf("cpu%d", i), fmt.Sprintf("dev.cpu.%d.temperature", i))
+    }
+    for i := 0; ; i++ {
+        if err := ctx.Err(); err != nil {
+            return res, failures, err
+        }
+        if !read("acpi_thermal", fmt.Sprintf("tz%d", i), fmt.Sprintf("hw.acpi.thermal.tz%d.temperature", i)) {
+            break
+        }
+    }
+    return res, failures, nil
+}
@@ -0,0 +1,14 @@
+//go:build !freebsd
+
+package sources
+
+import (
+    "context"
+    "errors"
+    "fmt"
+)
+
+// readSysctl fails: the temperature sysctls only exist on FreeBSD
+func readSysctl(ctx context.Context) ([]Reading, ReadErrors, error) {
+    return nil, nil, fmt.Errorf("sysctl: températures disponibles uniquement sous FreeBSD: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,80 @@
+package sources
+
+import (
+    "context"
+    "path/filepath"
+    "strings"
+)
+
+// Thermal reads the temperature of the zones of the thermal class
+type Thermal struct {
+    Paths   []string    // base directories, /sys/class/thermal by default
+    FS      FS          // filesystem holding Paths, OS when nil
+    Workers int         // files read in parallel
+    Reader  *FileReader // per file deadline, nil for none
+}
+
+// Name implements Source
+func (t *Thermal) Name() string {
+    return "thermal"
+}
+
+// Discover lists the temp file of the zones of every base directory
+func (t *Thermal) Discover(ctx context.Context) ([]Sensor, error) {
+    paths := t.Paths
+    if len(paths) == 0 {
+        paths = []string{"/sys/class/thermal"}
+    }
+    return discoverPaths(ctx, paths, func(ctx context.Context, thermalBase string) ([]Sensor, error) {
+        return discoverThermal(ctx, orOS(t.FS), thermalBase)
+    })
+}
+
+// Read reads the zones returned by Discover, possibly narrowed down by the caller
+func (t *Thermal) Read(ctx context.Context, sensors []Sensor) ([]Reading, ReadErrors) {
+    return ReadFiles(ctx, t.FS, t.Name(), sensors, t.Workers, t.Reader)
+}
+
+// Readings implements Source
+func (t *Thermal) Readings(ctx context.Context) ([]Reading, error) {
+    s, err := t.Discover(ctx)
+    rs, failures := t.Read(ctx, s)
+    return rs, failures.join(err)
+}
+
+// discoverThermal scans /sys/class/thermal for thermal_zone*/temp, stopping early like discoverHwmon
+func discoverThermal(ctx context.Context, fsys FS, thermalBase string) ([]Sensor, error) {
+    var sensors []Sensor
+    entries, err := fsys.ReadDir(thermalBase)
+    if err != nil {
+        return sensors, err
+    }
+    visited := make(map[string]bool)
+    for _, e := range entries {
+        if err := ctx.Err(); err != nil {
+            return sensors, err
+        }
+        if !strings.HasPrefix(e.Name(), "thermal_zone") {
+            continue
+        }
+        zoneDir := filepath.Join(thermalBase, e.Name())
+        if !visitDir(fsys, zoneDir, visited) {
+            continue
+        }
+        ttype, _ := readFirstLine(fsys, filepath.Join(zoneDir, "type"))
+        // Some systems have trip points; we only read current temp
+        tempPath := filepath.Join(zoneDir, "temp")
+        if _, err := fsys.Stat(tempPath); err == nil {
+            sensors = append(sensors, Sensor{
+                Chip:   "thermal",
+                Name:   ttype,
+                Label:  e.Name(),
+                Path:   tempPath,
+                Driver: deviceDriver(fsys, zoneDir),
+                Device: deviceName(fsys, zoneDir),
+                Factor: 0.001,
+            })
+        }
+    }
+    return sensors, nil
+}
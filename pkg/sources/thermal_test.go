@@ -0,0 +1,61 @@
+package sources
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "testing"
+    "testing/fstest"
+)
+
+func thermalTree() fstest.MapFS {
+    return fstest.MapFS{
+        "sys/class/thermal/thermal_zone0/type": file("x86_pkg_temp\n"),
+        "sys/class/thermal/thermal_zone0/temp": file("52000\n"),
+        // a zone without temp file is not a sensor
+        "sys/class/thermal/thermal_zone1/type": file("acpitz\n"),
+        // neither are the cooling devices
+        "sys/class/thermal/cooling_device0/type":      file("Processor\n"),
+        "sys/class/thermal/cooling_device0/cur_state": file("0\n"),
+        // a zone without type keeps an empty sensor name
+        "sys/class/thermal/thermal_zone2/temp": file("-5500\n"),
+        "sys/class/thermal/thermal_zone3/type": file("iwlwifi_1\n"),
+        "sys/class/thermal/thermal_zone3/temp": file("\n"),
+    }
+}
+
+func TestThermalDiscover(t *testing.T) {
+    th := &Thermal{Paths: []string{"/sys/class/thermal"}, FS: FromFS(thermalTree())}
+    got, err := th.Discover(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []Sensor{
+        {Chip: "thermal", Name: "x86_pkg_temp", Label: "thermal_zone0", Path: "/sys/class/thermal/thermal_zone0/temp", Factor: 0.001},
+        {Chip: "thermal", Name: "", Label: "thermal_zone2", Path: "/sys/class/thermal/thermal_zone2/temp", Factor: 0.001},
+        {Chip: "thermal", Name: "iwlwifi_1", Label: "thermal_zone3", Path: "/sys/class/thermal/thermal_zone3/temp", Factor: 0.001},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("Discover() =\n%s\nwant\n%s", sensorList(got), sensorList(want))
+    }
+}
+
+func TestThermalReadings(t *testing.T) {
+    th := &Thermal{Paths: []string{"/sys/class/thermal"}, FS: FromFS(thermalTree())}
+    rs, err := th.Readings(context.Background())
+    failures, err := SplitErrors(err)
+    if err != nil {
+        t.Fatalf("Readings() source error = %v", err)
+    }
+    got := make(map[string]float64)
+    for _, r := range rs {
+        got[r.Label] = r.Value
+    }
+    if want := map[string]float64{"thermal_zone0": 52, "thermal_zone2": -5.5}; !reflect.DeepEqual(got, want) {
+        t.Errorf("Readings() = %v, want %v", got, want)
+    }
+    // a blank line is a value that does not parse, not an empty file
+    if len(failures) != 1 || failures[0].Label != "thermal_zone3" || errors.Is(failures[0], ErrEmptyFile) {
+        t.Errorf("Readings() failures = %v, want a parse error for the blank temp of thermal_zone3", failures)
+    }
+}